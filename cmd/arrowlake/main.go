@@ -31,23 +31,183 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
 
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
 	"github.com/TFMV/arrowlake/pkg/join"
+	"github.com/apache/arrow/go/v17/arrow/array"
 )
 
+const usage = `usage:
+  arrowlake [-format csv|json|ndjson] [config.yaml]   run the join and print its result
+  arrowlake validate [config.yaml]                    check the config without running it
+  arrowlake render [config.yaml]                      print the query SQL with its template rendered
+  arrowlake config [config.yaml]                      print the config with references resolved and secrets masked
+`
+
 func main() {
-	ctx := context.Background()
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "validate":
+			return validate(args[1:], stdout, stderr)
+		case "render":
+			return render(args[1:], stdout, stderr)
+		case "config":
+			return printConfig(args[1:], stdout, stderr)
+		}
+	}
+
+	flags := flag.NewFlagSet("arrowlake", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "csv", "output format: csv, json or ndjson")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	switch *format {
+	case "csv", "json", "ndjson":
+	default:
+		fmt.Fprintf(stderr, "Unsupported output format: %s\n", *format)
+		return 2
+	}
+
+	config, ok := loadConfig(configPath(flags.Args()), stderr)
+	if !ok {
+		return 1
+	}
+
+	// Join data sources and print the result
+	sink := join.SinkFunc(func(ctx context.Context, rdr array.RecordReader) error {
+		return writeResult(stdout, rdr, *format)
+	})
+	if err := join.JoinDataSourcesTo(ctx, config, sink); err != nil {
+		fmt.Fprintf(stderr, "Failed to join data sources: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func validate(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 1 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	path := configPath(args)
+	if _, ok := loadConfig(path, stderr); !ok {
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s is valid\n", path)
+	return 0
+}
+
+func render(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 1 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	path := configPath(args)
+	config, err := join.LoadConfig(path)
+	if err != nil {
+		reportLoadError(path, err, stderr)
+		return 1
+	}
+	query, err := config.RenderSQL()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to render query: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(stdout, config.Redact(strings.TrimRight(query, "\n")))
+	return 0
+}
+
+func printConfig(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 1 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
 
-	// Load the configuration file
-	config, err := join.LoadConfig("/Users/thomasmcgeehan/ArrowLake/arrowlake/config.yaml")
+	path := configPath(args)
+	config, err := join.LoadConfig(path)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		reportLoadError(path, err, stderr)
+		return 1
 	}
+	fmt.Fprint(stdout, config)
+	return 0
+}
 
-	// Join data sources
-	err = join.JoinDataSources(ctx, config)
+// loadConfig reads and validates the config at path, reporting any problems
+// to stderr.
+func loadConfig(path string, stderr io.Writer) (*join.Config, bool) {
+	config, err := join.LoadConfig(path)
 	if err != nil {
-		log.Fatalf("Failed to join data sources: %v", err)
+		reportLoadError(path, err, stderr)
+		return nil, false
+	}
+
+	if err := config.Validate(); err != nil {
+		if !reportProblems(path, err, stderr) {
+			fmt.Fprintf(stderr, "Invalid config: %v\n", err)
+		}
+		return nil, false
+	}
+	return config, true
+}
+
+func reportLoadError(path string, err error, stderr io.Writer) {
+	if !reportProblems(path, err, stderr) {
+		fmt.Fprintf(stderr, "Failed to load config: %v\n", err)
+	}
+}
+
+// reportProblems prints the problems of err if it is a
+// join.ValidationErrors, and reports whether it was.
+func reportProblems(path string, err error, stderr io.Writer) bool {
+	var errs join.ValidationErrors
+	if !errors.As(err, &errs) {
+		return false
+	}
+	for _, e := range errs {
+		fmt.Fprintln(stderr, e)
+	}
+	noun := "problems"
+	if len(errs) == 1 {
+		noun = "problem"
+	}
+	fmt.Fprintf(stderr, "%s: %d %s found\n", path, len(errs), noun)
+	return true
+}
+
+func configPath(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return "config.yaml"
+}
+
+func writeResult(w io.Writer, rdr array.RecordReader, format string) error {
+	switch format {
+	case "csv":
+		return lakearrow.WriteCSV(w, rdr, lakearrow.CSVOptions{})
+	case "json":
+		return lakearrow.WriteJSON(w, rdr, lakearrow.JSONOptions{})
+	case "ndjson":
+		return lakearrow.WriteJSON(w, rdr, lakearrow.JSONOptions{Lines: true})
 	}
+	return fmt.Errorf("unsupported output format: %s", format)
 }
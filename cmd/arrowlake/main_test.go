@@ -0,0 +1,173 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	path := writeConfig(t, `sources:
+  - type: parquet
+    table_name: nation
+    file_path: ../../data/nation.parquet
+query:
+  sql: SELECT n_nationkey, n_name FROM nation ORDER BY n_nationkey LIMIT 2
+`)
+
+	var stdout, stderr strings.Builder
+	if code := run(context.Background(), []string{"-format", "ndjson", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited with %d: %s", code, stderr.String())
+	}
+	if want := "{\"n_nationkey\":0,\"n_name\":\"ALGERIA\"}\n{\"n_nationkey\":1,\"n_name\":\"ARGENTINA\"}\n"; stdout.String() != want {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run(context.Background(), []string{"validate", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("validate exited with %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "is valid") {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	path := writeConfig(t, `sources:
+  - type: parquet
+    table_name: nation
+    file_path: missing.parquet
+  - type: parquet
+    table_name: nation
+    file_path: ../../data/nation.parquet
+query:
+  sql: SELECT * FROM {region}
+`)
+
+	for _, args := range [][]string{{"validate", path}, {path}} {
+		var stdout, stderr strings.Builder
+		if code := run(context.Background(), args, &stdout, &stderr); code != 1 {
+			t.Fatalf("%v: expected exit code 1, got %d", args, code)
+		}
+		want := path + ":4:16: sources[0].file_path: no files match \"missing.parquet\"\n" +
+			path + ":6:17: sources[1].table_name: duplicate table name \"nation\", also used by sources[0]\n" +
+			path + ":9:22: query.sql: unknown reference {region}\n" +
+			path + ": 3 problems found\n"
+		if stderr.String() != want || stdout.Len() != 0 {
+			t.Fatalf("%v: unexpected output:\n%s\nwant:\n%s", args, stderr.String(), want)
+		}
+	}
+
+	var stdout, stderr strings.Builder
+	if code := run(context.Background(), []string{"validate", filepath.Join(t.TempDir(), "missing.yaml")}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit code 1 for a missing config, got %d", code)
+	}
+	if code := run(context.Background(), []string{"-format", "xml", path}, &stdout, &stderr); code != 2 {
+		t.Fatalf("expected exit code 2 for an unknown format, got %d", code)
+	}
+}
+
+func TestRunRender(t *testing.T) {
+	path := writeConfig(t, `sources:
+  - type: parquet
+    table_name: nation
+    file_path: ../../data/nation.parquet
+query:
+  select_columns: [n_name, n_regionkey]
+  params:
+    regions: [1, 2]
+  sql: |
+    SELECT {select_columns} FROM {nation}
+    {if regions}WHERE n_regionkey IN ({regions}){end}
+`)
+
+	var stdout, stderr strings.Builder
+	if code := run(context.Background(), []string{"render", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("render exited with %d: %s", code, stderr.String())
+	}
+	want := "SELECT n_name, n_regionkey FROM \"nation\"\nWHERE n_regionkey IN (1, 2)\n"
+	if stdout.String() != want {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "pgpass")
+	if err := os.WriteFile(secretFile, []byte("hunter2\n"), 0o600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+	t.Setenv("ARROWLAKE_PGHOST", "db.prod")
+	path := writeConfig(t, `sources:
+  - type: postgres
+    table_name: pg
+    connection_string: host=${ARROWLAKE_PGHOST} password=${file:`+secretFile+`}
+query:
+  params:
+    token: ${file:`+secretFile+`}
+  sql: SELECT {token}
+`)
+
+	var stdout, stderr strings.Builder
+	if code := run(context.Background(), []string{"config", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("config exited with %d: %s", code, stderr.String())
+	}
+	if out := stdout.String(); strings.Contains(out, "hunter2") ||
+		!strings.Contains(out, "connection_string: host=db.prod password=***\n") || !strings.Contains(out, `token: '***'`) {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	stdout.Reset()
+	if code := run(context.Background(), []string{"render", path}, &stdout, &stderr); code != 0 || stdout.String() != "SELECT '***'\n" {
+		t.Fatalf("render exited with %d: %q %s", code, stdout.String(), stderr.String())
+	}
+
+	path = writeConfig(t, "sources:\n  - type: parquet\n    table_name: t\n    file_path: ${ARROWLAKE_UNSET}\n")
+	stdout.Reset()
+	if code := run(context.Background(), []string{"validate", path}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	want := path + ":4:16: sources[0].file_path: environment variable ARROWLAKE_UNSET is not set\n" + path + ": 1 problem found\n"
+	if !strings.HasSuffix(stderr.String(), want) {
+		t.Fatalf("unexpected output: %q", stderr.String())
+	}
+}
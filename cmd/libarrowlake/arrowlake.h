@@ -0,0 +1,96 @@
+/*
+ * ArrowLake C API.
+ *
+ * Build the shared library with `make libarrowlake`, which produces
+ * build/libarrowlake.so. Query results are returned through the Arrow C
+ * Stream interface: https://arrow.apache.org/docs/format/CStreamInterface.html
+ *
+ * Functions that can fail return 0 on success and -1 on failure. On failure,
+ * if error is not NULL, *error is set to a message that must be freed with
+ * arrowlake_free_error.
+ */
+
+#ifndef ARROWLAKE_H
+#define ARROWLAKE_H
+
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+#ifndef ARROW_C_DATA_INTERFACE
+#define ARROW_C_DATA_INTERFACE
+
+#define ARROW_FLAG_DICTIONARY_ORDERED 1
+#define ARROW_FLAG_NULLABLE 2
+#define ARROW_FLAG_MAP_KEYS_SORTED 4
+
+struct ArrowSchema {
+  const char* format;
+  const char* name;
+  const char* metadata;
+  int64_t flags;
+  int64_t n_children;
+  struct ArrowSchema** children;
+  struct ArrowSchema* dictionary;
+  void (*release)(struct ArrowSchema*);
+  void* private_data;
+};
+
+struct ArrowArray {
+  int64_t length;
+  int64_t null_count;
+  int64_t offset;
+  int64_t n_buffers;
+  int64_t n_children;
+  const void** buffers;
+  struct ArrowArray** children;
+  struct ArrowArray* dictionary;
+  void (*release)(struct ArrowArray*);
+  void* private_data;
+};
+
+#endif /* ARROW_C_DATA_INTERFACE */
+
+#ifndef ARROW_C_STREAM_INTERFACE
+#define ARROW_C_STREAM_INTERFACE
+
+struct ArrowArrayStream {
+  int (*get_schema)(struct ArrowArrayStream*, struct ArrowSchema* out);
+  int (*get_next)(struct ArrowArrayStream*, struct ArrowArray* out);
+  const char* (*get_last_error)(struct ArrowArrayStream*);
+  void (*release)(struct ArrowArrayStream*);
+  void* private_data;
+};
+
+#endif /* ARROW_C_STREAM_INTERFACE */
+
+/* The library's own build declares these through cgo. */
+#ifndef ARROWLAKE_INTERNAL
+
+/*
+ * arrowlake_open opens a DuckDB database at path, or an in-memory database
+ * when path is empty, and stores a handle to the engine in *engine.
+ */
+int arrowlake_open(const char* path, uintptr_t* engine, char** error);
+
+/*
+ * arrowlake_query runs sql and exports its results into out, which must be
+ * zero-initialized. The caller owns the stream and must call out->release
+ * when done, before closing the engine.
+ */
+int arrowlake_query(uintptr_t engine, const char* sql, struct ArrowArrayStream* out, char** error);
+
+/* arrowlake_close closes the engine. Closing an unknown engine does nothing. */
+void arrowlake_close(uintptr_t engine);
+
+void arrowlake_free_error(char* error);
+
+#endif /* ARROWLAKE_INTERNAL */
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif /* ARROWLAKE_H */
@@ -0,0 +1,132 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+// Command libarrowlake builds ArrowLake as a C shared library. See
+// arrowlake.h for the API.
+package main
+
+/*
+#include <stdlib.h>
+#define ARROWLAKE_INTERNAL
+#include "arrowlake.h"
+*/
+import "C"
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
+	"github.com/apache/arrow/go/v17/arrow/cdata"
+	_ "github.com/marcboeker/go-duckdb"
+)
+
+type engine struct {
+	db    *sql.DB
+	arrow *lakearrow.Arrow
+}
+
+var (
+	enginesMu sync.Mutex
+	engines   = make(map[uintptr]*engine)
+	nextID    uintptr
+)
+
+func lookup(id C.uintptr_t) (*engine, error) {
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+
+	e, ok := engines[uintptr(id)]
+	if !ok {
+		return nil, fmt.Errorf("unknown engine %d", uintptr(id))
+	}
+	return e, nil
+}
+
+func fail(errOut **C.char, err error) C.int {
+	if errOut != nil {
+		*errOut = C.CString(err.Error())
+	}
+	return -1
+}
+
+//export arrowlake_open
+func arrowlake_open(path *C.char, id *C.uintptr_t, errOut **C.char) C.int {
+	db, err := sql.Open("duckdb", C.GoString(path))
+	if err != nil {
+		return fail(errOut, fmt.Errorf("failed to open database: %w", err))
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return fail(errOut, fmt.Errorf("failed to open database: %w", err))
+	}
+
+	enginesMu.Lock()
+	nextID++
+	engines[nextID] = &engine{db: db, arrow: lakearrow.NewArrow(db)}
+	*id = C.uintptr_t(nextID)
+	enginesMu.Unlock()
+	return 0
+}
+
+//export arrowlake_query
+func arrowlake_query(id C.uintptr_t, query *C.char, out *C.struct_ArrowArrayStream, errOut **C.char) C.int {
+	e, err := lookup(id)
+	if err != nil {
+		return fail(errOut, err)
+	}
+
+	stream := (*cdata.CArrowArrayStream)(unsafe.Pointer(out))
+	if err := e.arrow.ExportCStream(context.Background(), stream, C.GoString(query)); err != nil {
+		return fail(errOut, err)
+	}
+	return 0
+}
+
+//export arrowlake_close
+func arrowlake_close(id C.uintptr_t) {
+	enginesMu.Lock()
+	e, ok := engines[uintptr(id)]
+	delete(engines, uintptr(id))
+	enginesMu.Unlock()
+
+	if ok {
+		e.db.Close()
+	}
+}
+
+//export arrowlake_free_error
+func arrowlake_free_error(msg *C.char) {
+	C.free(unsafe.Pointer(msg))
+}
+
+func main() {}
@@ -0,0 +1,112 @@
+/*
+ * C-level tests for the ArrowLake shared library. Run with `make test-c`.
+ */
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include "arrowlake.h"
+
+static int failures = 0;
+
+#define CHECK(cond)                                                   \
+  do {                                                                \
+    if (!(cond)) {                                                    \
+      fprintf(stderr, "%s:%d: check failed: %s\n", __FILE__, __LINE__, \
+              #cond);                                                 \
+      failures++;                                                     \
+    }                                                                 \
+  } while (0)
+
+static void test_query_stream(uintptr_t engine) {
+  struct ArrowArrayStream stream;
+  memset(&stream, 0, sizeof(stream));
+  char* error = NULL;
+
+  int rc = arrowlake_query(
+      engine, "SELECT range AS id, 'row ' || range AS name FROM range(5000)",
+      &stream, &error);
+  CHECK(rc == 0);
+  CHECK(error == NULL);
+  if (rc != 0) {
+    fprintf(stderr, "query failed: %s\n", error);
+    arrowlake_free_error(error);
+    return;
+  }
+
+  struct ArrowSchema schema;
+  memset(&schema, 0, sizeof(schema));
+  CHECK(stream.get_schema(&stream, &schema) == 0);
+  CHECK(schema.n_children == 2);
+  CHECK(strcmp(schema.format, "+s") == 0);
+  CHECK(strcmp(schema.children[0]->name, "id") == 0);
+  CHECK(strcmp(schema.children[0]->format, "l") == 0);
+  CHECK(strcmp(schema.children[1]->name, "name") == 0);
+  CHECK(strcmp(schema.children[1]->format, "u") == 0);
+  schema.release(&schema);
+
+  int64_t rows = 0, sum = 0;
+  for (;;) {
+    struct ArrowArray batch;
+    memset(&batch, 0, sizeof(batch));
+    CHECK(stream.get_next(&stream, &batch) == 0);
+    if (batch.release == NULL) {
+      break; /* end of stream */
+    }
+
+    CHECK(batch.n_children == 2);
+    const struct ArrowArray* ids = batch.children[0];
+    const int64_t* values = (const int64_t*)ids->buffers[1];
+    for (int64_t i = 0; i < ids->length; i++) {
+      sum += values[ids->offset + i];
+    }
+    rows += batch.length;
+    batch.release(&batch);
+  }
+  CHECK(rows == 5000);
+  CHECK(sum == 5000LL * 4999 / 2);
+
+  stream.release(&stream);
+  CHECK(stream.release == NULL);
+}
+
+static void test_query_error(uintptr_t engine) {
+  struct ArrowArrayStream stream;
+  memset(&stream, 0, sizeof(stream));
+  char* error = NULL;
+
+  CHECK(arrowlake_query(engine, "SELECT missing_column", &stream, &error) == -1);
+  CHECK(error != NULL && strstr(error, "missing_column") != NULL);
+  CHECK(stream.release == NULL);
+  arrowlake_free_error(error);
+}
+
+int main(void) {
+  uintptr_t engine = 0;
+  char* error = NULL;
+
+  if (arrowlake_open("", &engine, &error) != 0) {
+    fprintf(stderr, "open failed: %s\n", error);
+    arrowlake_free_error(error);
+    return 1;
+  }
+
+  test_query_stream(engine);
+  test_query_error(engine);
+  arrowlake_close(engine);
+
+  /* a closed engine is rejected */
+  struct ArrowArrayStream stream;
+  memset(&stream, 0, sizeof(stream));
+  CHECK(arrowlake_query(engine, "SELECT 1", &stream, &error) == -1);
+  CHECK(error != NULL && strstr(error, "unknown engine") != NULL);
+  arrowlake_free_error(error);
+
+  if (failures > 0) {
+    fprintf(stderr, "%d check(s) failed\n", failures);
+    return 1;
+  }
+  printf("ok\n");
+  return 0;
+}
@@ -33,6 +33,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sync"
 
 	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/apache/arrow/go/v17/arrow/array"
@@ -41,100 +42,81 @@ import (
 )
 
 type Arrow struct {
-	db *sql.DB
+	db       *sql.DB
+	mem      memory.Allocator
+	memLimit int64
+	onStats  func(QueryStats)
+	dialect  string
+	typeMap  TypeMap
+
+	// customMem is set when WithAllocator replaced the default allocator.
+	customMem      bool
+	sourceMetadata bool
+	redactQuery    func(string) string
+
+	mu    sync.Mutex
+	views map[string]struct{}
 }
 
-func NewArrow(db *sql.DB) *Arrow {
-	return &Arrow{db: db}
+func NewArrow(db *sql.DB, opts ...Option) *Arrow {
+	a := &Arrow{db: db, mem: memory.NewGoAllocator(), views: make(map[string]struct{})}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.dialect == "" {
+		a.dialect = detectDialect(db.Driver())
+	}
+	return a
 }
 
 func (a *Arrow) QueryArrow(ctx context.Context, query string, args ...interface{}) (arrow.Record, error) {
+	src := a.lookupSources(ctx, query)
 	rows, err := a.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
-	defer rows.Close()
 
-	columns, err := rows.ColumnTypes()
+	rdr, err := a.newRowReader(ctx, query, src, nil, rows, 0)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get columns: %w", err)
+		return nil, err
 	}
+	defer rdr.Release()
 
-	pool := memory.NewGoAllocator()
-	builders := make([]array.Builder, len(columns))
-	for i, col := range columns {
-		switch col.DatabaseTypeName() {
-		case "INTEGER":
-			builders[i] = array.NewInt32Builder(pool)
-		case "DOUBLE":
-			builders[i] = array.NewFloat64Builder(pool)
-		case "VARCHAR":
-			builders[i] = array.NewStringBuilder(pool)
-		default:
-			return nil, fmt.Errorf("unsupported column type: %s", col.DatabaseTypeName())
-		}
+	return rdr.readBatch()
+}
+
+// QueryArrowStream runs query and returns a reader that converts its result
+// into records of at most batchSize rows as they are consumed, so the full
+// result never has to be held in memory. The caller must release the reader;
+// cancelling ctx stops the stream and is reported through the reader's Err.
+func (a *Arrow) QueryArrowStream(ctx context.Context, batchSize int, query string, args ...interface{}) (array.RecordReader, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("invalid batch size: %d", batchSize)
 	}
 
-	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		for i := range values {
-			switch columns[i].DatabaseTypeName() {
-			case "INTEGER":
-				var v sql.NullInt32
-				values[i] = &v
-			case "DOUBLE":
-				var v sql.NullFloat64
-				values[i] = &v
-			case "VARCHAR":
-				var v sql.NullString
-				values[i] = &v
-			default:
-				return nil, fmt.Errorf("unsupported column type: %s", columns[i].DatabaseTypeName())
-			}
-		}
-
-		if err := rows.Scan(values...); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		for i, val := range values {
-			switch v := val.(type) {
-			case *sql.NullInt32:
-				builder := builders[i].(*array.Int32Builder)
-				if v.Valid {
-					builder.Append(v.Int32)
-				} else {
-					builder.AppendNull()
-				}
-			case *sql.NullFloat64:
-				builder := builders[i].(*array.Float64Builder)
-				if v.Valid {
-					builder.Append(v.Float64)
-				} else {
-					builder.AppendNull()
-				}
-			case *sql.NullString:
-				builder := builders[i].(*array.StringBuilder)
-				if v.Valid {
-					builder.Append(v.String)
-				} else {
-					builder.AppendNull()
-				}
-			}
-		}
+	src := a.lookupSources(ctx, query)
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 
-	fieldTypes := make([]arrow.Field, len(columns))
-	arrs := make([]arrow.Array, len(columns))
-	for i, col := range columns {
-		arr := builders[i].NewArray()
-		defer arr.Release()
-		fieldTypes[i] = arrow.Field{Name: col.Name(), Type: arr.DataType()}
-		arrs[i] = arr
+	return a.newRowReader(ctx, query, src, nil, rows, batchSize)
+}
+
+// QueryArrowStreamSchema is like QueryArrowStream, but converts the result
+// into the fields of schema instead of deriving them from the column types
+// the driver reports. It suits databases whose declared column types do not
+// describe their values, such as SQLite. Schema must have one field per
+// result column.
+func (a *Arrow) QueryArrowStreamSchema(ctx context.Context, schema *arrow.Schema, batchSize int, query string, args ...interface{}) (array.RecordReader, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("invalid batch size: %d", batchSize)
 	}
 
-	schema := arrow.NewSchema(fieldTypes, nil)
-	record := array.NewRecord(schema, arrs, int64(arrs[0].Len()))
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
 
-	return record, nil
+	return a.newRowReader(ctx, query, columnSources{}, schema.Fields(), rows, batchSize)
 }
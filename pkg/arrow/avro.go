@@ -0,0 +1,645 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/decimal128"
+	"github.com/apache/arrow/go/v17/arrow/decimal256"
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+)
+
+// avroAppender appends a value decoded by the generic Avro decoder to b.
+type avroAppender func(b array.Builder, v any) error
+
+// ReadAvro returns a reader over the records of an Avro object container
+// file. The Avro schema is mapped as follows:
+//
+//   - records become structs, arrays lists and maps maps with string keys
+//   - enums become string dictionaries
+//   - decimal, date, time, timestamp and duration logical types become the
+//     matching Arrow types; local timestamps have no time zone
+//   - a union of null and one other type becomes a nullable column of that
+//     type; any other union becomes a struct with one nullable member per
+//     branch, named after the branch type, of which only the member of the
+//     decoded branch is set
+//
+// A file whose schema is not a record is read as a single column named
+// value. The caller must release the reader.
+func (a *Arrow) ReadAvro(r io.Reader) (array.RecordReader, error) {
+	dec, err := ocf.NewDecoder(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Avro file: %w", err)
+	}
+
+	root, err := avro.Parse(string(dec.Metadata()["avro.schema"]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Avro schema: %w", err)
+	}
+	rec, isRecord := root.(*avro.RecordSchema)
+	var fields []*avro.Field
+	if isRecord {
+		fields = rec.Fields()
+	} else {
+		field, err := avro.NewField("value", root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map Avro schema: %w", err)
+		}
+		fields = []*avro.Field{field}
+	}
+
+	m := avroMapper{seen: make(map[string]bool)}
+	if isRecord {
+		m.seen[rec.FullName()] = true
+	}
+	arrowFields := make([]arrow.Field, len(fields))
+	apps := make([]avroAppender, len(fields))
+	for i, f := range fields {
+		arrowFields[i], apps[i], err = m.field(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map Avro schema: %w", err)
+		}
+	}
+
+	schema := arrow.NewSchema(arrowFields, nil)
+	return &avroReader{
+		refCount:  1,
+		dec:       dec,
+		schema:    schema,
+		names:     fieldNames(fields),
+		wrapped:   !isRecord,
+		apps:      apps,
+		bld:       array.NewRecordBuilder(a.mem, schema),
+		batchSize: defaultBatchSize,
+	}, nil
+}
+
+func fieldNames(fields []*avro.Field) []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Name()
+	}
+	return names
+}
+
+type avroReader struct {
+	refCount  int64
+	dec       *ocf.Decoder
+	schema    *arrow.Schema
+	names     []string
+	wrapped   bool
+	apps      []avroAppender
+	bld       *array.RecordBuilder
+	batchSize int
+	rows      int64
+	rec       arrow.Record
+	err       error
+}
+
+func (r *avroReader) Retain() { atomic.AddInt64(&r.refCount, 1) }
+
+func (r *avroReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) != 0 {
+		return
+	}
+	if r.rec != nil {
+		r.rec.Release()
+		r.rec = nil
+	}
+	r.bld.Release()
+}
+
+func (r *avroReader) Schema() *arrow.Schema { return r.schema }
+
+func (r *avroReader) Record() arrow.Record { return r.rec }
+
+func (r *avroReader) Err() error { return r.err }
+
+func (r *avroReader) Next() bool {
+	if r.rec != nil {
+		r.rec.Release()
+		r.rec = nil
+	}
+	if r.err != nil {
+		return false
+	}
+
+	n := 0
+	for n < r.batchSize && r.dec.HasNext() {
+		var v any
+		if err := r.dec.Decode(&v); err != nil {
+			r.err = fmt.Errorf("failed to decode Avro record %d: %w", r.rows, err)
+			return false
+		}
+		if err := r.appendRow(v); err != nil {
+			r.err = fmt.Errorf("Avro record %d: %w", r.rows, err)
+			return false
+		}
+		r.rows++
+		n++
+	}
+	if err := r.dec.Error(); err != nil {
+		r.err = fmt.Errorf("failed to read Avro file: %w", err)
+		return false
+	}
+	if n == 0 {
+		return false
+	}
+
+	r.rec = r.bld.NewRecord()
+	return true
+}
+
+func (r *avroReader) appendRow(v any) error {
+	if r.wrapped {
+		return r.apps[0](r.bld.Field(0), v)
+	}
+
+	row, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("unexpected %T value for Avro record", v)
+	}
+	for i, name := range r.names {
+		if err := r.apps[i](r.bld.Field(i), row[name]); err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// avroMapper maps Avro schemas to Arrow types, tracking the named records
+// being mapped so recursive schemas are rejected instead of looping.
+type avroMapper struct {
+	seen map[string]bool
+}
+
+func (m avroMapper) field(f *avro.Field) (arrow.Field, avroAppender, error) {
+	dt, nullable, app, err := m.mapType(f.Type())
+	if err != nil {
+		return arrow.Field{}, nil, fmt.Errorf("field %s: %w", f.Name(), err)
+	}
+
+	return arrow.Field{Name: f.Name(), Type: dt, Nullable: nullable}, app, nil
+}
+
+// mapType returns the Arrow type of s, whether its values may be null, and
+// the appender for its decoded values.
+func (m avroMapper) mapType(s avro.Schema) (arrow.DataType, bool, avroAppender, error) {
+	if ref, ok := s.(*avro.RefSchema); ok {
+		s = ref.Schema()
+	}
+
+	switch s := s.(type) {
+	case *avro.PrimitiveSchema:
+		dt, app, err := primitiveAvroType(s)
+		return dt, s.Type() == avro.Null, app, err
+	case *avro.FixedSchema:
+		dt, app, err := fixedAvroType(s)
+		return dt, false, app, err
+	case *avro.EnumSchema:
+		dt := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}
+		return dt, false, nullable(func(b array.Builder, v any) error {
+			sym, err := avroValue[string](s, v)
+			if err != nil {
+				return err
+			}
+			return b.(*array.BinaryDictionaryBuilder).AppendString(sym)
+		}), nil
+	case *avro.ArraySchema:
+		elem, elemNullable, elemApp, err := m.mapType(s.Items())
+		if err != nil {
+			return nil, false, nil, err
+		}
+		dt := arrow.ListOfField(arrow.Field{Name: "item", Type: elem, Nullable: elemNullable})
+		return dt, false, nullable(func(b array.Builder, v any) error {
+			items, err := avroValue[[]any](s, v)
+			if err != nil {
+				return err
+			}
+			lb := b.(*array.ListBuilder)
+			lb.Append(true)
+			for _, item := range items {
+				if err := elemApp(lb.ValueBuilder(), item); err != nil {
+					return err
+				}
+			}
+			return nil
+		}), nil
+	case *avro.MapSchema:
+		item, _, itemApp, err := m.mapType(s.Values())
+		if err != nil {
+			return nil, false, nil, err
+		}
+		dt := arrow.MapOf(arrow.BinaryTypes.String, item)
+		return dt, false, nullable(func(b array.Builder, v any) error {
+			entries, err := avroValue[map[string]any](s, v)
+			if err != nil {
+				return err
+			}
+			keys := make([]string, 0, len(entries))
+			for k := range entries {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			mb := b.(*array.MapBuilder)
+			mb.Append(true)
+			for _, k := range keys {
+				mb.KeyBuilder().(*array.StringBuilder).Append(k)
+				if err := itemApp(mb.ItemBuilder(), entries[k]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}), nil
+	case *avro.RecordSchema:
+		return m.recordType(s)
+	case *avro.UnionSchema:
+		return m.unionType(s)
+	}
+
+	return nil, false, nil, fmt.Errorf("unsupported Avro type: %s", s.Type())
+}
+
+func (m avroMapper) recordType(s *avro.RecordSchema) (arrow.DataType, bool, avroAppender, error) {
+	name := s.FullName()
+	if m.seen[name] {
+		return nil, false, nil, fmt.Errorf("recursive Avro record %s is not supported", name)
+	}
+	m.seen[name] = true
+	defer delete(m.seen, name)
+
+	fields := make([]arrow.Field, len(s.Fields()))
+	apps := make([]avroAppender, len(s.Fields()))
+	for i, f := range s.Fields() {
+		var err error
+		fields[i], apps[i], err = m.field(f)
+		if err != nil {
+			return nil, false, nil, err
+		}
+	}
+
+	names := fieldNames(s.Fields())
+	return arrow.StructOf(fields...), false, nullable(func(b array.Builder, v any) error {
+		rec, err := avroValue[map[string]any](s, v)
+		if err != nil {
+			return err
+		}
+		sb := b.(*array.StructBuilder)
+		sb.Append(true)
+		for i, name := range names {
+			if err := apps[i](sb.FieldBuilder(i), rec[name]); err != nil {
+				return fmt.Errorf("field %s: %w", name, err)
+			}
+		}
+		return nil
+	}), nil
+}
+
+func (m avroMapper) unionType(s *avro.UnionSchema) (arrow.DataType, bool, avroAppender, error) {
+	var branches []avro.Schema
+	for _, t := range s.Types() {
+		if t.Type() != avro.Null {
+			branches = append(branches, t)
+		}
+	}
+	hasNull := len(branches) < len(s.Types())
+
+	switch len(branches) {
+	case 0:
+		return arrow.Null, true, nullable(func(b array.Builder, v any) error {
+			return fmt.Errorf("unexpected %T value for Avro null", v)
+		}), nil
+	case 1:
+		dt, _, app, err := m.mapType(branches[0])
+		if err != nil {
+			return nil, false, nil, err
+		}
+		return dt, true, nullable(func(b array.Builder, v any) error {
+			// The generic decoder keys the value of a union by its branch.
+			if branch, ok := v.(map[string]any); ok && len(branch) == 1 {
+				for _, value := range branch {
+					v = value
+				}
+			}
+			return app(b, v)
+		}), nil
+	}
+
+	fields := make([]arrow.Field, len(branches))
+	apps := make([]avroAppender, len(branches))
+	keys := make(map[string]int, len(branches))
+	used := make(map[string]bool, len(branches))
+	for i, t := range branches {
+		dt, _, app, err := m.mapType(t)
+		if err != nil {
+			return nil, false, nil, err
+		}
+		key := avroTypeName(t, true)
+		name := avroTypeName(t, false)
+		if used[name] {
+			name = key
+		}
+		used[name] = true
+		keys[key] = i
+		fields[i] = arrow.Field{Name: name, Type: dt, Nullable: true}
+		apps[i] = app
+	}
+
+	return arrow.StructOf(fields...), hasNull, nullable(func(b array.Builder, v any) error {
+		branch, err := avroValue[map[string]any](s, v)
+		if err != nil {
+			return err
+		}
+		sb := b.(*array.StructBuilder)
+		sb.Append(true)
+		set := -1
+		for key, value := range branch {
+			i, ok := keys[key]
+			if !ok {
+				return fmt.Errorf("unknown Avro union branch %s", key)
+			}
+			if err := apps[i](sb.FieldBuilder(i), value); err != nil {
+				return err
+			}
+			set = i
+		}
+		for i := range branches {
+			if i != set {
+				sb.FieldBuilder(i).AppendNull()
+			}
+		}
+		return nil
+	}), nil
+}
+
+// avroTypeName names a union branch the way the generic decoder keys it
+// when full is set, or by the short name of named types otherwise.
+func avroTypeName(s avro.Schema, full bool) string {
+	if ref, ok := s.(*avro.RefSchema); ok {
+		s = ref.Schema()
+	}
+	if named, ok := s.(avro.NamedSchema); ok {
+		if full {
+			return named.FullName()
+		}
+		return named.Name()
+	}
+
+	name := string(s.Type())
+	if lt, ok := s.(avro.LogicalTypeSchema); ok && lt.Logical() != nil {
+		name += "." + string(lt.Logical().Type())
+	}
+	return name
+}
+
+func primitiveAvroType(s *avro.PrimitiveSchema) (arrow.DataType, avroAppender, error) {
+	var logical avro.LogicalType
+	if ls := s.Logical(); ls != nil {
+		logical = ls.Type()
+	}
+
+	switch s.Type() {
+	case avro.Null:
+		return arrow.Null, nullable(func(b array.Builder, v any) error {
+			return fmt.Errorf("unexpected %T value for Avro null", v)
+		}), nil
+	case avro.Boolean:
+		return arrow.FixedWidthTypes.Boolean, nullable(func(b array.Builder, v any) error {
+			x, err := avroValue[bool](s, v)
+			if err == nil {
+				b.(*array.BooleanBuilder).Append(x)
+			}
+			return err
+		}), nil
+	case avro.Int:
+		switch logical {
+		case avro.Date:
+			return arrow.FixedWidthTypes.Date32, nullable(func(b array.Builder, v any) error {
+				t, err := avroValue[time.Time](s, v)
+				if err == nil {
+					b.(*array.Date32Builder).Append(arrow.Date32FromTime(t))
+				}
+				return err
+			}), nil
+		case avro.TimeMillis:
+			return arrow.FixedWidthTypes.Time32ms, nullable(func(b array.Builder, v any) error {
+				d, err := avroValue[time.Duration](s, v)
+				if err == nil {
+					b.(*array.Time32Builder).Append(arrow.Time32(d.Milliseconds()))
+				}
+				return err
+			}), nil
+		}
+		return arrow.PrimitiveTypes.Int32, nullable(func(b array.Builder, v any) error {
+			x, err := avroValue[int](s, v)
+			if err == nil {
+				b.(*array.Int32Builder).Append(int32(x))
+			}
+			return err
+		}), nil
+	case avro.Long:
+		switch logical {
+		case avro.TimeMicros:
+			return arrow.FixedWidthTypes.Time64us, nullable(func(b array.Builder, v any) error {
+				d, err := avroValue[time.Duration](s, v)
+				if err == nil {
+					b.(*array.Time64Builder).Append(arrow.Time64(d.Microseconds()))
+				}
+				return err
+			}), nil
+		case avro.TimestampMillis:
+			return timestampAvroType(s, arrow.Millisecond, "UTC")
+		case avro.TimestampMicros:
+			return timestampAvroType(s, arrow.Microsecond, "UTC")
+		case avro.LocalTimestampMillis:
+			return timestampAvroType(s, arrow.Millisecond, "")
+		case avro.LocalTimestampMicros:
+			return timestampAvroType(s, arrow.Microsecond, "")
+		}
+		return arrow.PrimitiveTypes.Int64, nullable(func(b array.Builder, v any) error {
+			x, err := avroValue[int64](s, v)
+			if err == nil {
+				b.(*array.Int64Builder).Append(x)
+			}
+			return err
+		}), nil
+	case avro.Float:
+		return arrow.PrimitiveTypes.Float32, nullable(func(b array.Builder, v any) error {
+			x, err := avroValue[float32](s, v)
+			if err == nil {
+				b.(*array.Float32Builder).Append(x)
+			}
+			return err
+		}), nil
+	case avro.Double:
+		return arrow.PrimitiveTypes.Float64, nullable(func(b array.Builder, v any) error {
+			x, err := avroValue[float64](s, v)
+			if err == nil {
+				b.(*array.Float64Builder).Append(x)
+			}
+			return err
+		}), nil
+	case avro.String:
+		return arrow.BinaryTypes.String, nullable(func(b array.Builder, v any) error {
+			x, err := avroValue[string](s, v)
+			if err == nil {
+				b.(*array.StringBuilder).Append(x)
+			}
+			return err
+		}), nil
+	case avro.Bytes:
+		if dec, ok := s.Logical().(*avro.DecimalLogicalSchema); ok && logical == avro.Decimal {
+			return decimalAvroType(s, dec)
+		}
+		return arrow.BinaryTypes.Binary, nullable(func(b array.Builder, v any) error {
+			x, err := avroValue[[]byte](s, v)
+			if err == nil {
+				b.(*array.BinaryBuilder).Append(x)
+			}
+			return err
+		}), nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported Avro type: %s", s.Type())
+}
+
+func fixedAvroType(s *avro.FixedSchema) (arrow.DataType, avroAppender, error) {
+	if ls := s.Logical(); ls != nil {
+		switch ls.Type() {
+		case avro.Decimal:
+			return decimalAvroType(s, ls.(*avro.DecimalLogicalSchema))
+		case avro.Duration:
+			return arrow.FixedWidthTypes.MonthDayNanoInterval, nullable(func(b array.Builder, v any) error {
+				d, err := avroValue[avro.LogicalDuration](s, v)
+				if err == nil {
+					b.(*array.MonthDayNanoIntervalBuilder).Append(arrow.MonthDayNanoInterval{
+						Months:      int32(d.Months),
+						Days:        int32(d.Days),
+						Nanoseconds: int64(d.Milliseconds) * int64(time.Millisecond),
+					})
+				}
+				return err
+			}), nil
+		}
+	}
+
+	size := s.Size()
+	return &arrow.FixedSizeBinaryType{ByteWidth: size}, nullable(func(b array.Builder, v any) error {
+		// Fixed values decode as byte arrays of the fixed size.
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Array || rv.Len() != size {
+			return fmt.Errorf("unexpected %T value for Avro fixed(%d)", v, size)
+		}
+		buf := make([]byte, size)
+		reflect.Copy(reflect.ValueOf(buf), rv)
+		b.(*array.FixedSizeBinaryBuilder).Append(buf)
+		return nil
+	}), nil
+}
+
+func timestampAvroType(s avro.Schema, unit arrow.TimeUnit, tz string) (arrow.DataType, avroAppender, error) {
+	return &arrow.TimestampType{Unit: unit, TimeZone: tz}, nullable(func(b array.Builder, v any) error {
+		t, err := avroValue[time.Time](s, v)
+		if err != nil {
+			return err
+		}
+		ts, err := arrow.TimestampFromTime(t, unit)
+		if err != nil {
+			return err
+		}
+		b.(*array.TimestampBuilder).Append(ts)
+		return nil
+	}), nil
+}
+
+func decimalAvroType(s avro.Schema, dec *avro.DecimalLogicalSchema) (arrow.DataType, avroAppender, error) {
+	precision, scale := int32(dec.Precision()), int32(dec.Scale())
+	unscaled := func(v any) (*big.Int, error) {
+		r, err := avroValue[*big.Rat](s, v)
+		if err != nil {
+			return nil, err
+		}
+		n := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+		n.Mul(n, r.Num())
+		return n.Quo(n, r.Denom()), nil
+	}
+
+	if precision <= decimal128.MaxPrecision {
+		return &arrow.Decimal128Type{Precision: precision, Scale: scale}, nullable(func(b array.Builder, v any) error {
+			n, err := unscaled(v)
+			if err != nil {
+				return err
+			}
+			b.(*array.Decimal128Builder).Append(decimal128.FromBigInt(n))
+			return nil
+		}), nil
+	}
+	if precision <= decimal256.MaxPrecision {
+		return &arrow.Decimal256Type{Precision: precision, Scale: scale}, nullable(func(b array.Builder, v any) error {
+			n, err := unscaled(v)
+			if err != nil {
+				return err
+			}
+			b.(*array.Decimal256Builder).Append(decimal256.FromBigInt(n))
+			return nil
+		}), nil
+	}
+	return nil, nil, fmt.Errorf("unsupported Avro decimal precision: %d", precision)
+}
+
+// nullable wraps app so that nil values are appended as nulls.
+func nullable(app avroAppender) avroAppender {
+	return func(b array.Builder, v any) error {
+		if v == nil {
+			b.AppendNull()
+			return nil
+		}
+		return app(b, v)
+	}
+}
+
+func avroValue[T any](s avro.Schema, v any) (T, error) {
+	x, ok := v.(T)
+	if !ok {
+		return x, fmt.Errorf("unexpected %T value for Avro %s", v, s.Type())
+	}
+	return x, nil
+}
@@ -0,0 +1,240 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/TFMV/arrowlake/internal/fixture"
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/hamba/avro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const shipmentsAvroSchema = `{
+	"type": "record", "name": "Shipment", "namespace": "lake.test",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "note", "type": ["null", "string"]},
+		{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "SHIPPED", "LOST"]}},
+		{"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+		{"name": "weight", "type": {"type": "fixed", "name": "Weight", "size": 8, "logicalType": "decimal", "precision": 12, "scale": 3}},
+		{"name": "shipped_at", "type": ["null", {"type": "long", "logicalType": "timestamp-micros"}]},
+		{"name": "local_at", "type": {"type": "long", "logicalType": "local-timestamp-millis"}},
+		{"name": "day", "type": {"type": "int", "logicalType": "date"}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "dims", "type": {"type": "map", "values": "double"}},
+		{"name": "origin", "type": {"type": "record", "name": "Address", "fields": [
+			{"name": "city", "type": "string"},
+			{"name": "zip", "type": ["int", "null"]}
+		]}},
+		{"name": "ref", "type": ["null", "long", "string", "Address"]}
+	]
+}`
+
+func writeAvroFixture(t *testing.T, schema string, rows []map[string]any) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, fixture.WriteAvro(&buf, schema, rows))
+	return buf.Bytes()
+}
+
+func shipmentRow(id int64) map[string]any {
+	return map[string]any{
+		"id":         id,
+		"note":       map[string]any{"string": "fragile"},
+		"status":     "SHIPPED",
+		"amount":     big.NewRat(1234567, 100),
+		"weight":     big.NewRat(2500, 1000),
+		"shipped_at": map[string]any{"long.timestamp-micros": time.Date(2024, 5, 6, 7, 8, 9, 123456000, time.UTC)},
+		"local_at":   time.Date(2024, 5, 6, 9, 8, 9, 0, time.UTC),
+		"day":        time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC),
+		"tags":       []any{"a", "b"},
+		"dims":       map[string]any{"w": 1.5, "h": 2.0},
+		"origin":     map[string]any{"city": "Nottingham", "zip": map[string]any{"int": 12345}},
+		"ref":        map[string]any{"string": "R-1"},
+	}
+}
+
+func TestReadAvro(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+	arrowInstance := NewArrow(db, WithAllocator(mem))
+
+	empty := shipmentRow(2)
+	empty["note"] = nil
+	empty["status"] = "LOST"
+	empty["shipped_at"] = nil
+	empty["tags"] = []any{}
+	empty["origin"] = map[string]any{"city": "York", "zip": nil}
+	empty["ref"] = map[string]any{"lake.test.Address": map[string]any{"city": "Leeds", "zip": nil}}
+	data := writeAvroFixture(t, shipmentsAvroSchema, []map[string]any{shipmentRow(1), empty})
+
+	rdr, err := arrowInstance.ReadAvro(bytes.NewReader(data))
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	schema := rdr.Schema()
+	require.False(t, schema.Field(0).Nullable)
+	require.True(t, schema.Field(1).Nullable)
+	require.Equal(t, &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}, schema.Field(2).Type)
+	require.Equal(t, &arrow.Decimal128Type{Precision: 10, Scale: 2}, schema.Field(3).Type)
+	require.Equal(t, &arrow.Decimal128Type{Precision: 12, Scale: 3}, schema.Field(4).Type)
+	require.Equal(t, &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}, schema.Field(5).Type)
+	require.Equal(t, &arrow.TimestampType{Unit: arrow.Millisecond}, schema.Field(6).Type)
+	require.Equal(t, arrow.FixedWidthTypes.Date32, schema.Field(7).Type)
+	require.Equal(t, "struct<long: int64, string: utf8, Address: struct<city: utf8, zip: int32>>", schema.Field(11).Type.String())
+
+	require.True(t, rdr.Next())
+	rec := rdr.Record()
+	require.Equal(t, int64(2), rec.NumRows())
+	require.False(t, rdr.Next())
+	require.NoError(t, rdr.Err())
+
+	// Round trip through DuckDB to check the values.
+	rdr2, err := arrowInstance.ReadAvro(bytes.NewReader(data))
+	require.NoError(t, err)
+	defer rdr2.Release()
+	ctx := context.Background()
+	require.NoError(t, arrowInstance.RegisterView(ctx, "shipments", rdr2))
+	defer arrowInstance.UnregisterView(ctx, "shipments")
+
+	var (
+		note, status, tags, city, ref string
+		amount, weight, width         float64
+		localAt, day                  string
+		shippedAt                     int64
+		zip                           sql.NullInt64
+	)
+	err = db.QueryRow(`
+		SELECT note, status, amount::DOUBLE, weight::DOUBLE, epoch_us(shipped_at),
+			local_at::VARCHAR, day::VARCHAR, array_to_string(tags, ','), dims['w'][1], origin.city, origin.zip, ref.string
+		FROM shipments WHERE id = 1`).Scan(
+		&note, &status, &amount, &weight, &shippedAt, &localAt, &day, &tags, &width, &city, &zip, &ref)
+	require.NoError(t, err)
+	require.Equal(t, "fragile", note)
+	require.Equal(t, "SHIPPED", status)
+	require.Equal(t, 12345.67, amount)
+	require.Equal(t, 2.5, weight)
+	require.Equal(t, time.Date(2024, 5, 6, 7, 8, 9, 123456000, time.UTC).UnixMicro(), shippedAt)
+	require.Equal(t, "2024-05-06 09:08:09", localAt)
+	require.Equal(t, "2024-05-06", day)
+	require.Equal(t, "a,b", tags)
+	require.Equal(t, 1.5, width)
+	require.Equal(t, "Nottingham", city)
+	require.Equal(t, int64(12345), zip.Int64)
+	require.Equal(t, "R-1", ref)
+
+	var nullNote, nullShipped, nullZip, nullRefString bool
+	var refCity string
+	err = db.QueryRow(`
+		SELECT note IS NULL, shipped_at IS NULL, origin.zip IS NULL, ref.string IS NULL, ref.Address.city
+		FROM shipments WHERE id = 2`).Scan(&nullNote, &nullShipped, &nullZip, &nullRefString, &refCity)
+	require.NoError(t, err)
+	require.True(t, nullNote && nullShipped && nullZip && nullRefString)
+	require.Equal(t, "Leeds", refCity)
+}
+
+func TestReadAvroBatches(t *testing.T) {
+	rows := make([]map[string]any, 5000)
+	for i := range rows {
+		rows[i] = map[string]any{"n": i}
+	}
+	data := writeAvroFixture(t, `{"type": "record", "name": "N", "fields": [{"name": "n", "type": "int"}]}`, rows)
+
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+	arrowInstance := NewArrow(db)
+	rdr, err := arrowInstance.ReadAvro(bytes.NewReader(data))
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	var sizes []int64
+	sum := 0
+	for rdr.Next() {
+		rec := rdr.Record()
+		sizes = append(sizes, rec.NumRows())
+		for _, v := range rec.Column(0).(*array.Int32).Int32Values() {
+			sum += int(v)
+		}
+	}
+	require.NoError(t, rdr.Err())
+	require.Equal(t, []int64{2048, 2048, 904}, sizes)
+	require.Equal(t, 4999*5000/2, sum)
+}
+
+func TestReadAvroSchemas(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+	arrowInstance := NewArrow(db)
+
+	data := writeAvroFixture(t, `"string"`, []map[string]any{})
+	rdr, err := arrowInstance.ReadAvro(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, "value", rdr.Schema().Field(0).Name)
+	require.False(t, rdr.Next())
+	rdr.Release()
+
+	recursive := `{"type": "record", "name": "Node", "fields": [
+		{"name": "value", "type": "int"},
+		{"name": "next", "type": ["null", "Node"]}
+	]}`
+	data = writeAvroFixture(t, recursive, nil)
+	_, err = arrowInstance.ReadAvro(bytes.NewReader(data))
+	require.ErrorContains(t, err, "recursive Avro record Node")
+
+	_, err = arrowInstance.ReadAvro(bytes.NewReader([]byte("not avro")))
+	require.ErrorContains(t, err, "failed to read Avro file")
+}
+
+func TestAvroAppendMismatch(t *testing.T) {
+	mem := memory.NewGoAllocator()
+	for _, typ := range []avro.Type{avro.Boolean, avro.Int, avro.Long, avro.Float, avro.Double, avro.String, avro.Bytes} {
+		dt, app, err := primitiveAvroType(avro.NewPrimitiveSchema(typ, nil))
+		require.NoError(t, err)
+
+		b := array.NewBuilder(mem, dt)
+		require.Error(t, app(b, struct{}{}), typ)
+		require.Equal(t, 0, b.Len(), typ)
+		b.Release()
+	}
+}
@@ -0,0 +1,567 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/decimal128"
+	"github.com/apache/arrow/go/v17/arrow/decimal256"
+)
+
+// timeLayouts are the textual timestamp formats drivers commonly return,
+// most specific first.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// appendConverted appends a value whose Go type does not match b directly,
+// as returned by drivers other than DuckDB: integers widened to int64, text
+// and numbers as []byte, timestamps as strings and so on.
+func appendConverted(b array.Builder, v any) error {
+	if valuer, ok := v.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		return appendValue(b, value)
+	}
+
+	var err error
+	switch b := b.(type) {
+	case *array.BooleanBuilder:
+		var x bool
+		if x, err = asBool(v); err == nil {
+			b.Append(x)
+		}
+	case *array.Int8Builder:
+		var x int64
+		if x, err = asInt(v, 8); err == nil {
+			b.Append(int8(x))
+		}
+	case *array.Int16Builder:
+		var x int64
+		if x, err = asInt(v, 16); err == nil {
+			b.Append(int16(x))
+		}
+	case *array.Int32Builder:
+		var x int64
+		if x, err = asInt(v, 32); err == nil {
+			b.Append(int32(x))
+		}
+	case *array.Int64Builder:
+		var x int64
+		if x, err = asInt(v, 64); err == nil {
+			b.Append(x)
+		}
+	case *array.Uint8Builder:
+		var x uint64
+		if x, err = asUint(v, 8); err == nil {
+			b.Append(uint8(x))
+		}
+	case *array.Uint16Builder:
+		var x uint64
+		if x, err = asUint(v, 16); err == nil {
+			b.Append(uint16(x))
+		}
+	case *array.Uint32Builder:
+		var x uint64
+		if x, err = asUint(v, 32); err == nil {
+			b.Append(uint32(x))
+		}
+	case *array.Uint64Builder:
+		var x uint64
+		if x, err = asUint(v, 64); err == nil {
+			b.Append(x)
+		}
+	case *array.Float32Builder:
+		var x float64
+		if x, err = asFloat(v); err == nil {
+			b.Append(float32(x))
+		}
+	case *array.Float64Builder:
+		var x float64
+		if x, err = asFloat(v); err == nil {
+			b.Append(x)
+		}
+	case *array.StringBuilder:
+		var x string
+		if x, err = asString(v); err == nil {
+			b.Append(x)
+		}
+	case *array.BinaryBuilder:
+		var x []byte
+		if x, err = asBytes(v); err == nil {
+			b.Append(x)
+		}
+	case *array.FixedSizeBinaryBuilder:
+		var x []byte
+		if x, err = asFixedBytes(v, b.Type().(*arrow.FixedSizeBinaryType).ByteWidth); err == nil {
+			b.Append(x)
+		}
+	case *array.Date32Builder:
+		var x time.Time
+		if x, err = asTime(v); err == nil {
+			b.Append(arrow.Date32FromTime(x))
+		}
+	case *array.Time64Builder:
+		var x time.Duration
+		if x, err = asTimeOfDay(v); err == nil {
+			b.Append(arrow.Time64(x / b.Type().(*arrow.Time64Type).Unit.Multiplier()))
+		}
+	case *array.TimestampBuilder:
+		var x time.Time
+		if x, err = asTime(v); err == nil {
+			var ts arrow.Timestamp
+			if ts, err = arrow.TimestampFromTime(x, b.Type().(*arrow.TimestampType).Unit); err == nil {
+				b.Append(ts)
+			}
+		}
+	case *array.MonthDayNanoIntervalBuilder:
+		var x arrow.MonthDayNanoInterval
+		if x, err = asInterval(v); err == nil {
+			b.Append(x)
+		}
+	case *array.Decimal128Builder:
+		dt := b.Type().(*arrow.Decimal128Type)
+		var s string
+		if s, err = asDecimalString(v); err == nil {
+			var n decimal128.Num
+			if n, err = decimal128.FromString(s, dt.Precision, dt.Scale); err == nil {
+				b.Append(n)
+			}
+		}
+	case *array.Decimal256Builder:
+		dt := b.Type().(*arrow.Decimal256Type)
+		var s string
+		if s, err = asDecimalString(v); err == nil {
+			var n decimal256.Num
+			if n, err = decimal256.FromString(s, dt.Precision, dt.Scale); err == nil {
+				b.Append(n)
+			}
+		}
+	default:
+		err = errUnexpectedValue
+	}
+
+	if err == errUnexpectedValue {
+		return fmt.Errorf("unexpected value of type %T for %s column", v, b.Type())
+	}
+	return err
+}
+
+var errUnexpectedValue = errors.New("unexpected value")
+
+func asBool(v any) (bool, error) {
+	switch x := v.(type) {
+	case int64:
+		return x != 0, nil
+	case string:
+		return strconv.ParseBool(x)
+	case []byte:
+		return strconv.ParseBool(string(x))
+	}
+	return false, errUnexpectedValue
+}
+
+func asInt(v any, bits int) (int64, error) {
+	var n int64
+	switch x := v.(type) {
+	case int:
+		n = int64(x)
+	case int8:
+		n = int64(x)
+	case int16:
+		n = int64(x)
+	case int32:
+		n = int64(x)
+	case int64:
+		n = x
+	case uint8:
+		n = int64(x)
+	case uint16:
+		n = int64(x)
+	case uint32:
+		n = int64(x)
+	case uint64:
+		if x > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d overflows int%d", x, bits)
+		}
+		n = int64(x)
+	case float64:
+		if x != math.Trunc(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+			return 0, fmt.Errorf("value %v is not an int%d", x, bits)
+		}
+		n = int64(x)
+	case bool:
+		if x {
+			n = 1
+		}
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(x), 10, bits)
+	case []byte:
+		return strconv.ParseInt(strings.TrimSpace(string(x)), 10, bits)
+	default:
+		return 0, errUnexpectedValue
+	}
+
+	if bits < 64 && (n < -1<<(bits-1) || n > 1<<(bits-1)-1) {
+		return 0, fmt.Errorf("value %d overflows int%d", n, bits)
+	}
+	return n, nil
+}
+
+func asUint(v any, bits int) (uint64, error) {
+	var n uint64
+	switch x := v.(type) {
+	case string:
+		return strconv.ParseUint(strings.TrimSpace(x), 10, bits)
+	case []byte:
+		return strconv.ParseUint(strings.TrimSpace(string(x)), 10, bits)
+	case uint64:
+		n = x
+	default:
+		i, err := asInt(v, 64)
+		if err != nil {
+			return 0, err
+		}
+		if i < 0 {
+			return 0, fmt.Errorf("value %d overflows uint%d", i, bits)
+		}
+		n = uint64(i)
+	}
+
+	if bits < 64 && n > 1<<bits-1 {
+		return 0, fmt.Errorf("value %d overflows uint%d", n, bits)
+	}
+	return n, nil
+}
+
+func asFloat(v any) (float64, error) {
+	switch x := v.(type) {
+	case float32:
+		return float64(x), nil
+	case float64:
+		return x, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(x), 64)
+	case []byte:
+		return strconv.ParseFloat(strings.TrimSpace(string(x)), 64)
+	}
+	n, err := asInt(v, 64)
+	return float64(n), err
+}
+
+func asString(v any) (string, error) {
+	switch x := v.(type) {
+	case []byte:
+		return string(x), nil
+	case time.Time:
+		return x.Format(time.RFC3339Nano), nil
+	case bool, int, int8, int16, int32, int64, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(x), nil
+	case fmt.Stringer:
+		return x.String(), nil
+	}
+	return "", errUnexpectedValue
+}
+
+func asBytes(v any) ([]byte, error) {
+	if s, ok := v.(string); ok {
+		return []byte(s), nil
+	}
+	return nil, errUnexpectedValue
+}
+
+// asFixedBytes also accepts the canonical text form of a UUID, which is how
+// most drivers return them.
+func asFixedBytes(v any, width int) ([]byte, error) {
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = x
+	case [16]byte:
+		return x[:], nil
+	default:
+		return nil, errUnexpectedValue
+	}
+
+	if width == 16 && len(s) == 36 {
+		b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid uuid %q: %w", s, err)
+		}
+		return b, nil
+	}
+	if len(s) != width {
+		return nil, fmt.Errorf("value of length %d does not fit fixed size binary of width %d", len(s), width)
+	}
+	return []byte(s), nil
+}
+
+func asTime(v any) (time.Time, error) {
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = x
+	case []byte:
+		s = string(x)
+	case int64:
+		return time.Unix(x, 0).UTC(), nil
+	default:
+		return time.Time{}, errUnexpectedValue
+	}
+
+	return ParseTime(s)
+}
+
+// ParseTime parses text the way date and timestamp columns read from drivers
+// other than DuckDB are parsed.
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as a timestamp", s)
+}
+
+func asTimeOfDay(v any) (time.Duration, error) {
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = x
+	case []byte:
+		s = string(x)
+	default:
+		return 0, errUnexpectedValue
+	}
+
+	t, err := time.Parse("15:04:05.999999999", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %q as a time of day: %w", s, err)
+	}
+	return timeOfDay(t), nil
+}
+
+// timeOfDay returns the time elapsed since midnight of t's day.
+func timeOfDay(t time.Time) time.Duration {
+	h, m, s := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(s)*time.Second + time.Duration(t.Nanosecond())
+}
+
+// asInterval parses the text forms of a PostgreSQL interval: the default
+// "1 year 2 mons 3 days 04:05:06", the verbose "@ 1 year 2 mons ago" and ISO
+// 8601 "P1Y2M3DT4H5M6S".
+func asInterval(v any) (arrow.MonthDayNanoInterval, error) {
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = x
+	case []byte:
+		s = string(x)
+	default:
+		return arrow.MonthDayNanoInterval{}, errUnexpectedValue
+	}
+
+	s = strings.TrimSpace(s)
+	var (
+		iv arrow.MonthDayNanoInterval
+		ok bool
+	)
+	if strings.HasPrefix(s, "P") {
+		iv, ok = parseISOInterval(s[1:])
+	} else {
+		iv, ok = parsePGInterval(s)
+	}
+	if !ok {
+		return arrow.MonthDayNanoInterval{}, fmt.Errorf("cannot parse %q as an interval", s)
+	}
+	return iv, nil
+}
+
+func parsePGInterval(s string) (arrow.MonthDayNanoInterval, bool) {
+	var iv arrow.MonthDayNanoInterval
+	fields := strings.Fields(strings.TrimPrefix(s, "@"))
+	ago := len(fields) > 0 && fields[len(fields)-1] == "ago"
+	if ago {
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) == 0 {
+		return iv, false
+	}
+
+	for i := 0; i < len(fields); i++ {
+		if strings.Contains(fields[i], ":") {
+			nanos, ok := parseClock(fields[i])
+			if !ok {
+				return iv, false
+			}
+			iv.Nanoseconds += nanos
+			continue
+		}
+		if i+1 == len(fields) {
+			return iv, false
+		}
+		n, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return iv, false
+		}
+		i++
+		unit := strings.ToLower(fields[i])
+		if len(unit) > 2 {
+			unit = strings.TrimSuffix(unit, "s")
+		}
+		if !addIntervalUnit(&iv, n, unit) {
+			return iv, false
+		}
+	}
+	if ago {
+		iv = arrow.MonthDayNanoInterval{Months: -iv.Months, Days: -iv.Days, Nanoseconds: -iv.Nanoseconds}
+	}
+	return iv, true
+}
+
+// parseClock parses a signed [h]h:mm[:ss[.f]] duration into nanoseconds.
+func parseClock(s string) (int64, bool) {
+	neg := strings.HasPrefix(s, "-")
+	parts := strings.Split(strings.TrimLeft(s, "+-"), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	h, err1 := strconv.ParseInt(parts[0], 10, 64)
+	m, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+	d := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute
+	if len(parts) == 3 {
+		sec, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return 0, false
+		}
+		d += time.Duration(math.Round(sec * float64(time.Second)))
+	}
+	if neg {
+		d = -d
+	}
+	return int64(d), true
+}
+
+func addIntervalUnit(iv *arrow.MonthDayNanoInterval, n float64, unit string) bool {
+	whole := n == math.Trunc(n)
+	switch unit {
+	case "year", "yr", "y":
+		iv.Months += int32(n * 12)
+		return n*12 == math.Trunc(n*12)
+	case "mon", "month":
+		iv.Months += int32(n)
+		return whole
+	case "week", "w":
+		iv.Days += int32(n * 7)
+		return n*7 == math.Trunc(n*7)
+	case "day", "d":
+		iv.Days += int32(n)
+		return whole
+	case "hour", "hr", "h":
+		iv.Nanoseconds += int64(math.Round(n * float64(time.Hour)))
+	case "min", "minute", "m":
+		iv.Nanoseconds += int64(math.Round(n * float64(time.Minute)))
+	case "sec", "second":
+		iv.Nanoseconds += int64(math.Round(n * float64(time.Second)))
+	case "millisecond", "msec", "ms":
+		iv.Nanoseconds += int64(math.Round(n * float64(time.Millisecond)))
+	case "microsecond", "usec", "us":
+		iv.Nanoseconds += int64(math.Round(n * float64(time.Microsecond)))
+	default:
+		return false
+	}
+	return true
+}
+
+// parseISOInterval parses the part of an ISO 8601 duration after the P.
+func parseISOInterval(s string) (arrow.MonthDayNanoInterval, bool) {
+	var iv arrow.MonthDayNanoInterval
+	inTime := false
+	for s != "" {
+		if s[0] == 'T' {
+			if inTime {
+				return iv, false
+			}
+			inTime, s = true, s[1:]
+			continue
+		}
+		end := strings.IndexFunc(s, func(r rune) bool { return r >= 'A' && r <= 'Z' })
+		if end <= 0 {
+			return iv, false
+		}
+		n, err := strconv.ParseFloat(s[:end], 64)
+		if err != nil {
+			return iv, false
+		}
+		unit := map[byte]string{'Y': "year", 'M': "mon", 'W': "week", 'D': "day"}[s[end]]
+		if inTime {
+			unit = map[byte]string{'H': "hour", 'M': "min", 'S': "sec"}[s[end]]
+		}
+		if unit == "" || !addIntervalUnit(&iv, n, unit) {
+			return iv, false
+		}
+		s = s[end+1:]
+	}
+	return iv, true
+}
+
+func asDecimalString(v any) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return strings.TrimSpace(x), nil
+	case []byte:
+		return strings.TrimSpace(string(x)), nil
+	case float32, float64, int, int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(x), nil
+	}
+	return "", errUnexpectedValue
+}
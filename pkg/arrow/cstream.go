@@ -0,0 +1,52 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+
+	"github.com/apache/arrow/go/v17/arrow/cdata"
+)
+
+// ExportCStream runs query and exports its results through the Arrow C
+// Stream interface, so C and other cgo hosts can consume the record batches
+// without copying them. out must be zero-initialized. The consumer owns the
+// stream and must call its release callback; ctx must stay alive until then.
+func (a *Arrow) ExportCStream(ctx context.Context, out *cdata.CArrowArrayStream, query string, args ...interface{}) error {
+	rdr, err := a.QueryArrowNative(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	// The stream retains the reader until the consumer releases it.
+	cdata.ExportRecordReader(rdr, out)
+	rdr.Release()
+	return nil
+}
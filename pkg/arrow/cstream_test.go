@@ -0,0 +1,66 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/cdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportCStream(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+	var stream cdata.CArrowArrayStream
+	err = arrowInstance.ExportCStream(context.Background(), &stream, "SELECT range AS id, 'row ' || range AS name FROM range(5000)")
+	require.NoError(t, err)
+
+	imported, err := cdata.ImportCRecordReader(&stream, nil)
+	require.NoError(t, err)
+	rdr := imported.(array.RecordReader)
+
+	require.Equal(t, "id", rdr.Schema().Field(0).Name)
+	rows := 0
+	for rdr.Next() {
+		rows += int(rdr.Record().NumRows())
+	}
+	require.NoError(t, rdr.Err())
+	require.Equal(t, 5000, rows)
+
+	var failed cdata.CArrowArrayStream
+	require.Error(t, arrowInstance.ExportCStream(context.Background(), &failed, "SELECT missing"))
+}
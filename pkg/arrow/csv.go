@@ -0,0 +1,162 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow/array"
+)
+
+// CSVOptions configures CSV output.
+type CSVOptions struct {
+	// Delimiter separates fields. It defaults to ','.
+	Delimiter rune
+	// Quote encloses fields that need quoting. It defaults to '"'.
+	Quote rune
+	// QuoteAll quotes every non-null field instead of only those containing
+	// the delimiter, the quote, a line break, or the null representation.
+	QuoteAll bool
+	// NullValue is written for nulls. It defaults to the empty string.
+	NullValue string
+	NoHeader  bool
+	UseCRLF   bool
+
+	// BatchSize is the number of rows read per record when exporting query
+	// results. It defaults to 2048.
+	BatchSize int
+}
+
+// ExportCSV runs query and writes its results to w as CSV.
+func (a *Arrow) ExportCSV(ctx context.Context, w io.Writer, opts CSVOptions, query string, args ...interface{}) error {
+	rdr, err := a.QueryArrowStream(ctx, exportBatchSize(opts.BatchSize), query, args...)
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	return WriteCSV(w, rdr, opts)
+}
+
+// WriteCSV writes the records of rdr to w as CSV, one record at a time.
+// Timestamps, dates and times are written in ISO 8601 form, decimals with
+// their full scale, binary values in base64, and nested values as JSON.
+func WriteCSV(w io.Writer, rdr array.RecordReader, opts CSVOptions) error {
+	if opts.Delimiter == 0 {
+		opts.Delimiter = ','
+	}
+	if opts.Quote == 0 {
+		opts.Quote = '"'
+	}
+	if opts.Delimiter == opts.Quote {
+		return fmt.Errorf("csv delimiter and quote must differ")
+	}
+
+	cw := &csvWriter{w: bufio.NewWriter(w), opts: opts, special: string([]rune{opts.Delimiter, opts.Quote, '\r', '\n'})}
+	if opts.UseCRLF {
+		cw.eol = "\r\n"
+	} else {
+		cw.eol = "\n"
+	}
+
+	uuids := make([]bool, rdr.Schema().NumFields())
+	for i, f := range rdr.Schema().Fields() {
+		uuids[i] = isUUID(f)
+		if !opts.NoHeader {
+			cw.field(i, f.Name, false)
+		}
+	}
+	if !opts.NoHeader {
+		cw.w.WriteString(cw.eol)
+	}
+
+	var buf []byte
+	for rdr.Next() {
+		rec := rdr.Record()
+		for row := 0; row < int(rec.NumRows()); row++ {
+			for i, col := range rec.Columns() {
+				if isNull(col, row) {
+					cw.field(i, opts.NullValue, true)
+					continue
+				}
+				var err error
+				if uuids[i] {
+					buf = appendUUID(buf[:0], col.(*array.FixedSizeBinary).Value(row), false)
+				} else if buf, err = appendText(buf[:0], col, row, false); err != nil {
+					return fmt.Errorf("column %s: %w", rec.ColumnName(i), err)
+				}
+				cw.field(i, string(buf), false)
+			}
+			cw.w.WriteString(cw.eol)
+		}
+		if err := cw.w.Flush(); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+	}
+	if err := rdr.Err(); err != nil {
+		return err
+	}
+	if err := cw.w.Flush(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
+
+type csvWriter struct {
+	w       *bufio.Writer
+	opts    CSVOptions
+	special string
+	eol     string
+}
+
+// field writes one field, preceded by the delimiter unless it is the first.
+// Errors surface when the buffer is flushed.
+func (cw *csvWriter) field(i int, s string, null bool) {
+	if i > 0 {
+		cw.w.WriteRune(cw.opts.Delimiter)
+	}
+	if null {
+		cw.w.WriteString(s)
+		return
+	}
+	if !cw.opts.QuoteAll && s != cw.opts.NullValue && !strings.ContainsAny(s, cw.special) {
+		cw.w.WriteString(s)
+		return
+	}
+
+	q := string(cw.opts.Quote)
+	cw.w.WriteString(q)
+	cw.w.WriteString(strings.ReplaceAll(s, q, q+q))
+	cw.w.WriteString(q)
+}
@@ -0,0 +1,148 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+)
+
+// formatQuery covers the value kinds the text writers format specially.
+const formatQuery = `SELECT 1::INTEGER AS i, 'NaN'::DOUBLE AS nan, 12.30::DECIMAL(10,2) AS d,
+	'a,b "c"' || chr(10) || 'x' AS s, '' AS empty, NULL::VARCHAR AS n,
+	DATE '2024-01-02' AS dt, TIME '03:04:05.25' AS tm, TIMESTAMP '2024-01-02 03:04:05.5' AS ts,
+	TIMESTAMPTZ '2024-01-02 03:04:05+00' AS tstz, '\x01\x02'::BLOB AS b, [1, NULL, 3] AS l,
+	{'x': 1, 'y': 'z'} AS st, MAP {'k': 1} AS m, 'ok'::mood AS e,
+	'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11'::UUID AS u, true AS bo`
+
+func setupFormatDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+
+	_, err = db.Exec(`CREATE TYPE mood AS ENUM ('ok', 'sad')`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestExportCSV(t *testing.T) {
+	db := setupFormatDB(t)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, NewArrow(db).ExportCSV(context.Background(), &buf, CSVOptions{}, formatQuery))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, []string{"i", "nan", "d", "s", "empty", "n", "dt", "tm", "ts", "tstz", "b", "l", "st", "m", "e", "u", "bo"}, records[0])
+	require.Equal(t, []string{
+		"1", "NaN", "12.30", "a,b \"c\"\nx", "", "",
+		"2024-01-02", "03:04:05.25", "2024-01-02T03:04:05.5",
+		"2024-01-02T03:04:05Z", "AQI=", "[1,null,3]",
+		`{"x":1,"y":"z"}`, `{"k":1}`, "ok",
+		"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", "true",
+	}, records[1])
+}
+
+func TestExportCSVOptions(t *testing.T) {
+	db := setupFormatDB(t)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+	query := "SELECT * FROM (VALUES (1, 'a;b', NULL), (2, '', 'NULL'), (3, 'it''s', 'x')) t(id, s, v) ORDER BY id"
+
+	var buf bytes.Buffer
+	opts := CSVOptions{Delimiter: ';', Quote: '\'', NullValue: "NULL", UseCRLF: true, BatchSize: 1}
+	require.NoError(t, arrowInstance.ExportCSV(context.Background(), &buf, opts, query))
+	require.Equal(t, "id;s;v\r\n1;'a;b';NULL\r\n2;;'NULL'\r\n3;'it''s';x\r\n", buf.String())
+
+	buf.Reset()
+	opts = CSVOptions{QuoteAll: true, NoHeader: true}
+	require.NoError(t, arrowInstance.ExportCSV(context.Background(), &buf, opts, query))
+	require.Equal(t, `"1","a;b",`+"\n"+`"2","","NULL"`+"\n"+`"3","it's","x"`+"\n", buf.String())
+
+	err := arrowInstance.ExportCSV(context.Background(), &buf, CSVOptions{Delimiter: '"'}, query)
+	require.Error(t, err)
+}
+
+func TestWriteCSVEmpty(t *testing.T) {
+	db := setupFormatDB(t)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	err := NewArrow(db).ExportCSV(context.Background(), &buf, CSVOptions{}, "SELECT 1 AS a, 'x' AS b WHERE false")
+	require.NoError(t, err)
+	require.Equal(t, "a,b", strings.TrimSpace(buf.String()))
+}
+
+func TestWriteUnionNulls(t *testing.T) {
+	dt, err := arrowType("UNION(num INTEGER, str VARCHAR)")
+	require.NoError(t, err)
+
+	mem := memory.NewGoAllocator()
+	ub := array.NewBuilder(mem, dt)
+	defer ub.Release()
+	lb := array.NewListBuilder(mem, dt)
+	defer lb.Release()
+	for _, v := range []any{map[string]any{"num": int32(7)}, nil, map[string]any{"str": "seven"}} {
+		require.NoError(t, appendValue(ub, v))
+		lb.Append(true)
+		require.NoError(t, appendValue(lb.ValueBuilder(), v))
+	}
+	u, l := ub.NewArray(), lb.NewArray()
+	defer u.Release()
+	defer l.Release()
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "u", Type: dt, Nullable: true}, {Name: "l", Type: l.DataType(), Nullable: true}}, nil)
+	rec := array.NewRecord(schema, []arrow.Array{u, l}, 3)
+	defer rec.Release()
+	reader := func() array.RecordReader {
+		rdr, err := array.NewRecordReader(schema, []arrow.Record{rec})
+		require.NoError(t, err)
+		return rdr
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(&buf, reader(), CSVOptions{NullValue: "NULL"}))
+	require.Equal(t, "u,l\n7,[7]\nNULL,[null]\nseven,\"[\"\"seven\"\"]\"\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteJSON(&buf, reader(), JSONOptions{Lines: true}))
+	require.Equal(t, "{\"u\":7,\"l\":[7]}\n{\"u\":null,\"l\":[null]}\n{\"u\":\"seven\",\"l\":[\"seven\"]}\n", buf.String())
+}
@@ -0,0 +1,321 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+)
+
+// Layouts used when formatting temporal values as text. Timestamps with a
+// time zone carry their offset; those without one are written as wall time.
+const (
+	dateLayout        = "2006-01-02"
+	timeLayout        = "15:04:05.999999999"
+	timestampLayout   = "2006-01-02T15:04:05.999999999"
+	timestampTZLayout = time.RFC3339Nano
+)
+
+const jsonNull = "null"
+
+// appendText appends the text of value i of arr. Scalars are written bare
+// unless quote is set, in which case the result is valid JSON; nested values
+// are always written as JSON. Nulls must be handled by the caller.
+func appendText(b []byte, arr arrow.Array, i int, quote bool) ([]byte, error) {
+	str := func(s string) []byte {
+		if quote {
+			return appendJSONString(b, s)
+		}
+		return append(b, s...)
+	}
+
+	switch a := arr.(type) {
+	case *array.Null:
+		return append(b, jsonNull...), nil
+	case *array.Boolean:
+		return strconv.AppendBool(b, a.Value(i)), nil
+	case *array.Int8:
+		return strconv.AppendInt(b, int64(a.Value(i)), 10), nil
+	case *array.Int16:
+		return strconv.AppendInt(b, int64(a.Value(i)), 10), nil
+	case *array.Int32:
+		return strconv.AppendInt(b, int64(a.Value(i)), 10), nil
+	case *array.Int64:
+		return strconv.AppendInt(b, a.Value(i), 10), nil
+	case *array.Uint8:
+		return strconv.AppendUint(b, uint64(a.Value(i)), 10), nil
+	case *array.Uint16:
+		return strconv.AppendUint(b, uint64(a.Value(i)), 10), nil
+	case *array.Uint32:
+		return strconv.AppendUint(b, uint64(a.Value(i)), 10), nil
+	case *array.Uint64:
+		return strconv.AppendUint(b, a.Value(i), 10), nil
+	case *array.Float16:
+		return appendFloat(b, float64(a.Value(i).Float32()), 32, quote), nil
+	case *array.Float32:
+		return appendFloat(b, float64(a.Value(i)), 32, quote), nil
+	case *array.Float64:
+		return appendFloat(b, a.Value(i), 64, quote), nil
+	case *array.Decimal128:
+		return append(b, a.Value(i).ToString(a.DataType().(*arrow.Decimal128Type).Scale)...), nil
+	case *array.Decimal256:
+		return append(b, a.Value(i).ToString(a.DataType().(*arrow.Decimal256Type).Scale)...), nil
+	case *array.String:
+		return str(a.Value(i)), nil
+	case *array.LargeString:
+		return str(a.Value(i)), nil
+	case *array.StringView:
+		return str(a.Value(i)), nil
+	case *array.Binary:
+		return str(base64.StdEncoding.EncodeToString(a.Value(i))), nil
+	case *array.LargeBinary:
+		return str(base64.StdEncoding.EncodeToString(a.Value(i))), nil
+	case *array.BinaryView:
+		return str(base64.StdEncoding.EncodeToString(a.Value(i))), nil
+	case *array.FixedSizeBinary:
+		return str(base64.StdEncoding.EncodeToString(a.Value(i))), nil
+	case *array.Date32:
+		return str(a.Value(i).ToTime().Format(dateLayout)), nil
+	case *array.Date64:
+		return str(a.Value(i).ToTime().Format(dateLayout)), nil
+	case *array.Time32:
+		unit := a.DataType().(*arrow.Time32Type).Unit
+		return str(a.Value(i).ToTime(unit).Format(timeLayout)), nil
+	case *array.Time64:
+		unit := a.DataType().(*arrow.Time64Type).Unit
+		return str(a.Value(i).ToTime(unit).Format(timeLayout)), nil
+	case *array.Timestamp:
+		dt := a.DataType().(*arrow.TimestampType)
+		toTime, err := dt.GetToTimeFunc()
+		if err != nil {
+			return nil, err
+		}
+		if dt.TimeZone == "" {
+			return str(toTime(a.Value(i)).Format(timestampLayout)), nil
+		}
+		return str(toTime(a.Value(i)).Format(timestampTZLayout)), nil
+	case *array.Duration:
+		unit := a.DataType().(*arrow.DurationType).Unit
+		return str((time.Duration(a.Value(i)) * unit.Multiplier()).String()), nil
+	case *array.MonthInterval:
+		return strconv.AppendInt(b, int64(a.Value(i)), 10), nil
+	case *array.DayTimeInterval:
+		v := a.Value(i)
+		return fmt.Appendf(b, `{"days":%d,"milliseconds":%d}`, v.Days, v.Milliseconds), nil
+	case *array.MonthDayNanoInterval:
+		v := a.Value(i)
+		return fmt.Appendf(b, `{"months":%d,"days":%d,"nanoseconds":%d}`, v.Months, v.Days, v.Nanoseconds), nil
+	case *array.Dictionary:
+		return appendText(b, a.Dictionary(), a.GetValueIndex(i), quote)
+	case *array.DenseUnion:
+		return appendUnionChild(b, a.Field(a.ChildID(i)), int(a.ValueOffset(i)), quote)
+	case *array.SparseUnion:
+		return appendUnionChild(b, a.Field(a.ChildID(i)), i, quote)
+	case *array.RunEndEncoded:
+		return appendText(b, a.Values(), a.GetPhysicalIndex(i), quote)
+	case *array.Map:
+		return appendMap(b, a, i)
+	case array.ListLike:
+		return appendList(b, a, i)
+	case *array.Struct:
+		return appendStruct(b, a, i)
+	default:
+		v, err := json.Marshal(arr.GetOneForMarshal(i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to format %s value: %w", arr.DataType(), err)
+		}
+		return append(b, v...), nil
+	}
+}
+
+// appendFloat writes NaN and infinities, which JSON numbers cannot hold, as
+// strings.
+func appendFloat(b []byte, f float64, bits int, quote bool) []byte {
+	var special string
+	switch {
+	case math.IsNaN(f):
+		special = "NaN"
+	case math.IsInf(f, 1):
+		special = "Infinity"
+	case math.IsInf(f, -1):
+		special = "-Infinity"
+	default:
+		return strconv.AppendFloat(b, f, 'g', -1, bits)
+	}
+	if quote {
+		return appendJSONString(b, special)
+	}
+	return append(b, special...)
+}
+
+// isUUID reports whether f holds DuckDB UUIDs, which are written in their
+// canonical text form rather than as base64.
+func isUUID(f arrow.Field) bool {
+	dbType, _ := f.Metadata.GetValue(MetadataDBType)
+	fsb, ok := f.Type.(*arrow.FixedSizeBinaryType)
+	return ok && fsb.ByteWidth == 16 && dbType == "UUID"
+}
+
+func appendUUID(b []byte, v []byte, quote bool) []byte {
+	const hex = "0123456789abcdef"
+	if quote {
+		b = append(b, '"')
+	}
+	for i, c := range v {
+		if i == 4 || i == 6 || i == 8 || i == 10 {
+			b = append(b, '-')
+		}
+		b = append(b, hex[c>>4], hex[c&0xf])
+	}
+	if quote {
+		b = append(b, '"')
+	}
+	return b
+}
+
+// appendJSONString appends s as a JSON string. Invalid UTF-8 is replaced
+// with U+FFFD.
+func appendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b = append(b, '\\', byte(r))
+		case r == '\n':
+			b = append(b, '\\', 'n')
+		case r == '\r':
+			b = append(b, '\\', 'r')
+		case r == '\t':
+			b = append(b, '\\', 't')
+		case r < 0x20:
+			b = append(b, '\\', 'u', '0', '0', hex[r>>4], hex[r&0xf])
+		default:
+			b = utf8.AppendRune(b, r)
+		}
+	}
+	return append(b, '"')
+}
+
+// appendJSON appends value i of arr as JSON, including nulls.
+func appendJSON(b []byte, arr arrow.Array, i int) ([]byte, error) {
+	if isNull(arr, i) {
+		return append(b, jsonNull...), nil
+	}
+	return appendText(b, arr, i, true)
+}
+
+// appendUnionChild appends the selected child value of a union, which may be
+// null even though the union itself has no validity bitmap.
+func appendUnionChild(b []byte, child arrow.Array, i int, quote bool) ([]byte, error) {
+	if isNull(child, i) {
+		if quote {
+			return append(b, jsonNull...), nil
+		}
+		return b, nil
+	}
+	return appendText(b, child, i, quote)
+}
+
+// isNull reports whether value i of arr is null. Unions hold their nulls in
+// the selected child rather than in a validity bitmap of their own.
+func isNull(arr arrow.Array, i int) bool {
+	switch a := arr.(type) {
+	case *array.DenseUnion:
+		return isNull(a.Field(a.ChildID(i)), int(a.ValueOffset(i)))
+	case *array.SparseUnion:
+		return isNull(a.Field(a.ChildID(i)), i)
+	}
+	return arr.IsNull(i)
+}
+
+func appendList(b []byte, a array.ListLike, i int) ([]byte, error) {
+	start, end := a.ValueOffsets(i)
+	values := a.ListValues()
+	b = append(b, '[')
+	var err error
+	for j := start; j < end; j++ {
+		if j > start {
+			b = append(b, ',')
+		}
+		if b, err = appendJSON(b, values, int(j)); err != nil {
+			return nil, err
+		}
+	}
+	return append(b, ']'), nil
+}
+
+// appendMap writes a map as a JSON object. Keys that are not strings use
+// their JSON text.
+func appendMap(b []byte, a *array.Map, i int) ([]byte, error) {
+	start, end := a.ValueOffsets(i)
+	keys, items := a.Keys(), a.Items()
+	b = append(b, '{')
+	for j := start; j < end; j++ {
+		if j > start {
+			b = append(b, ',')
+		}
+		key, err := appendText(nil, keys, int(j), false)
+		if err != nil {
+			return nil, err
+		}
+		b = appendJSONString(b, string(key))
+		b = append(b, ':')
+		if b, err = appendJSON(b, items, int(j)); err != nil {
+			return nil, err
+		}
+	}
+	return append(b, '}'), nil
+}
+
+func appendStruct(b []byte, a *array.Struct, i int) ([]byte, error) {
+	st := a.DataType().(*arrow.StructType)
+	b = append(b, '{')
+	var err error
+	for j, f := range st.Fields() {
+		if j > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, f.Name)
+		b = append(b, ':')
+		if b, err = appendJSON(b, a.Field(j), i); err != nil {
+			return nil, err
+		}
+	}
+	return append(b, '}'), nil
+}
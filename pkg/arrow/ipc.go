@@ -0,0 +1,355 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/ipc"
+)
+
+// IPCCompression selects the body compression of exported IPC data.
+type IPCCompression int
+
+const (
+	IPCCompressionNone IPCCompression = iota
+	IPCCompressionLZ4
+	IPCCompressionZSTD
+)
+
+// IPCOptions configures Arrow IPC export.
+type IPCOptions struct {
+	Compression IPCCompression
+	// DictionaryDeltas lets stream writers send only the new entries of a
+	// grown dictionary. The file format allows one dictionary per field, so
+	// file writers unify the dictionaries of all batches instead.
+	DictionaryDeltas bool
+	// BatchSize is the number of rows per record batch of exported query
+	// results. It defaults to 2048.
+	BatchSize int
+}
+
+func (o IPCOptions) writerOptions(a *Arrow, schema *arrow.Schema) ([]ipc.Option, error) {
+	opts := []ipc.Option{ipc.WithSchema(schema), ipc.WithAllocator(a.mem), ipc.WithDictionaryDeltas(o.DictionaryDeltas)}
+	switch o.Compression {
+	case IPCCompressionNone:
+	case IPCCompressionLZ4:
+		opts = append(opts, ipc.WithLZ4())
+	case IPCCompressionZSTD:
+		opts = append(opts, ipc.WithZstd())
+	default:
+		return nil, fmt.Errorf("unknown IPC compression: %d", o.Compression)
+	}
+	return opts, nil
+}
+
+// ExportIPCStream runs query and writes its results to w in the Arrow IPC
+// streaming format.
+func (a *Arrow) ExportIPCStream(ctx context.Context, w io.Writer, opts IPCOptions, query string, args ...interface{}) error {
+	rdr, err := a.QueryArrowStream(ctx, exportBatchSize(opts.BatchSize), query, args...)
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	return a.WriteIPCStream(w, rdr, opts)
+}
+
+// ExportIPCFile runs query and writes its results to w in the Arrow IPC file
+// format.
+func (a *Arrow) ExportIPCFile(ctx context.Context, w io.Writer, opts IPCOptions, query string, args ...interface{}) error {
+	rdr, err := a.QueryArrowStream(ctx, exportBatchSize(opts.BatchSize), query, args...)
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	return a.WriteIPCFile(w, rdr, opts)
+}
+
+// WriteIPCStream writes the records of rdr to w in the Arrow IPC streaming
+// format.
+func (a *Arrow) WriteIPCStream(w io.Writer, rdr array.RecordReader, opts IPCOptions) error {
+	wopts, err := opts.writerOptions(a, rdr.Schema())
+	if err != nil {
+		return err
+	}
+
+	iw := ipc.NewWriter(w, wopts...)
+	for rdr.Next() {
+		if err := iw.Write(rdr.Record()); err != nil {
+			iw.Close()
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+	if err := rdr.Err(); err != nil {
+		iw.Close()
+		return err
+	}
+	if err := iw.Close(); err != nil {
+		return fmt.Errorf("failed to close IPC stream: %w", err)
+	}
+	return nil
+}
+
+// WriteIPCFile writes the records of rdr to w in the Arrow IPC file format.
+// Records with dictionary-encoded columns are collected in memory so their
+// dictionaries can be unified first. ipc.FileWriter keeps a reference to the
+// last dictionaries it wrote after Close, so their memory is only reclaimed by
+// the garbage collector.
+func (a *Arrow) WriteIPCFile(w io.Writer, rdr array.RecordReader, opts IPCOptions) error {
+	schema := rdr.Schema()
+	wopts, err := opts.writerOptions(a, schema)
+	if err != nil {
+		return err
+	}
+
+	fw, err := ipc.NewFileWriter(&offsetWriter{w: w}, wopts...)
+	if err != nil {
+		return fmt.Errorf("failed to create IPC file writer: %w", err)
+	}
+
+	if hasDictionary(schema) {
+		rdr, err = a.unifiedDictionaries(rdr)
+		if err != nil {
+			fw.Close()
+			return err
+		}
+		defer rdr.Release()
+	}
+
+	for rdr.Next() {
+		if err := fw.Write(rdr.Record()); err != nil {
+			fw.Close()
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+	if err := rdr.Err(); err != nil {
+		fw.Close()
+		return err
+	}
+	if err := fw.Close(); err != nil {
+		return fmt.Errorf("failed to close IPC file: %w", err)
+	}
+	return nil
+}
+
+// unifiedDictionaries reads all of rdr and returns a reader over the same
+// records sharing one dictionary per field.
+func (a *Arrow) unifiedDictionaries(rdr array.RecordReader) (array.RecordReader, error) {
+	recs, err := collectRecords(rdr)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseRecords(recs)
+
+	table := array.NewTableFromRecords(rdr.Schema(), recs)
+	defer table.Release()
+
+	unified, err := array.UnifyTableDicts(a.mem, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unify dictionaries: %w", err)
+	}
+	defer unified.Release()
+
+	return array.NewTableReader(unified, 0), nil
+}
+
+func hasDictionary(schema *arrow.Schema) bool {
+	var walk func(dt arrow.DataType) bool
+	walk = func(dt arrow.DataType) bool {
+		if dt.ID() == arrow.DICTIONARY {
+			return true
+		}
+		if nested, ok := dt.(arrow.NestedType); ok {
+			for _, f := range nested.Fields() {
+				if walk(f.Type) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, f := range schema.Fields() {
+		if walk(f.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// offsetWriter lets the IPC file writer, which only asks for the current
+// offset, write to a plain io.Writer.
+type offsetWriter struct {
+	w   io.Writer
+	pos int64
+}
+
+func (o *offsetWriter) Write(p []byte) (int, error) {
+	n, err := o.w.Write(p)
+	o.pos += int64(n)
+	return n, err
+}
+
+func (o *offsetWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekCurrent {
+		return 0, fmt.Errorf("seek is not supported")
+	}
+	return o.pos, nil
+}
+
+// ReadIPCStream returns a reader over Arrow IPC stream data. The caller must
+// release it.
+func (a *Arrow) ReadIPCStream(r io.Reader) (array.RecordReader, error) {
+	rdr, err := ipc.NewReader(r, ipc.WithAllocator(a.mem))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IPC stream: %w", err)
+	}
+	return rdr, nil
+}
+
+// ReadIPC loads every record of Arrow IPC data in either the file or the
+// streaming format. Files are read from the start of r. The caller must
+// release the records.
+func (a *Arrow) ReadIPC(r io.Reader) ([]arrow.Record, error) {
+	rdr, err := a.OpenIPC(r)
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Release()
+	return collectRecords(rdr)
+}
+
+// OpenIPC returns a reader over Arrow IPC data in either the file or the
+// streaming format, so the schema is available even when the data holds no
+// records. Files are read from the start of r. The caller must release the
+// reader.
+func (a *Arrow) OpenIPC(r io.Reader) (array.RecordReader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(6)
+	if string(magic) != "ARROW1" {
+		return a.ReadIPCStream(br)
+	}
+
+	ras, ok := r.(ipc.ReadAtSeeker)
+	if !ok {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read IPC file: %w", err)
+		}
+		ras = bytes.NewReader(data)
+	} else if _, err := ras.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read IPC file: %w", err)
+	}
+
+	// FileReader.Close does not release the dictionaries it decoded; they
+	// are left to the garbage collector.
+	fr, err := ipc.NewFileReader(ras, ipc.WithAllocator(a.mem))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IPC file: %w", err)
+	}
+	return &fileRecordReader{refCount: 1, fr: fr}, nil
+}
+
+// fileRecordReader reads the records of an IPC file in order. Unlike
+// FileReader.Read it hands out records through RecordAt, which the reader
+// owns until the next call to Next.
+type fileRecordReader struct {
+	refCount int64
+	fr       *ipc.FileReader
+	next     int
+	rec      arrow.Record
+	err      error
+}
+
+func (f *fileRecordReader) Retain() { atomic.AddInt64(&f.refCount, 1) }
+
+func (f *fileRecordReader) Release() {
+	if atomic.AddInt64(&f.refCount, -1) != 0 {
+		return
+	}
+	if f.rec != nil {
+		f.rec.Release()
+		f.rec = nil
+	}
+	f.fr.Close()
+}
+
+func (f *fileRecordReader) Schema() *arrow.Schema { return f.fr.Schema() }
+
+func (f *fileRecordReader) Next() bool {
+	if f.rec != nil {
+		f.rec.Release()
+		f.rec = nil
+	}
+	if f.err != nil || f.next >= f.fr.NumRecords() {
+		return false
+	}
+
+	rec, err := f.fr.RecordAt(f.next)
+	if err != nil {
+		f.err = fmt.Errorf("failed to read record %d: %w", f.next, err)
+		return false
+	}
+	f.next++
+	f.rec = rec
+	return true
+}
+
+func (f *fileRecordReader) Record() arrow.Record { return f.rec }
+
+func (f *fileRecordReader) Err() error { return f.err }
+
+func collectRecords(rdr array.RecordReader) ([]arrow.Record, error) {
+	var recs []arrow.Record
+	for rdr.Next() {
+		rec := rdr.Record()
+		rec.Retain()
+		recs = append(recs, rec)
+	}
+	if err := rdr.Err(); err != nil {
+		releaseRecords(recs)
+		return nil, err
+	}
+	return recs, nil
+}
+
+func releaseRecords(recs []arrow.Record) {
+	for _, rec := range recs {
+		rec.Release()
+	}
+}
@@ -0,0 +1,203 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func setupIPCDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+
+	_, err = db.Exec(`
+		CREATE TYPE color AS ENUM ('red', 'green', 'blue', 'cyan', 'pink');
+		CREATE TABLE paints (id INTEGER, name VARCHAR, shade color);
+		INSERT INTO paints VALUES
+			(1, 'a', 'red'), (2, 'b', 'green'), (3, NULL, 'blue'),
+			(4, 'd', 'cyan'), (5, 'e', NULL), (6, 'f', 'pink');
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+const paintsQuery = "SELECT id, name, shade FROM paints ORDER BY id"
+
+func requirePaints(t *testing.T, recs []arrow.Record) {
+	table := array.NewTableFromRecords(recs[0].Schema(), recs)
+	defer table.Release()
+	require.Equal(t, int64(6), table.NumRows())
+
+	query, _ := table.Schema().Metadata().GetValue(MetadataQuery)
+	require.Equal(t, paintsQuery, query)
+	dbType, _ := table.Schema().Field(1).Metadata.GetValue(MetadataDBType)
+	require.Equal(t, "VARCHAR", dbType)
+
+	var ids []int32
+	var shades []string
+	tr := array.NewTableReader(table, 0)
+	defer tr.Release()
+	for tr.Next() {
+		rec := tr.Record()
+		idCol := rec.Column(0).(*array.Int32)
+		shadeCol := rec.Column(2).(*array.Dictionary)
+		for i := 0; i < int(rec.NumRows()); i++ {
+			ids = append(ids, idCol.Value(i))
+			shades = append(shades, shadeCol.ValueStr(i))
+		}
+	}
+	require.Equal(t, []int32{1, 2, 3, 4, 5, 6}, ids)
+	require.Equal(t, []string{"red", "green", "blue", "cyan", "(null)", "pink"}, shades)
+}
+
+func TestExportIPCStream(t *testing.T) {
+	db := setupIPCDB(t)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	arrowInstance := NewArrow(db, WithAllocator(mem))
+
+	for _, compression := range []IPCCompression{IPCCompressionNone, IPCCompressionLZ4, IPCCompressionZSTD} {
+		for _, deltas := range []bool{false, true} {
+			var buf bytes.Buffer
+			opts := IPCOptions{Compression: compression, DictionaryDeltas: deltas, BatchSize: 2}
+			require.NoError(t, arrowInstance.ExportIPCStream(context.Background(), &buf, opts, paintsQuery))
+
+			recs, err := arrowInstance.ReadIPC(&buf)
+			require.NoError(t, err)
+			require.Len(t, recs, 3)
+			requirePaints(t, recs)
+			releaseRecords(recs)
+		}
+	}
+
+	err := arrowInstance.ExportIPCStream(context.Background(), &bytes.Buffer{}, IPCOptions{Compression: 9}, paintsQuery)
+	require.Error(t, err)
+}
+
+func TestExportIPCDictionaryDeltas(t *testing.T) {
+	db := setupIPCDB(t)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+	size := func(deltas bool) int {
+		var buf bytes.Buffer
+		opts := IPCOptions{DictionaryDeltas: deltas, BatchSize: 1}
+		require.NoError(t, arrowInstance.ExportIPCStream(context.Background(), &buf, opts, "SELECT shade FROM paints ORDER BY id"))
+		return buf.Len()
+	}
+
+	// every batch adds one color, which deltas send on their own instead of
+	// replacing the whole dictionary
+	require.Less(t, size(true), size(false))
+}
+
+func TestExportIPCFile(t *testing.T) {
+	db := setupIPCDB(t)
+	defer db.Close()
+
+	// the IPC file reader and writer leave dictionaries to the garbage
+	// collector, so only the dictionary-free export is checked for leaks
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	var buf bytes.Buffer
+	err := NewArrow(db, WithAllocator(mem)).ExportIPCFile(context.Background(), &buf, IPCOptions{}, "SELECT id, name FROM paints")
+	require.NoError(t, err)
+	mem.AssertSize(t, 0)
+
+	arrowInstance := NewArrow(db)
+	recs, err := arrowInstance.ReadIPC(&buf)
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	require.Equal(t, int64(6), recs[0].NumRows())
+	releaseRecords(recs)
+
+	// a plain writer
+	buf.Reset()
+	opts := IPCOptions{Compression: IPCCompressionZSTD, BatchSize: 4}
+	require.NoError(t, arrowInstance.ExportIPCFile(context.Background(), &buf, opts, paintsQuery))
+	require.Equal(t, "ARROW1", buf.String()[:6])
+
+	recs, err = arrowInstance.ReadIPC(&buf)
+	require.NoError(t, err)
+	require.Len(t, recs, 2)
+	requirePaints(t, recs)
+	releaseRecords(recs)
+
+	// a file, read back through random access
+	path := filepath.Join(t.TempDir(), "paints.arrow")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, arrowInstance.ExportIPCFile(context.Background(), f, IPCOptions{Compression: IPCCompressionLZ4}, paintsQuery))
+	require.NoError(t, f.Close())
+
+	f, err = os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	recs, err = arrowInstance.ReadIPC(f)
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	requirePaints(t, recs)
+	releaseRecords(recs)
+}
+
+func TestReadIPCStream(t *testing.T) {
+	db := setupIPCDB(t)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+	var buf bytes.Buffer
+	require.NoError(t, arrowInstance.ExportIPCStream(context.Background(), &buf, IPCOptions{}, paintsQuery))
+
+	rdr, err := arrowInstance.ReadIPCStream(&buf)
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	require.Equal(t, 3, rdr.Schema().NumFields())
+	rows := int64(0)
+	for rdr.Next() {
+		rows += rdr.Record().NumRows()
+	}
+	require.NoError(t, rdr.Err())
+	require.Equal(t, int64(6), rows)
+
+	_, err = arrowInstance.ReadIPC(bytes.NewReader([]byte("not arrow")))
+	require.Error(t, err)
+}
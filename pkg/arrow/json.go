@@ -0,0 +1,127 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow/go/v17/arrow/array"
+)
+
+// JSONOptions configures JSON output.
+type JSONOptions struct {
+	// Lines writes newline-delimited JSON, one object per line, instead of a
+	// single JSON array.
+	Lines bool
+
+	// BatchSize is the number of rows read per record when exporting query
+	// results. It defaults to 2048.
+	BatchSize int
+}
+
+// ExportJSON runs query and writes its results to w as JSON.
+func (a *Arrow) ExportJSON(ctx context.Context, w io.Writer, opts JSONOptions, query string, args ...interface{}) error {
+	rdr, err := a.QueryArrowStream(ctx, exportBatchSize(opts.BatchSize), query, args...)
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	return WriteJSON(w, rdr, opts)
+}
+
+// WriteJSON writes the records of rdr to w as JSON objects keyed by column
+// name, one record at a time. Values are formatted as by WriteCSV, except that
+// numbers, booleans and nested values keep their JSON types, and NaN and
+// infinities become strings.
+func WriteJSON(w io.Writer, rdr array.RecordReader, opts JSONOptions) error {
+	bw := bufio.NewWriter(w)
+	if !opts.Lines {
+		bw.WriteByte('[')
+	}
+
+	keys := make([][]byte, rdr.Schema().NumFields())
+	uuids := make([]bool, len(keys))
+	for i, f := range rdr.Schema().Fields() {
+		keys[i] = append(appendJSONString(nil, f.Name), ':')
+		uuids[i] = isUUID(f)
+	}
+
+	var buf []byte
+	first := true
+	for rdr.Next() {
+		rec := rdr.Record()
+		for row := 0; row < int(rec.NumRows()); row++ {
+			buf = buf[:0]
+			if !opts.Lines && !first {
+				buf = append(buf, ',')
+			}
+			first = false
+
+			buf = append(buf, '{')
+			for i, col := range rec.Columns() {
+				if i > 0 {
+					buf = append(buf, ',')
+				}
+				buf = append(buf, keys[i]...)
+				if uuids[i] && !col.IsNull(row) {
+					buf = appendUUID(buf, col.(*array.FixedSizeBinary).Value(row), true)
+					continue
+				}
+				var err error
+				if buf, err = appendJSON(buf, col, row); err != nil {
+					return fmt.Errorf("column %s: %w", rec.ColumnName(i), err)
+				}
+			}
+			buf = append(buf, '}')
+			if opts.Lines {
+				buf = append(buf, '\n')
+			}
+			bw.Write(buf)
+		}
+		if err := bw.Flush(); err != nil {
+			return fmt.Errorf("failed to write json: %w", err)
+		}
+	}
+	if err := rdr.Err(); err != nil {
+		return err
+	}
+
+	if !opts.Lines {
+		bw.WriteString("]\n")
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write json: %w", err)
+	}
+	return nil
+}
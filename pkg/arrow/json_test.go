@@ -0,0 +1,98 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportJSON(t *testing.T) {
+	db := setupFormatDB(t)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, NewArrow(db).ExportJSON(context.Background(), &buf, JSONOptions{}, formatQuery+" UNION ALL "+formatQuery))
+
+	var rows []map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rows))
+	require.Len(t, rows, 2)
+
+	expected := map[string]string{
+		"i":     `1`,
+		"nan":   `"NaN"`,
+		"d":     `12.30`,
+		"s":     `"a,b \"c\"\nx"`,
+		"empty": `""`,
+		"n":     `null`,
+		"dt":    `"2024-01-02"`,
+		"tm":    `"03:04:05.25"`,
+		"ts":    `"2024-01-02T03:04:05.5"`,
+		"tstz":  `"2024-01-02T03:04:05Z"`,
+		"b":     `"AQI="`,
+		"l":     `[1,null,3]`,
+		"st":    `{"x":1,"y":"z"}`,
+		"m":     `{"k":1}`,
+		"e":     `"ok"`,
+		"u":     `"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"`,
+		"bo":    `true`,
+	}
+	for _, row := range rows {
+		require.Len(t, row, len(expected))
+		for k, v := range expected {
+			require.Equal(t, v, string(row[k]), k)
+		}
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	db := setupFormatDB(t)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	query := "SELECT range AS id, 'ctl' || chr(1) AS s FROM range(3)"
+	require.NoError(t, NewArrow(db).ExportJSON(context.Background(), &buf, JSONOptions{Lines: true, BatchSize: 2}, query))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Equal(t, []string{
+		`{"id":0,"s":"ctl\u0001"}`,
+		`{"id":1,"s":"ctl\u0001"}`,
+		`{"id":2,"s":"ctl\u0001"}`,
+	}, lines)
+
+	buf.Reset()
+	require.NoError(t, NewArrow(db).ExportJSON(context.Background(), &buf, JSONOptions{}, query+" WHERE false"))
+	require.Equal(t, "[]\n", buf.String())
+}
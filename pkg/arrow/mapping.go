@@ -0,0 +1,332 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+)
+
+// Dialect names used to pick a type mapping for a database/sql driver.
+const (
+	DialectDuckDB   = "duckdb"
+	DialectPostgres = "postgres"
+	DialectMySQL    = "mysql"
+	DialectSQLite   = "sqlite"
+)
+
+// TypeMap maps the database type names a driver reports through
+// sql.ColumnType.DatabaseTypeName to Arrow types. Names are matched
+// case-insensitively and without any parenthesized parameters, so an entry
+// for "VARCHAR" also covers "varchar(255)".
+type TypeMap map[string]arrow.DataType
+
+var (
+	typeMapsMu sync.RWMutex
+	typeMaps   = map[string]TypeMap{
+		DialectPostgres: {
+			"BOOL":        arrow.FixedWidthTypes.Boolean,
+			"INT2":        arrow.PrimitiveTypes.Int16,
+			"INT4":        arrow.PrimitiveTypes.Int32,
+			"INT8":        arrow.PrimitiveTypes.Int64,
+			"OID":         arrow.PrimitiveTypes.Uint32,
+			"FLOAT4":      arrow.PrimitiveTypes.Float32,
+			"FLOAT8":      arrow.PrimitiveTypes.Float64,
+			"TEXT":        arrow.BinaryTypes.String,
+			"VARCHAR":     arrow.BinaryTypes.String,
+			"BPCHAR":      arrow.BinaryTypes.String,
+			"NAME":        arrow.BinaryTypes.String,
+			"JSON":        arrow.BinaryTypes.String,
+			"JSONB":       arrow.BinaryTypes.String,
+			"NUMERIC":     arrow.BinaryTypes.String,
+			"BYTEA":       arrow.BinaryTypes.Binary,
+			"UUID":        &arrow.FixedSizeBinaryType{ByteWidth: 16},
+			"DATE":        arrow.FixedWidthTypes.Date32,
+			"TIME":        arrow.FixedWidthTypes.Time64us,
+			"TIMESTAMP":   &arrow.TimestampType{Unit: arrow.Microsecond},
+			"TIMESTAMPTZ": &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"},
+			"INTERVAL":    arrow.FixedWidthTypes.MonthDayNanoInterval,
+		},
+		DialectMySQL: {
+			"TINYINT":            arrow.PrimitiveTypes.Int8,
+			"SMALLINT":           arrow.PrimitiveTypes.Int16,
+			"MEDIUMINT":          arrow.PrimitiveTypes.Int32,
+			"INT":                arrow.PrimitiveTypes.Int32,
+			"BIGINT":             arrow.PrimitiveTypes.Int64,
+			"UNSIGNED TINYINT":   arrow.PrimitiveTypes.Uint8,
+			"UNSIGNED SMALLINT":  arrow.PrimitiveTypes.Uint16,
+			"UNSIGNED MEDIUMINT": arrow.PrimitiveTypes.Uint32,
+			"UNSIGNED INT":       arrow.PrimitiveTypes.Uint32,
+			"UNSIGNED BIGINT":    arrow.PrimitiveTypes.Uint64,
+			"FLOAT":              arrow.PrimitiveTypes.Float32,
+			"DOUBLE":             arrow.PrimitiveTypes.Float64,
+			"BIT":                arrow.BinaryTypes.Binary,
+			"CHAR":               arrow.BinaryTypes.String,
+			"VARCHAR":            arrow.BinaryTypes.String,
+			"TEXT":               arrow.BinaryTypes.String,
+			"TINYTEXT":           arrow.BinaryTypes.String,
+			"MEDIUMTEXT":         arrow.BinaryTypes.String,
+			"LONGTEXT":           arrow.BinaryTypes.String,
+			"ENUM":               arrow.BinaryTypes.String,
+			"SET":                arrow.BinaryTypes.String,
+			"JSON":               arrow.BinaryTypes.String,
+			"BINARY":             arrow.BinaryTypes.Binary,
+			"VARBINARY":          arrow.BinaryTypes.Binary,
+			"BLOB":               arrow.BinaryTypes.Binary,
+			"TINYBLOB":           arrow.BinaryTypes.Binary,
+			"MEDIUMBLOB":         arrow.BinaryTypes.Binary,
+			"LONGBLOB":           arrow.BinaryTypes.Binary,
+			"DATE":               arrow.FixedWidthTypes.Date32,
+			"TIME":               arrow.FixedWidthTypes.Time64us,
+			"YEAR":               arrow.PrimitiveTypes.Int16,
+			"DATETIME":           &arrow.TimestampType{Unit: arrow.Microsecond},
+			"TIMESTAMP":          &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"},
+		},
+		DialectSQLite: {
+			"INTEGER":   arrow.PrimitiveTypes.Int64,
+			"INT":       arrow.PrimitiveTypes.Int64,
+			"BIGINT":    arrow.PrimitiveTypes.Int64,
+			"REAL":      arrow.PrimitiveTypes.Float64,
+			"DOUBLE":    arrow.PrimitiveTypes.Float64,
+			"FLOAT":     arrow.PrimitiveTypes.Float64,
+			"NUMERIC":   arrow.PrimitiveTypes.Float64,
+			"TEXT":      arrow.BinaryTypes.String,
+			"VARCHAR":   arrow.BinaryTypes.String,
+			"CHAR":      arrow.BinaryTypes.String,
+			"BLOB":      arrow.BinaryTypes.Binary,
+			"BOOLEAN":   arrow.FixedWidthTypes.Boolean,
+			"DATE":      arrow.FixedWidthTypes.Date32,
+			"DATETIME":  &arrow.TimestampType{Unit: arrow.Microsecond},
+			"TIMESTAMP": &arrow.TimestampType{Unit: arrow.Microsecond},
+		},
+	}
+)
+
+// RegisterTypeMap adds entries to the type map of a dialect, overriding any
+// built-in entries with the same name. It can also introduce new dialects,
+// which are then selected with WithDialect.
+func RegisterTypeMap(dialect string, m TypeMap) {
+	typeMapsMu.Lock()
+	defer typeMapsMu.Unlock()
+
+	existing, ok := typeMaps[dialect]
+	if !ok {
+		existing = make(TypeMap, len(m))
+		typeMaps[dialect] = existing
+	}
+	for name, dt := range m {
+		existing[normalizeTypeName(name)] = dt
+	}
+}
+
+// WithDialect selects the type mapping used for query results instead of
+// detecting it from the database driver.
+func WithDialect(dialect string) Option {
+	return func(a *Arrow) {
+		a.dialect = dialect
+	}
+}
+
+// WithTypeMap adds type names that take precedence over the dialect's type
+// mapping for this instance only.
+func WithTypeMap(m TypeMap) Option {
+	return func(a *Arrow) {
+		if a.typeMap == nil {
+			a.typeMap = make(TypeMap, len(m))
+		}
+		for name, dt := range m {
+			a.typeMap[normalizeTypeName(name)] = dt
+		}
+	}
+}
+
+// detectDialect guesses the dialect from the package path of the driver.
+func detectDialect(d driver.Driver) string {
+	t := reflect.TypeOf(d)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	path := t.PkgPath()
+	switch {
+	case strings.Contains(path, "go-duckdb"):
+		return DialectDuckDB
+	case strings.Contains(path, "pgx"), strings.Contains(path, "lib/pq"):
+		return DialectPostgres
+	case strings.Contains(path, "mysql"):
+		return DialectMySQL
+	case strings.Contains(path, "sqlite"):
+		return DialectSQLite
+	}
+	return path
+}
+
+// columnField resolves the Arrow field for a result column. Instance type
+// maps win, then the dialect's mapping (DuckDB's type names are parsed in
+// full), then the column's precision and scale, and finally its scan type.
+func (a *Arrow) columnField(col *sql.ColumnType) (arrow.Field, error) {
+	nullable, ok := col.Nullable()
+	if !ok {
+		nullable = true
+	}
+	field := arrow.Field{Name: col.Name(), Nullable: nullable}
+
+	typeName := col.DatabaseTypeName()
+	name := normalizeTypeName(typeName)
+	if dt, ok := a.typeMap[name]; ok {
+		field.Type = dt
+		return field, nil
+	}
+
+	if a.dialect == DialectDuckDB {
+		dt, err := arrowType(typeName)
+		if err != nil {
+			return field, err
+		}
+		field.Type = dt
+		return field, nil
+	}
+
+	if name == "DECIMAL" || name == "NUMERIC" {
+		if precision, scale, ok := col.DecimalSize(); ok && precision > 0 {
+			field.Type = decimalOf(int32(precision), int32(scale))
+			return field, nil
+		}
+		// Some drivers only report the declared type, e.g. DECIMAL(10,2).
+		if strings.Contains(typeName, "(") {
+			if dt, err := parseType(typeName); err == nil {
+				field.Type = dt
+				return field, nil
+			}
+		}
+	}
+
+	typeMapsMu.RLock()
+	dt, ok := typeMaps[a.dialect][name]
+	typeMapsMu.RUnlock()
+	if ok {
+		field.Type = dt
+		return field, nil
+	}
+
+	dt, err := scanTypeArrow(col.ScanType())
+	if err != nil {
+		return field, fmt.Errorf("unsupported column type %q: %w", typeName, err)
+	}
+	field.Type = dt
+	return field, nil
+}
+
+func normalizeTypeName(typeName string) string {
+	if i := strings.IndexByte(typeName, '('); i >= 0 {
+		typeName = typeName[:i]
+	}
+	return strings.ToUpper(strings.TrimSpace(typeName))
+}
+
+func decimalOf(precision, scale int32) arrow.DataType {
+	if precision > 38 {
+		return &arrow.Decimal256Type{Precision: precision, Scale: scale}
+	}
+	return &arrow.Decimal128Type{Precision: precision, Scale: scale}
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	rawBytesType = reflect.TypeOf(sql.RawBytes{})
+	nullTypes    = map[reflect.Type]arrow.DataType{
+		reflect.TypeOf(sql.NullBool{}):    arrow.FixedWidthTypes.Boolean,
+		reflect.TypeOf(sql.NullByte{}):    arrow.PrimitiveTypes.Uint8,
+		reflect.TypeOf(sql.NullInt16{}):   arrow.PrimitiveTypes.Int16,
+		reflect.TypeOf(sql.NullInt32{}):   arrow.PrimitiveTypes.Int32,
+		reflect.TypeOf(sql.NullInt64{}):   arrow.PrimitiveTypes.Int64,
+		reflect.TypeOf(sql.NullFloat64{}): arrow.PrimitiveTypes.Float64,
+		reflect.TypeOf(sql.NullString{}):  arrow.BinaryTypes.String,
+		reflect.TypeOf(sql.NullTime{}):    &arrow.TimestampType{Unit: arrow.Microsecond},
+	}
+)
+
+// scanTypeArrow derives an Arrow type from the Go type a driver scans a
+// column into, for type names no mapping knows about.
+func scanTypeArrow(t reflect.Type) (arrow.DataType, error) {
+	if t == nil {
+		return nil, fmt.Errorf("driver reports no scan type")
+	}
+	if dt, ok := nullTypes[t]; ok {
+		return dt, nil
+	}
+	if t == timeType {
+		return &arrow.TimestampType{Unit: arrow.Microsecond}, nil
+	}
+	if t == rawBytesType {
+		return arrow.BinaryTypes.Binary, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return scanTypeArrow(t.Elem())
+	case reflect.Bool:
+		return arrow.FixedWidthTypes.Boolean, nil
+	case reflect.Int8:
+		return arrow.PrimitiveTypes.Int8, nil
+	case reflect.Int16:
+		return arrow.PrimitiveTypes.Int16, nil
+	case reflect.Int32:
+		return arrow.PrimitiveTypes.Int32, nil
+	case reflect.Int, reflect.Int64:
+		return arrow.PrimitiveTypes.Int64, nil
+	case reflect.Uint8:
+		return arrow.PrimitiveTypes.Uint8, nil
+	case reflect.Uint16:
+		return arrow.PrimitiveTypes.Uint16, nil
+	case reflect.Uint32:
+		return arrow.PrimitiveTypes.Uint32, nil
+	case reflect.Uint, reflect.Uint64:
+		return arrow.PrimitiveTypes.Uint64, nil
+	case reflect.Float32:
+		return arrow.PrimitiveTypes.Float32, nil
+	case reflect.Float64:
+		return arrow.PrimitiveTypes.Float64, nil
+	case reflect.String:
+		return arrow.BinaryTypes.String, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return arrow.BinaryTypes.Binary, nil
+		}
+	}
+	return nil, fmt.Errorf("no arrow type for scan type %s", t)
+}
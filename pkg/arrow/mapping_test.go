@@ -0,0 +1,238 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func setupSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`
+		CREATE TABLE readings (
+			id INTEGER,
+			label VARCHAR(20),
+			reading REAL,
+			payload BLOB,
+			amount DECIMAL(10,2),
+			taken_at DATETIME,
+			valid BOOLEAN
+		);
+		INSERT INTO readings VALUES
+			(1, 'a', 1.5, x'0102', 12.34, '2024-01-02 03:04:05', 1),
+			(NULL, NULL, NULL, NULL, NULL, NULL, NULL);
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestQueryArrowSQLite(t *testing.T) {
+	db := setupSQLite(t)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	arrowInstance := NewArrow(db, WithAllocator(mem))
+	require.Equal(t, DialectSQLite, arrowInstance.dialect)
+
+	record, err := arrowInstance.QueryArrow(context.Background(), "SELECT *, id * 2 AS doubled FROM readings ORDER BY id NULLS LAST")
+	require.NoError(t, err)
+	defer record.Release()
+
+	expectedTypes := []arrow.DataType{
+		arrow.PrimitiveTypes.Int64,
+		arrow.BinaryTypes.String,
+		arrow.PrimitiveTypes.Float64,
+		arrow.BinaryTypes.Binary,
+		&arrow.Decimal128Type{Precision: 10, Scale: 2},
+		&arrow.TimestampType{Unit: arrow.Microsecond},
+		arrow.FixedWidthTypes.Boolean,
+		arrow.PrimitiveTypes.Int64,
+	}
+	require.Equal(t, int64(len(expectedTypes)), record.NumCols())
+	for i, dt := range expectedTypes {
+		require.Truef(t, arrow.TypeEqual(dt, record.Column(i).DataType()),
+			"column %s: expected %s, got %s", record.ColumnName(i), dt, record.Column(i).DataType())
+		require.True(t, record.Schema().Field(i).Nullable)
+		require.True(t, record.Column(i).IsNull(1))
+	}
+
+	require.Equal(t, int64(1), record.Column(0).(*array.Int64).Value(0))
+	require.Equal(t, "a", record.Column(1).(*array.String).Value(0))
+	require.Equal(t, 1.5, record.Column(2).(*array.Float64).Value(0))
+	require.Equal(t, []byte{1, 2}, record.Column(3).(*array.Binary).Value(0))
+	require.Equal(t, "12.34", record.Column(4).(*array.Decimal128).ValueStr(0))
+	require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), record.Column(5).(*array.Timestamp).Value(0).ToTime(arrow.Microsecond))
+	require.True(t, record.Column(6).(*array.Boolean).Value(0))
+	require.Equal(t, int64(2), record.Column(7).(*array.Int64).Value(0))
+}
+
+func TestQueryArrowTypeMapOverrides(t *testing.T) {
+	db := setupSQLite(t)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db, WithTypeMap(TypeMap{
+		"varchar": arrow.BinaryTypes.Binary,
+		"REAL":    arrow.PrimitiveTypes.Float32,
+	}))
+
+	record, err := arrowInstance.QueryArrow(context.Background(), "SELECT label, reading FROM readings WHERE id = 1")
+	require.NoError(t, err)
+	defer record.Release()
+
+	require.Equal(t, []byte("a"), record.Column(0).(*array.Binary).Value(0))
+	require.Equal(t, float32(1.5), record.Column(1).(*array.Float32).Value(0))
+}
+
+func TestRegisterTypeMapDialect(t *testing.T) {
+	db := setupSQLite(t)
+	defer db.Close()
+
+	RegisterTypeMap("custom-sqlite", TypeMap{"INTEGER": arrow.PrimitiveTypes.Int32})
+
+	record, err := NewArrow(db, WithDialect("custom-sqlite")).QueryArrow(context.Background(), "SELECT id, label FROM readings WHERE id = 1")
+	require.NoError(t, err)
+	defer record.Release()
+
+	require.Equal(t, int32(1), record.Column(0).(*array.Int32).Value(0))
+	// unmapped names fall back to the driver's scan type
+	require.Equal(t, "a", record.Column(1).(*array.String).Value(0))
+}
+
+func TestQueryArrowPostgresInterval(t *testing.T) {
+	// SQLite reports the declared type, so a column declared INTERVAL goes
+	// through the Postgres type map like a driver's interval text.
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE spans (d INTERVAL);
+		INSERT INTO spans VALUES ('1 year 2 mons 3 days 04:05:06'), ('P1DT2H'), (NULL)`)
+	require.NoError(t, err)
+
+	record, err := NewArrow(db, WithDialect(DialectPostgres)).QueryArrow(context.Background(), "SELECT d FROM spans")
+	require.NoError(t, err)
+	defer record.Release()
+
+	col := record.Column(0).(*array.MonthDayNanoInterval)
+	require.Equal(t, arrow.MonthDayNanoInterval{Months: 14, Days: 3, Nanoseconds: int64(4*time.Hour + 5*time.Minute + 6*time.Second)}, col.Value(0))
+	require.Equal(t, arrow.MonthDayNanoInterval{Days: 1, Nanoseconds: int64(2 * time.Hour)}, col.Value(1))
+	require.True(t, col.IsNull(2))
+}
+
+func TestScanTypeArrow(t *testing.T) {
+	tests := []struct {
+		scanType reflect.Type
+		expected arrow.DataType
+	}{
+		{reflect.TypeOf(sql.NullInt64{}), arrow.PrimitiveTypes.Int64},
+		{reflect.TypeOf(sql.NullString{}), arrow.BinaryTypes.String},
+		{reflect.TypeOf(sql.RawBytes{}), arrow.BinaryTypes.Binary},
+		{reflect.TypeOf(time.Time{}), &arrow.TimestampType{Unit: arrow.Microsecond}},
+		{reflect.TypeOf(uint16(0)), arrow.PrimitiveTypes.Uint16},
+		{reflect.TypeOf(new(float32)), arrow.PrimitiveTypes.Float32},
+	}
+	for _, tt := range tests {
+		dt, err := scanTypeArrow(tt.scanType)
+		require.NoError(t, err)
+		require.True(t, arrow.TypeEqual(tt.expected, dt), tt.scanType.String())
+	}
+
+	_, err := scanTypeArrow(reflect.TypeOf(map[string]int{}))
+	require.Error(t, err)
+	_, err = scanTypeArrow(nil)
+	require.Error(t, err)
+}
+
+func TestAppendConverted(t *testing.T) {
+	mem := memory.NewGoAllocator()
+
+	tests := []struct {
+		dt       arrow.DataType
+		value    any
+		expected string
+	}{
+		{arrow.PrimitiveTypes.Int16, int64(-300), "-300"},
+		{arrow.PrimitiveTypes.Uint8, []byte("200"), "200"},
+		{arrow.PrimitiveTypes.Float32, "2.5", "2.5"},
+		{arrow.FixedWidthTypes.Boolean, []byte("t"), "true"},
+		{arrow.BinaryTypes.String, []byte("text"), "text"},
+		{arrow.BinaryTypes.String, int64(42), "42"},
+		{&arrow.Decimal128Type{Precision: 10, Scale: 2}, []byte("1234.50"), "1234.5"},
+		{&arrow.Decimal128Type{Precision: 10, Scale: 2}, float64(12.34), "12.34"},
+		{arrow.FixedWidthTypes.Date32, "2024-03-04", "2024-03-04"},
+		{arrow.FixedWidthTypes.Time64us, []byte("13:14:15.5"), "13:14:15.500000"},
+		{&arrow.TimestampType{Unit: arrow.Second}, "2024-03-04T05:06:07Z", "2024-03-04 05:06:07Z"},
+		{&arrow.FixedSizeBinaryType{ByteWidth: 16}, "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", "oO68mZwLTvi7bWu5vTgKEQ=="},
+		{arrow.FixedWidthTypes.MonthDayNanoInterval, []byte("-1 days +02:03:00.5"), `{"months":0,"days":-1,"nanoseconds":7380500000000}`},
+		{arrow.FixedWidthTypes.MonthDayNanoInterval, "@ 1 year 2 mons 3 hours ago", `{"months":-14,"days":0,"nanoseconds":-10800000000000}`},
+		{arrow.FixedWidthTypes.MonthDayNanoInterval, "P1Y2M3DT4H5M6.5S", `{"months":14,"days":3,"nanoseconds":14706500000000}`},
+	}
+	for _, tt := range tests {
+		b := array.NewBuilder(mem, tt.dt)
+		require.NoError(t, appendValue(b, tt.value), "%s <- %T", tt.dt, tt.value)
+		arr := b.NewArray()
+		require.Equal(t, tt.expected, arr.ValueStr(0), "%s <- %T", tt.dt, tt.value)
+		arr.Release()
+		b.Release()
+	}
+
+	for _, bad := range []struct {
+		dt    arrow.DataType
+		value any
+	}{
+		{arrow.PrimitiveTypes.Int8, int64(300)},
+		{arrow.PrimitiveTypes.Uint32, int64(-1)},
+		{arrow.PrimitiveTypes.Int64, "abc"},
+		{arrow.FixedWidthTypes.Date32, "not a date"},
+		{arrow.PrimitiveTypes.Int32, struct{}{}},
+		{arrow.FixedWidthTypes.MonthDayNanoInterval, "3 fortnights"},
+		{arrow.FixedWidthTypes.MonthDayNanoInterval, "P1X"},
+	} {
+		b := array.NewBuilder(mem, bad.dt)
+		require.Error(t, appendValue(b, bad.value), "%s <- %v", bad.dt, bad.value)
+		b.Release()
+	}
+}
@@ -0,0 +1,155 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v17/arrow/memory"
+)
+
+// Option configures an Arrow instance created with NewArrow.
+type Option func(*Arrow)
+
+// WithAllocator sets the allocator used for every record built by the row
+// based query paths. It defaults to memory.NewGoAllocator().
+func WithAllocator(mem memory.Allocator) Option {
+	return func(a *Arrow) {
+		a.mem = mem
+		a.customMem = true
+	}
+}
+
+// WithQueryMemoryLimit caps the bytes a single query may hold in Arrow
+// buffers at once. A query that needs more fails with a *MemoryLimitError.
+// Zero, the default, disables the limit.
+func WithQueryMemoryLimit(limit int64) Option {
+	return func(a *Arrow) {
+		a.memLimit = limit
+	}
+}
+
+// WithQueryStats registers a callback that receives the allocation
+// statistics of each query once its result has been fully read.
+func WithQueryStats(fn func(QueryStats)) Option {
+	return func(a *Arrow) {
+		a.onStats = fn
+	}
+}
+
+// QueryStats reports the Arrow memory used while building a query's result.
+type QueryStats struct {
+	Query string
+	// BytesAllocated is the total of every allocation made for the query.
+	BytesAllocated int64
+	// PeakBytes is the most memory the query held at any one time.
+	PeakBytes int64
+}
+
+// MemoryLimitError is returned when a query exceeds the limit set with
+// WithQueryMemoryLimit.
+type MemoryLimitError struct {
+	Limit     int64
+	Requested int64
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("query memory limit of %d bytes exceeded: %d bytes requested", e.Limit, e.Requested)
+}
+
+// queryAllocator wraps the configured allocator to account for, and
+// optionally cap, the memory used by a single query. memory.Allocator cannot
+// return errors, so exceeding the limit panics with a *MemoryLimitError that
+// the readers recover into an ordinary error.
+type queryAllocator struct {
+	mem   memory.Allocator
+	limit int64
+
+	inUse int64
+	total int64
+	peak  int64
+}
+
+func newQueryAllocator(mem memory.Allocator, limit int64) *queryAllocator {
+	return &queryAllocator{mem: mem, limit: limit}
+}
+
+func (q *queryAllocator) Allocate(size int) []byte {
+	q.reserve(int64(size))
+	return q.mem.Allocate(size)
+}
+
+func (q *queryAllocator) Reallocate(size int, b []byte) []byte {
+	q.reserve(int64(size - len(b)))
+	return q.mem.Reallocate(size, b)
+}
+
+func (q *queryAllocator) Free(b []byte) {
+	atomic.AddInt64(&q.inUse, -int64(len(b)))
+	q.mem.Free(b)
+}
+
+func (q *queryAllocator) reserve(delta int64) {
+	inUse := atomic.AddInt64(&q.inUse, delta)
+	if q.limit > 0 && delta > 0 && inUse > q.limit {
+		atomic.AddInt64(&q.inUse, -delta)
+		panic(&MemoryLimitError{Limit: q.limit, Requested: inUse})
+	}
+	if delta > 0 {
+		atomic.AddInt64(&q.total, delta)
+	}
+	for {
+		peak := atomic.LoadInt64(&q.peak)
+		if inUse <= peak || atomic.CompareAndSwapInt64(&q.peak, peak, inUse) {
+			break
+		}
+	}
+}
+
+func (q *queryAllocator) stats(query string) QueryStats {
+	return QueryStats{
+		Query:          query,
+		BytesAllocated: atomic.LoadInt64(&q.total),
+		PeakBytes:      atomic.LoadInt64(&q.peak),
+	}
+}
+
+// recoverMemoryLimit turns a *MemoryLimitError panic raised by a
+// queryAllocator into an error assigned to err. Any other panic is re-raised.
+func recoverMemoryLimit(err *error) {
+	if r := recover(); r != nil {
+		limitErr, ok := r.(*MemoryLimitError)
+		if !ok {
+			panic(r)
+		}
+		*err = limitErr
+	}
+}
@@ -0,0 +1,112 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	_ "github.com/marcboeker/go-duckdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryArrowCheckedAllocator(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	arrowInstance := NewArrow(db, WithAllocator(mem))
+
+	record, err := arrowInstance.QueryArrow(context.Background(), `
+		SELECT i, 'v' || i AS s, [i, i + 1] AS l, {'a': i, 'b': 'x'} AS st, MAP {'k': i} AS m
+		FROM range(5000) t(i)
+	`)
+	require.NoError(t, err)
+	require.Equal(t, int64(5000), record.NumRows())
+	require.NotZero(t, mem.CurrentAlloc())
+	record.Release()
+}
+
+func TestQueryMemoryLimit(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	arrowInstance := NewArrow(db, WithAllocator(mem), WithQueryMemoryLimit(64*1024))
+
+	_, err = arrowInstance.QueryArrow(context.Background(), "SELECT i, 'some padding text ' || i AS s FROM range(100000) t(i)")
+	require.Error(t, err)
+
+	var limitErr *MemoryLimitError
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, int64(64*1024), limitErr.Limit)
+
+	rdr, err := arrowInstance.QueryArrowStream(context.Background(), 100, "SELECT i FROM range(100000) t(i)")
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	var rows int64
+	for rdr.Next() {
+		rows += rdr.Record().NumRows()
+	}
+	require.NoError(t, rdr.Err())
+	require.Equal(t, int64(100000), rows)
+}
+
+func TestQueryStats(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var stats []QueryStats
+	arrowInstance := NewArrow(db, WithQueryStats(func(s QueryStats) {
+		stats = append(stats, s)
+	}))
+
+	query := "SELECT i FROM range(1000) t(i)"
+	record, err := arrowInstance.QueryArrow(context.Background(), query)
+	require.NoError(t, err)
+	record.Release()
+
+	require.Len(t, stats, 1)
+	require.Equal(t, query, stats[0].Query)
+	require.GreaterOrEqual(t, stats[0].BytesAllocated, int64(1000*8))
+	require.GreaterOrEqual(t, stats[0].BytesAllocated, stats[0].PeakBytes)
+	require.NotZero(t, stats[0].PeakBytes)
+}
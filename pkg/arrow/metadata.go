@@ -0,0 +1,328 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/apache/arrow/go/v17/arrow"
+)
+
+// Metadata keys attached to the fields and schemas of query results.
+const (
+	MetadataDBType       = "arrowlake.db_type"
+	MetadataPrecision    = "arrowlake.precision"
+	MetadataScale        = "arrowlake.scale"
+	MetadataSourceSchema = "arrowlake.source_schema"
+	MetadataSourceTable  = "arrowlake.source_table"
+	MetadataSourceColumn = "arrowlake.source_column"
+	MetadataComment      = "arrowlake.comment"
+	// MetadataNullable records the nullability declared in the catalog for
+	// the source column. Result fields are left nullable, since the query
+	// may still produce nulls for a NOT NULL column.
+	MetadataNullable = "arrowlake.nullable"
+
+	MetadataQuery      = "arrowlake.query"
+	MetadataExecutedAt = "arrowlake.executed_at"
+)
+
+// columnSource is the catalog column a result column was read from.
+type columnSource struct {
+	schema, table, column string
+	nullable              bool
+	comment               string
+}
+
+// columnSources maps lower-cased result column names to their catalog
+// columns.
+type columnSources struct {
+	byName map[string]columnSource
+}
+
+const identPattern = `"(?:[^"]|"")*"|[A-Za-z_][A-Za-z0-9_$]*`
+
+var identRe = regexp.MustCompile(identPattern)
+
+// WithSourceMetadata attributes result columns to the catalog columns they
+// are read from, adding the MetadataSource* keys, MetadataComment and
+// MetadataNullable to their fields. It costs a catalog query per query, and
+// applies to DuckDB only.
+func WithSourceMetadata() Option {
+	return func(a *Arrow) {
+		a.sourceMetadata = true
+	}
+}
+
+// WithQueryRedactor stores fn(query) rather than the query itself as
+// MetadataQuery, for queries that may hold secrets.
+func WithQueryRedactor(fn func(string) string) Option {
+	return func(a *Arrow) {
+		a.redactQuery = fn
+	}
+}
+
+// lookupSources resolves result columns to catalog columns on a best-effort
+// basis: a result column is attributed when its select item is a bare
+// column reference, or comes from a *, and exactly one table referenced in
+// query has that column. Expressions, set operations and queries the scan
+// below does not understand get no source metadata. DuckDB has no column
+// lineage API, so other dialects get none either. Lookup failures are not
+// fatal.
+func (a *Arrow) lookupSources(ctx context.Context, query string) columnSources {
+	var src columnSources
+	if !a.sourceMetadata || a.dialect != DialectDuckDB {
+		return src
+	}
+
+	refs, ok := bareColumns(query)
+	if !ok {
+		return src
+	}
+
+	idents := make(map[string]bool)
+	for _, tok := range identRe.FindAllString(query, -1) {
+		idents[strings.ToLower(unquoteIdent(tok))] = true
+	}
+
+	rows, err := a.db.QueryContext(ctx, `SELECT schema_name, table_name, column_name, is_nullable, comment
+		FROM duckdb_columns() WHERE NOT internal`)
+	if err != nil {
+		return src
+	}
+	defer rows.Close()
+
+	matches := make(map[string][]columnSource)
+	for rows.Next() {
+		var c columnSource
+		var comment sql.NullString
+		if err := rows.Scan(&c.schema, &c.table, &c.column, &c.nullable, &comment); err != nil {
+			return src
+		}
+		if !idents[strings.ToLower(c.table)] {
+			continue
+		}
+		c.comment = comment.String
+		name := strings.ToLower(c.column)
+		matches[name] = append(matches[name], c)
+	}
+	if rows.Err() != nil {
+		return src
+	}
+
+	src.byName = make(map[string]columnSource)
+	for name, column := range refs.columns {
+		if cs := matches[column]; len(cs) == 1 {
+			src.byName[name] = cs[0]
+		}
+	}
+	if refs.star {
+		for name, cs := range matches {
+			if _, ok := src.byName[name]; !ok && !refs.computed[name] && len(cs) == 1 {
+				src.byName[name] = cs[0]
+			}
+		}
+	}
+	return src
+}
+
+// selectRefs describes the select list of a query: the lower-cased result
+// names of bare column references with the columns they read, the names of
+// other items, and whether the list has a *.
+type selectRefs struct {
+	columns  map[string]string
+	computed map[string]bool
+	star     bool
+}
+
+var (
+	bareColumnRe = regexp.MustCompile(`^(?:(?:` + identPattern + `)\s*\.\s*){0,2}(` + identPattern + `)(?:\s+(?:(?i:AS)\s+)?(` + identPattern + `))?$`)
+	starRe       = regexp.MustCompile(`^(?:(?:` + identPattern + `)\s*\.\s*)?\*`)
+	aliasRe      = regexp.MustCompile(`(` + identPattern + `)$`)
+)
+
+// bareColumns scans the select list of query, which must be a single SELECT.
+func bareColumns(query string) (selectRefs, bool) {
+	refs := selectRefs{columns: make(map[string]string), computed: make(map[string]bool)}
+	q := strings.TrimSpace(query)
+	if len(q) < 7 || !strings.EqualFold(q[:6], "SELECT") || !unicode.IsSpace(rune(q[6])) {
+		return refs, false
+	}
+
+	items, rest := splitSelectList(q[6:])
+	for _, kw := range []string{"UNION", "INTERSECT", "EXCEPT"} {
+		if keywordAt(rest, kw) >= 0 {
+			return refs, false
+		}
+	}
+	for i, item := range items {
+		item = strings.TrimSpace(item)
+		if i == 0 {
+			item = strings.TrimSpace(trimKeyword(trimKeyword(item, "DISTINCT"), "ALL"))
+		}
+		switch m := bareColumnRe.FindStringSubmatch(item); {
+		case m != nil:
+			name := m[1]
+			if m[2] != "" {
+				name = m[2]
+			}
+			refs.columns[strings.ToLower(unquoteIdent(name))] = strings.ToLower(unquoteIdent(m[1]))
+		case starRe.MatchString(item):
+			refs.star = true
+		default:
+			// The result name of an expression is its alias, if any; an
+			// unaliased expression cannot be told apart from a column.
+			if m := aliasRe.FindStringSubmatch(item); m != nil {
+				refs.computed[strings.ToLower(unquoteIdent(m[1]))] = true
+			}
+		}
+	}
+	return refs, true
+}
+
+// splitSelectList splits s, the text after SELECT, into the items of the
+// select list and the text from the FROM on.
+func splitSelectList(s string) (items []string, rest string) {
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\'', '"':
+			if j := strings.IndexByte(s[i+1:], c); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(s)
+			}
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, s[start:i])
+				start = i + 1
+			}
+		default:
+			if depth == 0 && isKeyword(s[i:], "FROM") && (i == 0 || !isIdentByte(s[i-1])) {
+				return append(items, s[start:i]), s[i:]
+			}
+		}
+	}
+	return append(items, s[start:]), ""
+}
+
+// keywordAt returns the offset of the first occurrence of the keyword kw
+// as a whole word in s, or -1.
+func keywordAt(s, kw string) int {
+	upper := strings.ToUpper(s)
+	for off := 0; ; {
+		i := strings.Index(upper[off:], kw)
+		if i < 0 {
+			return -1
+		}
+		i += off
+		end := i + len(kw)
+		if (i == 0 || !isIdentByte(s[i-1])) && (end == len(s) || !isIdentByte(s[end])) {
+			return i
+		}
+		off = i + 1
+	}
+}
+
+// isKeyword reports whether s starts with the keyword kw as a whole word.
+func isKeyword(s, kw string) bool {
+	return len(s) >= len(kw) && strings.EqualFold(s[:len(kw)], kw) && (len(s) == len(kw) || !isIdentByte(s[len(kw)]))
+}
+
+func trimKeyword(s, kw string) string {
+	if isKeyword(s, kw) {
+		return s[len(kw):]
+	}
+	return s
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func unquoteIdent(tok string) string {
+	if len(tok) >= 2 && tok[0] == '"' {
+		return strings.ReplaceAll(tok[1:len(tok)-1], `""`, `"`)
+	}
+	return tok
+}
+
+// withMetadata attaches the database type, decimal precision and scale and,
+// when known, the source column to field.
+func (src columnSources) withMetadata(field arrow.Field, dbType string) arrow.Field {
+	var keys, values []string
+	add := func(k, v string) {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+
+	if dbType != "" {
+		add(MetadataDBType, dbType)
+	}
+	if dt, ok := field.Type.(arrow.DecimalType); ok {
+		add(MetadataPrecision, strconv.Itoa(int(dt.GetPrecision())))
+		add(MetadataScale, strconv.Itoa(int(dt.GetScale())))
+	}
+	if c, ok := src.byName[strings.ToLower(field.Name)]; ok {
+		add(MetadataSourceSchema, c.schema)
+		add(MetadataSourceTable, c.table)
+		add(MetadataSourceColumn, c.column)
+		add(MetadataNullable, strconv.FormatBool(c.nullable))
+		if c.comment != "" {
+			add(MetadataComment, c.comment)
+		}
+	}
+
+	field.Metadata = arrow.NewMetadata(keys, values)
+	return field
+}
+
+func (a *Arrow) queryMetadata(query string, executedAt time.Time) *arrow.Metadata {
+	if a.redactQuery != nil {
+		query = a.redactQuery(query)
+	}
+	keys := []string{MetadataQuery}
+	values := []string{query}
+	if !executedAt.IsZero() {
+		keys = append(keys, MetadataExecutedAt)
+		values = append(values, executedAt.UTC().Format(time.RFC3339Nano))
+	}
+	md := arrow.NewMetadata(keys, values)
+	return &md
+}
@@ -0,0 +1,196 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/stretchr/testify/require"
+)
+
+func fieldMetadata(f arrow.Field, key string) string {
+	v, _ := f.Metadata.GetValue(key)
+	return v
+}
+
+func TestQueryArrowMetadata(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE accounts (id INTEGER NOT NULL, owner VARCHAR, balance DECIMAL(12,2) NOT NULL);
+		COMMENT ON COLUMN accounts.owner IS 'account holder';
+		CREATE TABLE branches (id INTEGER, city VARCHAR);
+		INSERT INTO accounts VALUES (1, 'ann', 10.50);
+	`)
+	require.NoError(t, err)
+
+	arrowInstance := NewArrow(db, WithSourceMetadata())
+	query := "SELECT owner, balance, balance * 2 AS doubled, 1 AS id FROM accounts"
+	before := time.Now().UTC()
+	record, err := arrowInstance.QueryArrow(context.Background(), query)
+	require.NoError(t, err)
+	defer record.Release()
+
+	schema := record.Schema()
+	require.Equal(t, query, metadataValue(t, schema.Metadata(), MetadataQuery))
+	executedAt, err := time.Parse(time.RFC3339Nano, metadataValue(t, schema.Metadata(), MetadataExecutedAt))
+	require.NoError(t, err)
+	require.False(t, executedAt.Before(before.Truncate(time.Second)))
+
+	owner := schema.Field(0)
+	require.True(t, owner.Nullable)
+	require.Equal(t, "VARCHAR", fieldMetadata(owner, MetadataDBType))
+	require.Equal(t, "main", fieldMetadata(owner, MetadataSourceSchema))
+	require.Equal(t, "accounts", fieldMetadata(owner, MetadataSourceTable))
+	require.Equal(t, "owner", fieldMetadata(owner, MetadataSourceColumn))
+	require.Equal(t, "account holder", fieldMetadata(owner, MetadataComment))
+	require.Equal(t, "true", fieldMetadata(owner, MetadataNullable))
+
+	balance := schema.Field(1)
+	require.True(t, balance.Nullable)
+	require.Equal(t, "DECIMAL(12,2)", fieldMetadata(balance, MetadataDBType))
+	require.Equal(t, "12", fieldMetadata(balance, MetadataPrecision))
+	require.Equal(t, "2", fieldMetadata(balance, MetadataScale))
+	require.Equal(t, "false", fieldMetadata(balance, MetadataNullable))
+
+	// computed and aliased columns have no source
+	for _, f := range schema.Fields()[2:] {
+		require.True(t, f.Nullable, f.Name)
+		require.Equal(t, "", fieldMetadata(f, MetadataSourceTable), f.Name)
+	}
+
+	// "id" exists in both referenced tables, and outer joins can null out
+	// NOT NULL columns
+	record2, err := arrowInstance.QueryArrow(context.Background(),
+		"SELECT accounts.id, balance FROM accounts LEFT JOIN branches ON accounts.id = branches.id")
+	require.NoError(t, err)
+	defer record2.Release()
+
+	require.Equal(t, "", fieldMetadata(record2.Schema().Field(0), MetadataSourceTable))
+	require.Equal(t, "accounts", fieldMetadata(record2.Schema().Field(1), MetadataSourceTable))
+	require.True(t, record2.Schema().Field(1).Nullable)
+
+	// an expression with an implicit alias is not its namesake column, and
+	// only bare references and * are attributed
+	record3, err := arrowInstance.QueryArrow(context.Background(),
+		`SELECT CASE WHEN id > 1 THEN balance END balance, a.owner AS "holder", * EXCLUDE (balance) FROM accounts a`)
+	require.NoError(t, err)
+	defer record3.Release()
+
+	schema3 := record3.Schema()
+	require.Equal(t, "", fieldMetadata(schema3.Field(0), MetadataSourceTable))
+	require.Equal(t, "", fieldMetadata(schema3.Field(0), MetadataNullable))
+	require.True(t, schema3.Field(0).Nullable)
+	require.True(t, record3.Column(0).IsNull(0))
+	require.Equal(t, "owner", fieldMetadata(schema3.Field(1), MetadataSourceColumn))
+	require.Equal(t, "id", fieldMetadata(schema3.Field(2), MetadataSourceColumn))
+
+	for _, query := range []string{
+		"SELECT owner FROM accounts UNION ALL SELECT city FROM branches",
+		"WITH x AS (SELECT owner FROM accounts) SELECT owner FROM x",
+	} {
+		rec, err := arrowInstance.QueryArrow(context.Background(), query)
+		require.NoError(t, err)
+		require.Equal(t, "", fieldMetadata(rec.Schema().Field(0), MetadataSourceTable), query)
+		rec.Release()
+	}
+
+	// source metadata is opt-in
+	record4, err := NewArrow(db).QueryArrow(context.Background(), query)
+	require.NoError(t, err)
+	defer record4.Release()
+
+	require.Equal(t, "", fieldMetadata(record4.Schema().Field(0), MetadataSourceTable))
+	require.Equal(t, "VARCHAR", fieldMetadata(record4.Schema().Field(0), MetadataDBType))
+}
+
+func TestRegisterViewKeepsComments(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE accounts (id INTEGER, owner VARCHAR);
+		COMMENT ON COLUMN accounts.owner IS 'the account''s holder';
+		INSERT INTO accounts VALUES (1, 'ann');
+	`)
+	require.NoError(t, err)
+
+	arrowInstance := NewArrow(db, WithSourceMetadata())
+	rdr, err := arrowInstance.QueryArrowStream(context.Background(), 10, "SELECT id, owner FROM accounts")
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	require.NoError(t, arrowInstance.RegisterView(context.Background(), "accounts_copy", rdr))
+
+	var comment string
+	err = db.QueryRow(`SELECT comment FROM duckdb_columns() WHERE table_name = 'accounts_copy' AND column_name = 'owner'`).Scan(&comment)
+	require.NoError(t, err)
+	require.Equal(t, "the account's holder", comment)
+}
+
+func metadataValue(t *testing.T, md arrow.Metadata, key string) string {
+	v, ok := md.GetValue(key)
+	require.Truef(t, ok, "missing metadata %s", key)
+	return v
+}
+
+func TestQuerySchemaMetadata(t *testing.T) {
+	db := setupSQLite(t)
+	defer db.Close()
+
+	schema, err := NewArrow(db).QuerySchema(context.Background(), "SELECT label, amount FROM readings")
+	require.NoError(t, err)
+
+	require.Equal(t, "SELECT label, amount FROM readings", metadataValue(t, schema.Metadata(), MetadataQuery))
+	require.False(t, schema.Metadata().FindKey(MetadataExecutedAt) >= 0)
+	require.Equal(t, "VARCHAR(20)", fieldMetadata(schema.Field(0), MetadataDBType))
+	require.Equal(t, "10", fieldMetadata(schema.Field(1), MetadataPrecision))
+	require.Equal(t, "2", fieldMetadata(schema.Field(1), MetadataScale))
+
+	redact := WithQueryRedactor(func(q string) string { return strings.ReplaceAll(q, "amount", "***") })
+	schema, err = NewArrow(db, redact).QuerySchema(context.Background(), "SELECT label, amount FROM readings")
+	require.NoError(t, err)
+	require.Equal(t, "SELECT label, *** FROM readings", metadataValue(t, schema.Metadata(), MetadataQuery))
+
+	table, err := NewArrow(db, redact).QueryPartitioned(context.Background(), RangePartitions("SELECT label, amount FROM readings", "id", 0, 2, 2), 2)
+	require.NoError(t, err)
+	defer table.Release()
+	query := metadataValue(t, table.Schema().Metadata(), MetadataQuery)
+	require.NotContains(t, query, "amount")
+	require.Contains(t, query, "SELECT label, *** FROM readings")
+}
@@ -0,0 +1,96 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/marcboeker/go-duckdb"
+)
+
+// defaultBatchSize matches DuckDB's vector size, which is also the batch size
+// of its native Arrow results.
+const defaultBatchSize = 2048
+
+// exportBatchSize is the batch size of exports that leave it unset.
+func exportBatchSize(n int) int {
+	if n > 0 {
+		return n
+	}
+	return defaultBatchSize
+}
+
+// QueryArrowNative runs query through DuckDB's native Arrow result interface
+// when the database is opened with go-duckdb, so record batches are handed
+// over without scanning each cell into Go values. Column types follow
+// DuckDB's own Arrow export, which differs from QueryArrow for a few types
+// such as UUID and HUGEINT. For any other driver it falls back to
+// QueryArrowStream with the default batch size.
+//
+// DuckDB allocates the native batches itself, so with go-duckdb the options
+// WithAllocator and WithQueryMemoryLimit cannot apply and are an error, and
+// the WithQueryStats callback is not called.
+func (a *Arrow) QueryArrowNative(ctx context.Context, query string, args ...interface{}) (array.RecordReader, error) {
+	if _, ok := a.db.Driver().(duckdb.Driver); !ok {
+		return a.QueryArrowStream(ctx, defaultBatchSize, query, args...)
+	}
+	if a.customMem || a.memLimit > 0 {
+		return nil, errors.New("native Arrow queries do not support WithAllocator or WithQueryMemoryLimit, use QueryArrowStream")
+	}
+
+	conn, err := a.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var rdr array.RecordReader
+	err = conn.Raw(func(driverConn any) error {
+		ar, err := duckdb.NewArrowFromConn(driverConn.(driver.Conn))
+		if err != nil {
+			return err
+		}
+
+		rdr, err = ar.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rdr, nil
+}
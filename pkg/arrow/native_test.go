@@ -0,0 +1,178 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	_ "github.com/marcboeker/go-duckdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryArrowNative(t *testing.T) {
+	db, arrowInstance, err := setupDuckDBWithArrow()
+	require.NoError(t, err)
+	defer db.Close()
+
+	rdr, err := arrowInstance.QueryArrowNative(context.Background(), "SELECT id, name, value FROM test_table WHERE id IN (?, ?) ORDER BY id", 1, 3)
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	require.Equal(t, 3, rdr.Schema().NumFields())
+	require.True(t, arrow.TypeEqual(arrow.PrimitiveTypes.Int32, rdr.Schema().Field(0).Type))
+
+	var rows int64
+	for rdr.Next() {
+		rec := rdr.Record()
+		ids := rec.Column(0).(*array.Int32)
+		names := rec.Column(1).(*array.String)
+		values := rec.Column(2).(*array.Float64)
+		require.Equal(t, int32(1), ids.Value(0))
+		require.Equal(t, "Alice", names.Value(0))
+		require.Equal(t, 10.5, values.Value(0))
+		require.Equal(t, "Charlie", names.Value(1))
+		rows += rec.NumRows()
+	}
+	require.NoError(t, rdr.Err())
+	require.Equal(t, int64(2), rows)
+}
+
+func TestQueryArrowNativeUnion(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	rdr, err := NewArrow(db).QueryArrowNative(context.Background(), `
+		SELECT u FROM (VALUES
+			(union_value(num := 1)::UNION(num INTEGER, str VARCHAR)),
+			(union_value(str := 'two')::UNION(num INTEGER, str VARCHAR))
+		) t(u)
+	`)
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	require.Equal(t, arrow.SPARSE_UNION, rdr.Schema().Field(0).Type.ID())
+	require.True(t, rdr.Next())
+	require.Equal(t, int64(2), rdr.Record().NumRows())
+	require.False(t, rdr.Next())
+	require.NoError(t, rdr.Err())
+}
+
+func TestQueryArrowNativeError(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = NewArrow(db).QueryArrowNative(context.Background(), "SELECT * FROM missing_table")
+	require.Error(t, err)
+}
+
+func TestQueryArrowNativeOptions(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, opt := range []Option{WithAllocator(memory.NewGoAllocator()), WithQueryMemoryLimit(1 << 20)} {
+		_, err := NewArrow(db, opt).QueryArrowNative(context.Background(), "SELECT 1")
+		require.ErrorContains(t, err, "use QueryArrowStream")
+	}
+}
+
+func TestQueryArrowNativeFallback(t *testing.T) {
+	db := setupSQLite(t)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	rdr, err := NewArrow(db, WithAllocator(mem), WithQueryMemoryLimit(1<<20)).QueryArrowNative(context.Background(),
+		"SELECT id, label, reading FROM readings WHERE id = ?", 1)
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	require.True(t, arrow.TypeEqual(arrow.PrimitiveTypes.Int64, rdr.Schema().Field(0).Type))
+	require.True(t, rdr.Next())
+	rec := rdr.Record()
+	require.Equal(t, int64(1), rec.NumRows())
+	require.Equal(t, "a", rec.Column(1).(*array.String).Value(0))
+	require.Equal(t, 1.5, rec.Column(2).(*array.Float64).Value(0))
+	require.False(t, rdr.Next())
+	require.NoError(t, rdr.Err())
+}
+
+const benchmarkQuery = `
+	SELECT i AS id, i * 1.5 AS value, 'name ' || i AS name, i % 7 = 0 AS flag
+	FROM range(200000) t(i)
+`
+
+func BenchmarkQueryArrow(b *testing.B) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(b, err)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rdr, err := arrowInstance.QueryArrowStream(ctx, defaultBatchSize, benchmarkQuery)
+		require.NoError(b, err)
+		for rdr.Next() {
+		}
+		require.NoError(b, rdr.Err())
+		rdr.Release()
+	}
+}
+
+func BenchmarkQueryArrowNative(b *testing.B) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(b, err)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rdr, err := arrowInstance.QueryArrowNative(ctx, benchmarkQuery)
+		require.NoError(b, err)
+		for rdr.Next() {
+		}
+		require.NoError(b, rdr.Err())
+		rdr.Release()
+	}
+}
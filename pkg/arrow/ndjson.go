@@ -0,0 +1,602 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+)
+
+// defaultSampleSize is the number of rows used to infer the columns of text
+// data without an explicit schema.
+const defaultSampleSize = 1000
+
+// ParseError reports a row of text data that could not be parsed.
+type ParseError struct {
+	File string
+	// Line is the 1-based line of the row in the file.
+	Line int64
+	// Column is the 1-based position of the offending column, or 0 when the
+	// row as a whole could not be parsed.
+	Column     int
+	ColumnName string
+	Message    string
+	// Row is the raw text of the row.
+	Row string
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		b.WriteByte(':')
+	} else {
+		b.WriteString("line ")
+	}
+	b.WriteString(strconv.FormatInt(e.Line, 10))
+	if e.Column > 0 {
+		fmt.Fprintf(&b, ":%d", e.Column)
+	}
+	b.WriteString(": ")
+	if e.ColumnName != "" {
+		fmt.Fprintf(&b, "column %s: ", e.ColumnName)
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// NDJSONOptions configures reading newline-delimited JSON.
+type NDJSONOptions struct {
+	// Schema lists the columns to read; other keys are ignored. When nil,
+	// the columns are inferred from the first SampleSize rows: booleans,
+	// integers and numbers become BOOLEAN, BIGINT and DOUBLE, strings that
+	// all match the date or timestamp format become DATE or TIMESTAMP, and
+	// anything else, including objects and arrays, becomes VARCHAR holding
+	// the JSON text.
+	Schema     *arrow.Schema
+	SampleSize int
+	// NullStrings are string values read as null.
+	NullStrings []string
+	// DateFormat and TimestampFormat are strptime-style formats, such as
+	// %d/%m/%Y, for DATE and TIMESTAMP columns. They default to ISO 8601.
+	DateFormat      string
+	TimestampFormat string
+	// BatchSize is the number of rows per record. It defaults to 2048.
+	BatchSize int
+	// OnError is called for each row that cannot be parsed. The row is
+	// skipped when it returns nil; otherwise reading stops with the error it
+	// returns. When unset, the first parse error stops reading.
+	OnError func(*ParseError) error
+}
+
+// ReadNDJSON returns a reader over newline-delimited JSON objects, one row
+// per line. Blank lines are ignored. The caller must release the reader.
+func (a *Arrow) ReadNDJSON(r io.Reader, opts NDJSONOptions) (array.RecordReader, error) {
+	dates, err := formatLayouts(opts.DateFormat, []string{"2006-01-02"})
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format: %w", err)
+	}
+	timestamps, err := formatLayouts(opts.TimestampFormat, timeLayouts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp format: %w", err)
+	}
+
+	rdr := &ndjsonReader{
+		refCount:   1,
+		mem:        a.mem,
+		src:        bufio.NewReader(r),
+		opts:       opts,
+		nulls:      make(map[string]bool, len(opts.NullStrings)),
+		dates:      dates,
+		timestamps: timestamps,
+	}
+	for _, s := range opts.NullStrings {
+		rdr.nulls[s] = true
+	}
+	if rdr.opts.OnError == nil {
+		rdr.opts.OnError = func(e *ParseError) error { return e }
+	}
+
+	schema := opts.Schema
+	if schema == nil {
+		if schema, err = rdr.inferSchema(); err != nil {
+			rdr.Release()
+			return nil, err
+		}
+	}
+	rdr.schema = schema
+	rdr.bld = array.NewRecordBuilder(a.mem, schema)
+	rdr.scratch = make([]array.Builder, schema.NumFields())
+	rdr.values = make([]any, schema.NumFields())
+	return rdr, nil
+}
+
+// jsonRow is one parsed line of NDJSON data.
+type jsonRow struct {
+	line   int64
+	text   string
+	keys   []string
+	fields map[string]json.RawMessage
+}
+
+type ndjsonReader struct {
+	refCount   int64
+	mem        memory.Allocator
+	src        *bufio.Reader
+	opts       NDJSONOptions
+	nulls      map[string]bool
+	dates      []string
+	timestamps []string
+
+	schema  *arrow.Schema
+	bld     *array.RecordBuilder
+	scratch []array.Builder
+	values  []any
+
+	line    int64
+	sampled []jsonRow
+	eof     bool
+	rec     arrow.Record
+	err     error
+}
+
+func (r *ndjsonReader) Retain() { atomic.AddInt64(&r.refCount, 1) }
+
+func (r *ndjsonReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) != 0 {
+		return
+	}
+	if r.rec != nil {
+		r.rec.Release()
+		r.rec = nil
+	}
+	if r.bld != nil {
+		r.bld.Release()
+	}
+	for _, b := range r.scratch {
+		if b != nil {
+			b.Release()
+		}
+	}
+}
+
+func (r *ndjsonReader) Schema() *arrow.Schema { return r.schema }
+
+func (r *ndjsonReader) Record() arrow.Record { return r.rec }
+
+func (r *ndjsonReader) Err() error { return r.err }
+
+func (r *ndjsonReader) Next() bool {
+	if r.rec != nil {
+		r.rec.Release()
+		r.rec = nil
+	}
+	if r.err != nil {
+		return false
+	}
+
+	batchSize := exportBatchSize(r.opts.BatchSize)
+	n := 0
+	for n < batchSize {
+		var row jsonRow
+		if len(r.sampled) > 0 {
+			row, r.sampled = r.sampled[0], r.sampled[1:]
+		} else {
+			var ok bool
+			if row, ok = r.readRow(); !ok {
+				break
+			}
+		}
+
+		if perr := r.convertRow(row); perr != nil {
+			if err := r.opts.OnError(perr); err != nil {
+				r.err = err
+				return false
+			}
+			continue
+		}
+		r.appendRow()
+		n++
+	}
+	if r.err != nil || n == 0 {
+		return false
+	}
+
+	r.rec = r.bld.NewRecord()
+	for _, b := range r.scratch {
+		if b != nil {
+			b.NewArray().Release()
+		}
+	}
+	return true
+}
+
+// readRow returns the next line that holds a JSON object, reporting lines
+// that do not to OnError.
+func (r *ndjsonReader) readRow() (jsonRow, bool) {
+	for !r.eof {
+		text, err := r.src.ReadString('\n')
+		if err == io.EOF {
+			r.eof = true
+		} else if err != nil {
+			r.err = fmt.Errorf("failed to read NDJSON: %w", err)
+			return jsonRow{}, false
+		}
+		r.line++
+
+		text = strings.TrimRight(text, "\r\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		row, err := parseJSONRow(text)
+		if err != nil {
+			perr := &ParseError{Line: r.line, Message: err.Error(), Row: text}
+			if err := r.opts.OnError(perr); err != nil {
+				r.err = err
+				return jsonRow{}, false
+			}
+			continue
+		}
+		row.line = r.line
+		return row, true
+	}
+	return jsonRow{}, false
+}
+
+func parseJSONRow(text string) (jsonRow, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return jsonRow{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if tok != json.Delim('{') {
+		return jsonRow{}, errors.New("expected a JSON object")
+	}
+
+	row := jsonRow{text: text, fields: make(map[string]json.RawMessage)}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return jsonRow{}, fmt.Errorf("invalid JSON: %w", err)
+		}
+		key := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return jsonRow{}, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if _, ok := row.fields[key]; !ok {
+			row.keys = append(row.keys, key)
+		}
+		row.fields[key] = raw
+	}
+	if _, err := dec.Token(); err != nil {
+		return jsonRow{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return jsonRow{}, errors.New("invalid JSON: unexpected data after object")
+	}
+	return row, nil
+}
+
+// jsonKind tracks the values seen for a column during inference.
+type jsonKind struct {
+	name                string
+	bools, ints, floats bool
+	strings, other      bool
+	notDate, notTime    bool
+}
+
+func (r *ndjsonReader) inferSchema() (*arrow.Schema, error) {
+	sampleSize := r.opts.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultSampleSize
+	}
+
+	var kinds []*jsonKind
+	byName := make(map[string]*jsonKind)
+	for len(r.sampled) < sampleSize {
+		row, ok := r.readRow()
+		if !ok {
+			break
+		}
+		r.sampled = append(r.sampled, row)
+
+		for _, key := range row.keys {
+			k, ok := byName[key]
+			if !ok {
+				k = &jsonKind{name: key}
+				byName[key] = k
+				kinds = append(kinds, k)
+			}
+			r.observe(k, row.fields[key])
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(kinds) == 0 {
+		return nil, errors.New("no columns to infer from NDJSON data; set them explicitly")
+	}
+
+	fields := make([]arrow.Field, len(kinds))
+	for i, k := range kinds {
+		fields[i] = arrow.Field{Name: k.name, Type: k.dataType(), Nullable: true}
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+func (r *ndjsonReader) observe(k *jsonKind, raw json.RawMessage) {
+	switch raw[0] {
+	case 'n':
+	case 't', 'f':
+		k.bools = true
+	case '"':
+		var s string
+		if json.Unmarshal(raw, &s) != nil || r.nulls[s] {
+			return
+		}
+		k.strings = true
+		if _, ok := parseTime(s, r.dates); !ok {
+			k.notDate = true
+		}
+		if _, ok := parseTime(s, r.timestamps); !ok {
+			k.notTime = true
+		}
+	case '{', '[':
+		k.other = true
+	default:
+		if bytes.ContainsAny(raw, ".eE") {
+			k.floats = true
+		} else {
+			k.ints = true
+		}
+	}
+}
+
+func (k *jsonKind) dataType() arrow.DataType {
+	numbers := k.ints || k.floats
+	switch {
+	case k.other || k.strings && (numbers || k.bools) || k.bools && numbers:
+		return arrow.BinaryTypes.String
+	case k.bools:
+		return arrow.FixedWidthTypes.Boolean
+	case k.floats:
+		return arrow.PrimitiveTypes.Float64
+	case k.ints:
+		return arrow.PrimitiveTypes.Int64
+	case k.strings && !k.notDate:
+		return arrow.FixedWidthTypes.Date32
+	case k.strings && !k.notTime:
+		return &arrow.TimestampType{Unit: arrow.Microsecond}
+	}
+	return arrow.BinaryTypes.String
+}
+
+// convertRow parses the values of row into r.values, so that a row with a
+// bad value is rejected before anything is appended.
+func (r *ndjsonReader) convertRow(row jsonRow) *ParseError {
+	for i, f := range r.schema.Fields() {
+		v, err := r.convertValue(i, f.Type, row.fields[f.Name])
+		if err != nil {
+			return &ParseError{Line: row.line, Column: i + 1, ColumnName: f.Name, Message: err.Error(), Row: row.text}
+		}
+		r.values[i] = v
+	}
+	return nil
+}
+
+func (r *ndjsonReader) convertValue(i int, dt arrow.DataType, raw json.RawMessage) (any, error) {
+	if len(raw) == 0 || raw[0] == 'n' {
+		return nil, nil
+	}
+
+	var s string
+	isString := raw[0] == '"'
+	if isString {
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		if r.nulls[s] {
+			return nil, nil
+		}
+	}
+
+	switch dt := dt.(type) {
+	case *arrow.StringType:
+		if isString {
+			return s, nil
+		}
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, raw); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	case *arrow.Date32Type:
+		if !isString {
+			return nil, fmt.Errorf("expected a date string, got %s", raw)
+		}
+		t, ok := parseTime(s, r.dates)
+		if !ok {
+			return nil, fmt.Errorf("could not parse %q as a date", s)
+		}
+		return arrow.Date32FromTime(t), nil
+	case *arrow.TimestampType:
+		if !isString {
+			return nil, fmt.Errorf("expected a timestamp string, got %s", raw)
+		}
+		t, ok := parseTime(s, r.timestamps)
+		if !ok {
+			return nil, fmt.Errorf("could not parse %q as a timestamp", s)
+		}
+		return arrow.TimestampFromTime(t, dt.Unit)
+	}
+
+	// The builders do not check the range of integers.
+	if arrow.IsInteger(dt.ID()) {
+		text := string(raw)
+		if isString {
+			text = s
+		}
+		bits := dt.(arrow.FixedWidthDataType).BitWidth()
+		var err error
+		if arrow.IsUnsignedInteger(dt.ID()) {
+			_, err = strconv.ParseUint(text, 10, bits)
+		} else {
+			_, err = strconv.ParseInt(text, 10, bits)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not convert %s to %s", raw, dt)
+		}
+	}
+
+	// Other types are decoded by the builders themselves. The value is
+	// decoded into a scratch builder first to catch errors.
+	if r.scratch[i] == nil {
+		r.scratch[i] = array.NewBuilder(r.mem, dt)
+	}
+	if err := r.scratch[i].UnmarshalJSON(jsonList(raw)); err != nil {
+		return nil, fmt.Errorf("could not convert %s to %s", raw, dt)
+	}
+	return raw, nil
+}
+
+func (r *ndjsonReader) appendRow() {
+	for i, v := range r.values {
+		b := r.bld.Field(i)
+		switch v := v.(type) {
+		case nil:
+			b.AppendNull()
+		case string:
+			b.(*array.StringBuilder).Append(v)
+		case arrow.Date32:
+			b.(*array.Date32Builder).Append(v)
+		case arrow.Timestamp:
+			b.(*array.TimestampBuilder).Append(v)
+		case json.RawMessage:
+			// Already decoded once by convertValue, so this cannot fail.
+			_ = b.UnmarshalJSON(jsonList(v))
+		}
+	}
+}
+
+// jsonList wraps a single JSON value in a list, the form builders decode.
+func jsonList(raw json.RawMessage) []byte {
+	list := make([]byte, 0, len(raw)+2)
+	list = append(list, '[')
+	list = append(list, raw...)
+	return append(list, ']')
+}
+
+func parseTime(s string, layouts []string) (time.Time, bool) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatLayouts returns the Go layout of a strptime-style format, or
+// defaults when format is empty.
+func formatLayouts(format string, defaults []string) ([]string, error) {
+	if format == "" {
+		return defaults, nil
+	}
+	layout, err := strptimeLayout(format)
+	if err != nil {
+		return nil, err
+	}
+	return []string{layout}, nil
+}
+
+var strptimeDirectives = map[byte]string{
+	'Y': "2006", 'y': "06", 'm': "01", 'd': "02", 'e': "_2", 'j': "002",
+	'H': "15", 'I': "03", 'M': "04", 'S': "05", 'p': "PM",
+	'f': "000000", 'g': "000", 'n': "000000000",
+	'b': "Jan", 'h': "Jan", 'B': "January", 'a': "Mon", 'A': "Monday",
+	'z': "-0700", 'Z': "MST", 'T': "15:04:05", 'F': "2006-01-02", '%': "%",
+}
+
+// strptimeLayout converts the strptime-style formats DuckDB uses into Go
+// time layouts. Go layouts cannot escape literal text, so letters, digits and
+// underscores other than the T and Z of ISO 8601 are rejected rather than
+// risk being read as layout elements.
+func strptimeLayout(format string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if c := format[i]; c != '%' {
+			if !literalLayoutByte(c) {
+				return "", fmt.Errorf("unsupported literal %q in %q", c, format)
+			}
+			b.WriteByte(c)
+			continue
+		}
+		if i++; i == len(format) {
+			return "", fmt.Errorf("%q ends with %%", format)
+		}
+		if format[i] == '-' && i+1 < len(format) && strings.IndexByte("mdHIM", format[i+1]) >= 0 {
+			// %-m and friends drop the leading zero.
+			i++
+			b.WriteString(strings.TrimPrefix(strptimeDirectives[format[i]], "0"))
+			continue
+		}
+		layout, ok := strptimeDirectives[format[i]]
+		if !ok {
+			return "", fmt.Errorf("unsupported directive %%%c in %q", format[i], format)
+		}
+		b.WriteString(layout)
+	}
+	return b.String(), nil
+}
+
+func literalLayoutByte(c byte) bool {
+	switch {
+	case c == 'T' || c == 'Z':
+		return true
+	case c == '_', c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return false
+	}
+	return true
+}
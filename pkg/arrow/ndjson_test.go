@@ -0,0 +1,197 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+)
+
+const eventsNDJSON = `{"id": 1, "name": "start", "score": 1, "ok": true, "day": "2024-01-02", "at": "2024-01-02 03:04:05", "tags": ["a"]}
+{"id": 2, "name": "N/A", "score": 2.5, "ok": false, "day": "2024-01-03", "at": "2024-01-03T03:04:05Z", "tags": {"b": 1}}
+
+{"id": 3, "name": null, "ok": null, "day": null, "extra": "x"}
+`
+
+func newNDJSONArrow(t *testing.T, mem memory.Allocator) *Arrow {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return NewArrow(db, WithAllocator(mem))
+}
+
+func TestReadNDJSONInference(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	arrowInstance := newNDJSONArrow(t, mem)
+
+	rdr, err := arrowInstance.ReadNDJSON(strings.NewReader(eventsNDJSON), NDJSONOptions{NullStrings: []string{"N/A"}})
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	require.Equal(t, "schema:\n  fields: 8\n"+
+		"    - id: type=int64, nullable\n"+
+		"    - name: type=utf8, nullable\n"+
+		"    - score: type=float64, nullable\n"+
+		"    - ok: type=bool, nullable\n"+
+		"    - day: type=date32, nullable\n"+
+		"    - at: type=timestamp[us], nullable\n"+
+		"    - tags: type=utf8, nullable\n"+
+		"    - extra: type=utf8, nullable", rdr.Schema().String())
+
+	require.True(t, rdr.Next())
+	rec := rdr.Record()
+	require.Equal(t, int64(3), rec.NumRows())
+	require.Equal(t, `[1 2 3]`, rec.Column(0).String())
+	require.Equal(t, `["start" (null) (null)]`, rec.Column(1).String())
+	require.Equal(t, `[1 2.5 (null)]`, rec.Column(2).String())
+	day := rec.Column(4).(*array.Date32)
+	require.Equal(t, "2024-01-03", day.ValueStr(1))
+	require.True(t, day.IsNull(2))
+	at := rec.Column(5).(*array.Timestamp)
+	require.Equal(t, "2024-01-03 03:04:05 +0000 UTC", at.Value(1).ToTime(arrow.Microsecond).String())
+	require.Equal(t, `["[\"a\"]" "{\"b\":1}" (null)]`, rec.Column(6).String())
+	require.False(t, rdr.Next())
+	require.NoError(t, rdr.Err())
+}
+
+func TestReadNDJSONSchema(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	arrowInstance := newNDJSONArrow(t, mem)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		{Name: "day", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+		{Name: "amount", Type: &arrow.Decimal128Type{Precision: 10, Scale: 2}, Nullable: true},
+		{Name: "point", Type: arrow.StructOf(arrow.Field{Name: "x", Type: arrow.PrimitiveTypes.Int64, Nullable: true}), Nullable: true},
+	}, nil)
+	data := `{"id": 1, "day": "02/01/2024", "amount": "12.30", "point": {"x": 5}}
+{"id": "two", "day": "03/01/2024"}
+{"id": 3, "day": "2024-01-04"}
+not json
+{"id": 5, "day": "05/01/2024", "amount": 1.5, "point": {"x": 7}}
+`
+
+	var errs []*ParseError
+	rdr, err := arrowInstance.ReadNDJSON(strings.NewReader(data), NDJSONOptions{
+		Schema:     schema,
+		DateFormat: "%d/%m/%Y",
+		OnError: func(e *ParseError) error {
+			errs = append(errs, e)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	require.True(t, rdr.Next())
+	rec := rdr.Record()
+	require.Equal(t, `[1 5]`, rec.Column(0).String())
+	require.Equal(t, "2024-01-05", rec.Column(1).ValueStr(1))
+	require.Equal(t, "12.3", rec.Column(2).ValueStr(0))
+	require.Equal(t, `{[5 7]}`, rec.Column(3).String())
+	require.False(t, rdr.Next())
+	require.NoError(t, rdr.Err())
+
+	require.Len(t, errs, 3)
+	require.Equal(t, "line 2:1: column id: could not convert \"two\" to int32", errs[0].Error())
+	require.Equal(t, "line 3:2: column day: could not parse \"2024-01-04\" as a date", errs[1].Error())
+	require.Equal(t, int64(4), errs[2].Line)
+	require.Equal(t, 0, errs[2].Column)
+	require.Equal(t, "not json", errs[2].Row)
+}
+
+func TestReadNDJSONFailsOnError(t *testing.T) {
+	arrowInstance := newNDJSONArrow(t, memory.NewGoAllocator())
+
+	// Rows read to infer the columns fail when the reader is created.
+	_, err := arrowInstance.ReadNDJSON(strings.NewReader("{\"n\": 1}\n[1, 2]\n"), NDJSONOptions{})
+	require.EqualError(t, err, "line 2: expected a JSON object")
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "n", Type: arrow.PrimitiveTypes.Int8, Nullable: true}}, nil)
+	rdr, err := arrowInstance.ReadNDJSON(strings.NewReader("{\"n\": 1}\n{\"n\": 300}\n"), NDJSONOptions{Schema: schema})
+	require.NoError(t, err)
+	defer rdr.Release()
+	require.False(t, rdr.Next())
+	require.EqualError(t, rdr.Err(), "line 2:1: column n: could not convert 300 to int8")
+
+	_, err = arrowInstance.ReadNDJSON(strings.NewReader("\n\n"), NDJSONOptions{})
+	require.ErrorContains(t, err, "no columns to infer")
+
+	_, err = arrowInstance.ReadNDJSON(strings.NewReader(""), NDJSONOptions{DateFormat: "%Q"})
+	require.ErrorContains(t, err, "unsupported directive %Q")
+}
+
+func TestReadNDJSONBatches(t *testing.T) {
+	arrowInstance := newNDJSONArrow(t, memory.NewGoAllocator())
+
+	var data strings.Builder
+	for i := 0; i < 25; i++ {
+		data.WriteString(`{"n": 1}` + "\n")
+	}
+	rdr, err := arrowInstance.ReadNDJSON(strings.NewReader(data.String()), NDJSONOptions{SampleSize: 4, BatchSize: 10})
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	var sizes []int64
+	for rdr.Next() {
+		sizes = append(sizes, rdr.Record().NumRows())
+	}
+	require.NoError(t, rdr.Err())
+	require.Equal(t, []int64{10, 10, 5}, sizes)
+}
+
+func TestStrptimeLayout(t *testing.T) {
+	tests := map[string]string{
+		"%Y-%m-%d":               "2006-01-02",
+		"%d/%m/%y %H:%M:%S":      "02/01/06 15:04:05",
+		"%-m/%-d/%Y":             "1/2/2006",
+		"%Y-%m-%dT%H:%M:%S.%f%z": "2006-01-02T15:04:05.000000-0700",
+		"%b %d, %Y %I:%M %p":     "Jan 02, 2006 03:04 PM",
+		"%H%%":                   "15%",
+	}
+	for format, want := range tests {
+		got, err := strptimeLayout(format)
+		require.NoError(t, err, format)
+		require.Equal(t, want, got, format)
+	}
+
+	for _, format := range []string{"%Y-%", "100%%", "%d Jan %Y", "%Y_%-d", "day %d", "%Y-%m-%d 1"} {
+		_, err := strptimeLayout(format)
+		require.Error(t, err, format)
+	}
+}
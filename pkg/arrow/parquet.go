@@ -0,0 +1,463 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/parquet"
+	"github.com/apache/arrow/go/v17/parquet/compress"
+	"github.com/apache/arrow/go/v17/parquet/pqarrow"
+)
+
+// defaultMaxOpenFiles is the default of ParquetOptions.MaxOpenFiles.
+const defaultMaxOpenFiles = 64
+
+// hiveNullPartition names the partition directory of NULL values, as Hive does.
+const hiveNullPartition = "__HIVE_DEFAULT_PARTITION__"
+
+// ParquetOptions configures Parquet output.
+type ParquetOptions struct {
+	// RowGroupSize is the maximum number of rows per row group. It defaults
+	// to the Parquet writer's default of 64Mi rows.
+	RowGroupSize int64
+	// Compression is one of "snappy" (the default), "gzip", "brotli", "zstd"
+	// or "none".
+	Compression       string
+	DisableDictionary bool
+	DisableStatistics bool
+
+	// PartitionBy lists the columns that split a dataset into Hive-style
+	// col=value directories. The columns are not stored in the files.
+	PartitionBy []string
+	// FilePrefix names dataset files <prefix>-00000.parquet, numbered from
+	// zero in each directory. It defaults to "part".
+	FilePrefix string
+	// MaxRowsPerFile starts a new file in a directory once the current one
+	// holds this many rows. Zero means no limit.
+	MaxRowsPerFile int64
+	// MaxOpenFiles caps the files kept open while writing a partitioned
+	// dataset. When a new file would exceed it, the least recently written
+	// one is closed, and rows for its directory continue in a new file. It
+	// defaults to 64.
+	MaxOpenFiles int
+
+	// BatchSize is the number of rows read per record when exporting query
+	// results. It defaults to 2048.
+	BatchSize int
+}
+
+func (o ParquetOptions) properties(a *Arrow) (*parquet.WriterProperties, pqarrow.ArrowWriterProperties, error) {
+	codec, err := parquetCodec(o.Compression)
+	if err != nil {
+		return nil, pqarrow.ArrowWriterProperties{}, err
+	}
+
+	opts := []parquet.WriterProperty{
+		parquet.WithAllocator(a.mem),
+		parquet.WithCompression(codec),
+		parquet.WithDictionaryDefault(!o.DisableDictionary),
+		parquet.WithStats(!o.DisableStatistics),
+	}
+	if o.RowGroupSize > 0 {
+		opts = append(opts, parquet.WithMaxRowGroupLength(o.RowGroupSize))
+	}
+
+	// Storing the Arrow schema keeps field and schema metadata.
+	arrprops := pqarrow.NewArrowWriterProperties(pqarrow.WithAllocator(a.mem), pqarrow.WithStoreSchema())
+	return parquet.NewWriterProperties(opts...), arrprops, nil
+}
+
+func parquetCodec(name string) (compress.Compression, error) {
+	switch strings.ToLower(name) {
+	case "", "snappy":
+		return compress.Codecs.Snappy, nil
+	case "none", "uncompressed":
+		return compress.Codecs.Uncompressed, nil
+	case "gzip":
+		return compress.Codecs.Gzip, nil
+	case "brotli":
+		return compress.Codecs.Brotli, nil
+	case "zstd":
+		return compress.Codecs.Zstd, nil
+	default:
+		return compress.Codecs.Uncompressed, fmt.Errorf("unsupported parquet compression: %s", name)
+	}
+}
+
+// WriteParquet writes the records of rdr to w as a single Parquet file.
+// Partitioning options do not apply.
+func (a *Arrow) WriteParquet(w io.Writer, rdr array.RecordReader, opts ParquetOptions) error {
+	if len(opts.PartitionBy) > 0 {
+		return fmt.Errorf("partitioned output needs a directory, use WriteParquetDataset")
+	}
+
+	props, arrprops, err := opts.properties(a)
+	if err != nil {
+		return err
+	}
+
+	// The parquet writer closes writers that are io.Closers; w is the caller's.
+	fw, err := pqarrow.NewFileWriter(rdr.Schema(), struct{ io.Writer }{w}, props, arrprops)
+	if err != nil {
+		return fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+	for rdr.Next() {
+		if err := fw.WriteBuffered(rdr.Record()); err != nil {
+			fw.Close()
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+	if err := rdr.Err(); err != nil {
+		fw.Close()
+		return err
+	}
+	if err := fw.Close(); err != nil {
+		return fmt.Errorf("failed to close parquet writer: %w", err)
+	}
+	return nil
+}
+
+// ExportParquet runs query and writes its results as a Parquet dataset under
+// dir. See WriteParquetDataset.
+func (a *Arrow) ExportParquet(ctx context.Context, dir string, opts ParquetOptions, query string, args ...interface{}) ([]string, error) {
+	rdr, err := a.QueryArrowStream(ctx, exportBatchSize(opts.BatchSize), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Release()
+
+	return a.WriteParquetDataset(dir, rdr, opts)
+}
+
+// WriteParquetDataset writes the records of rdr as Parquet files under dir,
+// split into Hive-style directories by opts.PartitionBy, and returns the paths
+// of the files written in sorted order. The same input always produces the
+// same file names. An input without rows produces a single file that holds
+// only the schema. If writing fails, the files already created are removed.
+func (a *Arrow) WriteParquetDataset(dir string, rdr array.RecordReader, opts ParquetOptions) ([]string, error) {
+	ds, err := a.newParquetDataset(dir, rdr.Schema(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for rdr.Next() {
+		if err := ds.write(rdr.Record()); err != nil {
+			ds.abort()
+			return nil, err
+		}
+	}
+	if err := rdr.Err(); err != nil {
+		ds.abort()
+		return nil, err
+	}
+	if len(ds.paths) == 0 {
+		// Keep the schema, so that readers of dir still see the columns.
+		if _, err := ds.file(""); err != nil {
+			ds.abort()
+			return nil, err
+		}
+	}
+	if err := ds.close(); err != nil {
+		ds.abort()
+		return nil, err
+	}
+
+	sort.Strings(ds.paths)
+	return ds.paths, nil
+}
+
+type parquetDataset struct {
+	dir      string
+	opts     ParquetOptions
+	props    *parquet.WriterProperties
+	arrprops pqarrow.ArrowWriterProperties
+
+	schema    *arrow.Schema // the file schema, without partition columns
+	keep      []int
+	partition []int
+
+	files map[string]*parquetFile
+	paths []string
+	open  int   // the files whose writer is open
+	clock int64 // orders file uses for closing the least recently used
+}
+
+type parquetFile struct {
+	f     *os.File
+	w     *pqarrow.FileWriter
+	index int
+	rows  int64
+	used  int64
+}
+
+func (a *Arrow) newParquetDataset(dir string, schema *arrow.Schema, opts ParquetOptions) (*parquetDataset, error) {
+	props, arrprops, err := opts.properties(a)
+	if err != nil {
+		return nil, err
+	}
+	if opts.FilePrefix == "" {
+		opts.FilePrefix = "part"
+	}
+	if opts.MaxOpenFiles <= 0 {
+		opts.MaxOpenFiles = defaultMaxOpenFiles
+	}
+
+	ds := &parquetDataset{dir: dir, opts: opts, props: props, arrprops: arrprops, files: make(map[string]*parquetFile)}
+
+	partitioned := make(map[int]bool)
+	for _, name := range opts.PartitionBy {
+		idx := schema.FieldIndices(name)
+		if len(idx) != 1 {
+			return nil, fmt.Errorf("partition column %s not found", name)
+		}
+		if _, ok := schema.Field(idx[0]).Type.(arrow.NestedType); ok {
+			return nil, fmt.Errorf("partition column %s has nested type %s", name, schema.Field(idx[0]).Type)
+		}
+		partitioned[idx[0]] = true
+		ds.partition = append(ds.partition, idx[0])
+	}
+
+	var fields []arrow.Field
+	for i, f := range schema.Fields() {
+		if !partitioned[i] {
+			ds.keep = append(ds.keep, i)
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("every column is a partition column")
+	}
+	md := schema.Metadata()
+	ds.schema = arrow.NewSchema(fields, &md)
+	return ds, nil
+}
+
+// write splits rec into runs of consecutive rows with the same partition
+// values and appends each run to its partition's file.
+func (ds *parquetDataset) write(rec arrow.Record) error {
+	n := int(rec.NumRows())
+	if len(ds.partition) == 0 {
+		return ds.writeTo("", rec, 0, n)
+	}
+
+	start, key := 0, ""
+	for i := 0; i < n; i++ {
+		k := ds.partitionKey(rec, i)
+		if i > 0 && k != key {
+			if err := ds.writeTo(key, rec, start, i); err != nil {
+				return err
+			}
+			start = i
+		}
+		key = k
+	}
+	if n > 0 {
+		return ds.writeTo(key, rec, start, n)
+	}
+	return nil
+}
+
+// partitionKey is the relative directory of row i, e.g. "year=2024/city=Oslo".
+func (ds *parquetDataset) partitionKey(rec arrow.Record, i int) string {
+	parts := make([]string, len(ds.partition))
+	for j, c := range ds.partition {
+		col := rec.Column(c)
+		value := hiveNullPartition
+		if !col.IsNull(i) {
+			value = hiveEscape(col.ValueStr(i))
+		}
+		parts[j] = hiveEscape(rec.ColumnName(c)) + "=" + value
+	}
+	return strings.Join(parts, "/")
+}
+
+func (ds *parquetDataset) writeTo(key string, rec arrow.Record, start, end int) error {
+	for start < end {
+		pf, err := ds.file(key)
+		if err != nil {
+			return err
+		}
+
+		stop := end
+		if max := ds.opts.MaxRowsPerFile; max > 0 && pf.rows+int64(stop-start) > max {
+			stop = start + int(max-pf.rows)
+		}
+
+		cols := make([]arrow.Array, len(ds.keep))
+		for j, c := range ds.keep {
+			cols[j] = array.NewSlice(rec.Column(c), int64(start), int64(stop))
+		}
+		slice := array.NewRecord(ds.schema, cols, int64(stop-start))
+		for _, col := range cols {
+			col.Release()
+		}
+		err = pf.w.WriteBuffered(slice)
+		slice.Release()
+		if err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+
+		pf.rows += int64(stop - start)
+		start = stop
+	}
+	return nil
+}
+
+// file returns the open file for key, rolling over to the next file once the
+// current one is full or has been closed to stay within MaxOpenFiles.
+func (ds *parquetDataset) file(key string) (*parquetFile, error) {
+	ds.clock++
+	pf, ok := ds.files[key]
+	if ok && pf.w != nil && (ds.opts.MaxRowsPerFile <= 0 || pf.rows < ds.opts.MaxRowsPerFile) {
+		pf.used = ds.clock
+		return pf, nil
+	}
+
+	index := 0
+	if ok {
+		if err := ds.closeFile(pf); err != nil {
+			return nil, err
+		}
+		index = pf.index + 1
+	}
+	if ds.open >= ds.opts.MaxOpenFiles {
+		if err := ds.closeLeastRecent(); err != nil {
+			return nil, err
+		}
+	}
+
+	dir := filepath.Join(ds.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%05d.parquet", ds.opts.FilePrefix, index))
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	ds.paths = append(ds.paths, path)
+
+	w, err := pqarrow.NewFileWriter(ds.schema, f, ds.props, ds.arrprops)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+
+	pf = &parquetFile{f: f, w: w, index: index, used: ds.clock}
+	ds.files[key] = pf
+	ds.open++
+	return pf, nil
+}
+
+func (ds *parquetDataset) closeFile(pf *parquetFile) error {
+	if pf.w != nil {
+		ds.open--
+	}
+	return pf.close()
+}
+
+// closeLeastRecent closes the open file that was written longest ago.
+func (ds *parquetDataset) closeLeastRecent() error {
+	var oldest *parquetFile
+	for _, pf := range ds.files {
+		if pf.w != nil && (oldest == nil || pf.used < oldest.used) {
+			oldest = pf
+		}
+	}
+	if oldest == nil {
+		return nil
+	}
+	return ds.closeFile(oldest)
+}
+
+func (pf *parquetFile) close() error {
+	if pf.w == nil {
+		return nil
+	}
+	// Closing the parquet writer also closes the file.
+	err := pf.w.Close()
+	pf.w = nil
+	if err != nil {
+		return fmt.Errorf("failed to close parquet writer: %w", err)
+	}
+	return nil
+}
+
+func (ds *parquetDataset) close() error {
+	keys := make([]string, 0, len(ds.files))
+	for key := range ds.files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var first error
+	for _, key := range keys {
+		if err := ds.closeFile(ds.files[key]); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// abort closes and removes every file written so far.
+func (ds *parquetDataset) abort() {
+	for _, pf := range ds.files {
+		pf.close()
+		pf.f.Close()
+	}
+	for _, path := range ds.paths {
+		os.Remove(path)
+	}
+}
+
+// hiveEscape percent-encodes the characters Hive escapes in partition
+// directory names.
+func hiveEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c == 0x7f || strings.IndexByte("\"#%'*/:=?\\{[]^", c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
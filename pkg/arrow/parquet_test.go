@@ -0,0 +1,247 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/parquet/compress"
+	"github.com/apache/arrow/go/v17/parquet/file"
+	"github.com/apache/arrow/go/v17/parquet/pqarrow"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSalesDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+
+	_, err = db.Exec(`
+		CREATE TABLE sales AS
+		SELECT range AS id,
+			CASE WHEN range % 5 = 4 THEN NULL ELSE ['north', 'south', 'east/west', 'north'][range % 5 + 1] END AS region,
+			2020 + range % 2 AS year,
+			(range * 1.25)::DECIMAL(10,2) AS amount
+		FROM range(100)
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestWriteParquet(t *testing.T) {
+	db := setupSalesDB(t)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	arrowInstance := NewArrow(db, WithAllocator(mem))
+
+	query := "SELECT id, region, amount FROM sales ORDER BY id"
+	rdr, err := arrowInstance.QueryArrowStream(context.Background(), 7, query)
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	var buf bytes.Buffer
+	opts := ParquetOptions{RowGroupSize: 30, Compression: "zstd", DisableStatistics: true}
+	require.NoError(t, arrowInstance.WriteParquet(&buf, rdr, opts))
+
+	pf, err := file.NewParquetReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	defer pf.Close()
+
+	require.Equal(t, int64(100), pf.NumRows())
+	require.Equal(t, 4, pf.NumRowGroups())
+	chunk, err := pf.MetaData().RowGroup(0).ColumnChunk(0)
+	require.NoError(t, err)
+	require.Equal(t, compress.Codecs.Zstd, chunk.Compression())
+	stats, err := chunk.StatsSet()
+	require.NoError(t, err)
+	require.False(t, stats)
+
+	fr, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	require.NoError(t, err)
+	schema, err := fr.Schema()
+	require.NoError(t, err)
+	stored, _ := schema.Metadata().GetValue(MetadataQuery)
+	require.Equal(t, query, stored)
+	precision, _ := schema.Field(2).Metadata.GetValue(MetadataPrecision)
+	require.Equal(t, "10", precision)
+
+	var count int
+	var total float64
+	path := filepath.Join(t.TempDir(), "sales.parquet")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	err = db.QueryRow(fmt.Sprintf("SELECT count(*), sum(amount)::DOUBLE FROM read_parquet('%s')", path)).Scan(&count, &total)
+	require.NoError(t, err)
+	require.Equal(t, 100, count)
+	require.Equal(t, 6187.5, total)
+}
+
+func TestExportParquetPartitioned(t *testing.T) {
+	db := setupSalesDB(t)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	arrowInstance := NewArrow(db, WithAllocator(mem))
+
+	dir := t.TempDir()
+	opts := ParquetOptions{PartitionBy: []string{"year", "region"}, MaxRowsPerFile: 8, BatchSize: 16}
+	paths, err := arrowInstance.ExportParquet(context.Background(), dir, opts, "SELECT * FROM sales ORDER BY id")
+	require.NoError(t, err)
+
+	rel := make([]string, len(paths))
+	for i, p := range paths {
+		rel[i], err = filepath.Rel(dir, p)
+		require.NoError(t, err)
+		rel[i] = filepath.ToSlash(rel[i])
+	}
+	// 10 rows per (year, region) pair, except 20 for north
+	require.Equal(t, []string{
+		"year=2020/region=__HIVE_DEFAULT_PARTITION__/part-00000.parquet",
+		"year=2020/region=__HIVE_DEFAULT_PARTITION__/part-00001.parquet",
+		"year=2020/region=east%2Fwest/part-00000.parquet",
+		"year=2020/region=east%2Fwest/part-00001.parquet",
+		"year=2020/region=north/part-00000.parquet",
+		"year=2020/region=north/part-00001.parquet",
+		"year=2020/region=north/part-00002.parquet",
+		"year=2020/region=south/part-00000.parquet",
+		"year=2020/region=south/part-00001.parquet",
+		"year=2021/region=__HIVE_DEFAULT_PARTITION__/part-00000.parquet",
+		"year=2021/region=__HIVE_DEFAULT_PARTITION__/part-00001.parquet",
+		"year=2021/region=east%2Fwest/part-00000.parquet",
+		"year=2021/region=east%2Fwest/part-00001.parquet",
+		"year=2021/region=north/part-00000.parquet",
+		"year=2021/region=north/part-00001.parquet",
+		"year=2021/region=north/part-00002.parquet",
+		"year=2021/region=south/part-00000.parquet",
+		"year=2021/region=south/part-00001.parquet",
+	}, rel)
+
+	var count, years int
+	var total float64
+	err = db.QueryRow(fmt.Sprintf(`SELECT count(*), count(DISTINCT year), sum(amount)::DOUBLE
+		FROM read_parquet('%s/**/*.parquet', hive_partitioning = true)
+		WHERE region = 'south'`, dir)).Scan(&count, &years, &total)
+	require.NoError(t, err)
+	require.Equal(t, 20, count)
+	require.Equal(t, 2, years)
+
+	var want float64
+	require.NoError(t, db.QueryRow("SELECT sum(amount)::DOUBLE FROM sales WHERE region = 'south'").Scan(&want))
+	require.Equal(t, want, total)
+
+	// partition columns are left out of the files
+	var columns int
+	err = db.QueryRow(fmt.Sprintf("SELECT count(*) FROM (DESCRIBE SELECT * FROM read_parquet('%s', hive_partitioning = false))", paths[0])).Scan(&columns)
+	require.NoError(t, err)
+	require.Equal(t, 2, columns)
+}
+
+func TestExportParquetEmpty(t *testing.T) {
+	db := setupSalesDB(t)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+	dir := t.TempDir()
+	opts := ParquetOptions{PartitionBy: []string{"year"}}
+	paths, err := arrowInstance.ExportParquet(context.Background(), dir, opts, "SELECT * FROM sales WHERE id < 0")
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "part-00000.parquet")}, paths)
+
+	var count, columns int
+	require.NoError(t, db.QueryRow(fmt.Sprintf("SELECT count(*) FROM read_parquet('%s')", paths[0])).Scan(&count))
+	require.Equal(t, 0, count)
+	err = db.QueryRow(fmt.Sprintf("SELECT count(*) FROM (DESCRIBE SELECT * FROM read_parquet('%s'))", paths[0])).Scan(&columns)
+	require.NoError(t, err)
+	require.Equal(t, 3, columns)
+}
+
+func TestExportParquetMaxOpenFiles(t *testing.T) {
+	db := setupSalesDB(t)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	arrowInstance := NewArrow(db, WithAllocator(mem))
+
+	// The years alternate every 25 rows, so each directory is returned to
+	// after its file has been closed.
+	dir := t.TempDir()
+	opts := ParquetOptions{PartitionBy: []string{"year"}, MaxOpenFiles: 1, BatchSize: 10}
+	paths, err := arrowInstance.ExportParquet(context.Background(), dir, opts, "SELECT * FROM sales ORDER BY id % 4, id")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "year=2020", "part-00000.parquet"),
+		filepath.Join(dir, "year=2020", "part-00001.parquet"),
+		filepath.Join(dir, "year=2021", "part-00000.parquet"),
+		filepath.Join(dir, "year=2021", "part-00001.parquet"),
+	}, paths)
+
+	var count, years int
+	err = db.QueryRow(fmt.Sprintf(`SELECT count(*), count(DISTINCT year)
+		FROM read_parquet('%s/**/*.parquet', hive_partitioning = true)`, dir)).Scan(&count, &years)
+	require.NoError(t, err)
+	require.Equal(t, 100, count)
+	require.Equal(t, 2, years)
+}
+
+func TestParquetOptionErrors(t *testing.T) {
+	db := setupSalesDB(t)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	_, err := arrowInstance.ExportParquet(ctx, dir, ParquetOptions{Compression: "lzo"}, "SELECT * FROM sales")
+	require.ErrorContains(t, err, "unsupported parquet compression")
+	_, err = arrowInstance.ExportParquet(ctx, dir, ParquetOptions{PartitionBy: []string{"missing"}}, "SELECT * FROM sales")
+	require.ErrorContains(t, err, "partition column missing not found")
+	_, err = arrowInstance.ExportParquet(ctx, dir, ParquetOptions{PartitionBy: []string{"id"}}, "SELECT id FROM sales")
+	require.Error(t, err)
+
+	rdr, err := arrowInstance.QueryArrowStream(ctx, 10, "SELECT * FROM sales")
+	require.NoError(t, err)
+	defer rdr.Release()
+	require.Error(t, arrowInstance.WriteParquet(&bytes.Buffer{}, rdr, ParquetOptions{PartitionBy: []string{"year"}}))
+}
+
+func TestHiveEscape(t *testing.T) {
+	require.Equal(t, "2024-01-02 03%3A04%3A05Z", hiveEscape("2024-01-02 03:04:05Z"))
+	require.Equal(t, "a%3Db%2Fc%25d", hiveEscape("a=b/c%d"))
+	require.Equal(t, "plain value", hiveEscape("plain value"))
+}
@@ -0,0 +1,358 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+)
+
+// Partition is one slice of a logical query. Every partition of a query must
+// produce the same columns.
+type Partition struct {
+	Query string
+	Args  []interface{}
+}
+
+// RangePartitions splits query into n partitions over the integer key column,
+// covering lo through hi inclusive in contiguous, ascending ranges. Rows with a
+// NULL key fall outside every range.
+func RangePartitions(query, column string, lo, hi int64, n int) []Partition {
+	if n <= 0 || hi < lo {
+		return nil
+	}
+	span := uint64(hi-lo) + 1
+	if uint64(n) > span {
+		n = int(span)
+	}
+
+	base := fmt.Sprintf("SELECT * FROM (%s) AS q WHERE %s", strings.TrimRight(query, "; \t\n"), QuoteIdent(column))
+	parts := make([]Partition, n)
+	start := lo
+	for i := 0; i < n; i++ {
+		size := int64(span / uint64(n))
+		if uint64(i) < span%uint64(n) {
+			size++
+		}
+		end := start + size - 1
+		parts[i] = Partition{Query: base + " BETWEEN ? AND ?", Args: []interface{}{start, end}}
+		start = end + 1
+	}
+	return parts
+}
+
+// ParquetRowGroupPartitions splits a scan of the Parquet file at path into at
+// most n partitions of whole, consecutive row groups.
+func (a *Arrow) ParquetRowGroupPartitions(ctx context.Context, path string, n int) ([]Partition, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid partition count: %d", n)
+	}
+
+	file := QuoteString(path)
+	rows, err := a.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT DISTINCT row_group_id, row_group_num_rows FROM parquet_metadata(%s) ORDER BY row_group_id", file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet metadata: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []int64
+	for rows.Next() {
+		var id, numRows int64
+		if err := rows.Scan(&id, &numRows); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		groups = append(groups, numRows)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	if n > len(groups) {
+		n = len(groups)
+	}
+
+	query := fmt.Sprintf("SELECT * EXCLUDE (file_row_number) FROM read_parquet(%s, file_row_number = true)"+
+		" WHERE file_row_number >= ? AND file_row_number < ?", file)
+	parts := make([]Partition, 0, n)
+	var start, end int64
+	for i, g := 0, 0; i < n; i++ {
+		// spread the remaining row groups evenly over the remaining partitions
+		take := (len(groups) - g) / (n - i)
+		for ; take > 0; take-- {
+			end += groups[g]
+			g++
+		}
+		parts = append(parts, Partition{Query: query, Args: []interface{}{start, end}})
+		start = end
+	}
+	return parts, nil
+}
+
+// QueryPartitioned runs the partitions concurrently on at most workers
+// connections and merges their results, in partition order, into a table.
+// The first failure cancels the remaining partitions.
+func (a *Arrow) QueryPartitioned(ctx context.Context, parts []Partition, workers int) (arrow.Table, error) {
+	rdr, err := a.QueryPartitionedStream(ctx, parts, workers)
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Release()
+
+	recs, err := collectRecords(rdr)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseRecords(recs)
+
+	return array.NewTableFromRecords(rdr.Schema(), recs), nil
+}
+
+// QueryPartitionedStream is like QueryPartitioned but returns the partition
+// results as a reader, one record per non-empty partition in partition order.
+// At most workers results are held in memory at once. The caller must release
+// the reader, which cancels any partitions still running.
+func (a *Arrow) QueryPartitionedStream(ctx context.Context, parts []Partition, workers int) (array.RecordReader, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no partitions to query")
+	}
+	if workers <= 0 {
+		return nil, fmt.Errorf("invalid worker count: %d", workers)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r := &partitionReader{
+		refCount: 1,
+		cancel:   cancel,
+		results:  make([]chan partitionResult, len(parts)),
+		sem:      make(chan struct{}, workers),
+	}
+	for i := range r.results {
+		r.results[i] = make(chan partitionResult, 1)
+	}
+
+	r.wg.Add(1)
+	go r.dispatch(ctx, a, parts)
+
+	// The first partition fixes the schema of the stream.
+	first := r.take(0)
+	r.next = 1
+	if first.err != nil {
+		r.Release()
+		return nil, r.Err()
+	}
+	r.schema = a.mergedSchema(first.rec.Schema(), parts)
+	r.pending = first.rec
+	return r, nil
+}
+
+type partitionResult struct {
+	rec arrow.Record
+	err error
+}
+
+// partitionReader yields the results of concurrently running partitions in
+// order. Each partition holds a slot of sem from the time it starts until its
+// result is consumed, bounding the results held in memory.
+type partitionReader struct {
+	refCount int64
+
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	results []chan partitionResult
+	sem     chan struct{}
+	started int64
+
+	schema  *arrow.Schema
+	pending arrow.Record
+	next    int
+	cur     arrow.Record
+
+	errMu sync.Mutex
+	err   error
+}
+
+var _ array.RecordReader = (*partitionReader)(nil)
+
+func (r *partitionReader) dispatch(ctx context.Context, a *Arrow, parts []Partition) {
+	defer r.wg.Done()
+	for i, p := range parts {
+		select {
+		case r.sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		atomic.StoreInt64(&r.started, int64(i+1))
+
+		r.wg.Add(1)
+		go func(i int, p Partition) {
+			defer r.wg.Done()
+			rec, err := a.QueryArrow(ctx, p.Query, p.Args...)
+			if err != nil {
+				r.fail(fmt.Errorf("partition %d: %w", i, err))
+			}
+			r.results[i] <- partitionResult{rec: rec, err: err}
+		}(i, p)
+	}
+}
+
+// fail records the first partition error and cancels the others.
+func (r *partitionReader) fail(err error) {
+	r.errMu.Lock()
+	defer r.errMu.Unlock()
+	if r.err == nil {
+		r.err = err
+		r.cancel()
+	}
+}
+
+// take waits for the result of partition i and frees its slot.
+func (r *partitionReader) take(i int) partitionResult {
+	res := <-r.results[i]
+	<-r.sem
+	return res
+}
+
+func (r *partitionReader) Retain() {
+	atomic.AddInt64(&r.refCount, 1)
+}
+
+func (r *partitionReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) != 0 {
+		return
+	}
+
+	r.cancel()
+	r.wg.Wait()
+
+	started := int(atomic.LoadInt64(&r.started))
+	for i := r.next; i < started; i++ {
+		if res := <-r.results[i]; res.rec != nil {
+			res.rec.Release()
+		}
+	}
+	if r.pending != nil {
+		r.pending.Release()
+		r.pending = nil
+	}
+	if r.cur != nil {
+		r.cur.Release()
+		r.cur = nil
+	}
+}
+
+func (r *partitionReader) Schema() *arrow.Schema { return r.schema }
+
+func (r *partitionReader) Record() arrow.Record { return r.cur }
+
+func (r *partitionReader) Err() error {
+	r.errMu.Lock()
+	defer r.errMu.Unlock()
+	return r.err
+}
+
+func (r *partitionReader) Next() bool {
+	if r.cur != nil {
+		r.cur.Release()
+		r.cur = nil
+	}
+
+	for r.Err() == nil {
+		var rec arrow.Record
+		if r.pending != nil {
+			rec, r.pending = r.pending, nil
+		} else {
+			if r.next >= len(r.results) {
+				return false
+			}
+			res := r.take(r.next)
+			r.next++
+			if res.err != nil {
+				return false
+			}
+			rec = res.rec
+		}
+
+		if !SameColumns(r.schema, rec.Schema()) {
+			rec.Release()
+			r.fail(fmt.Errorf("partition %d: schema %s does not match %s", r.next-1, rec.Schema(), r.schema))
+			return false
+		}
+		if rec.NumRows() == 0 {
+			rec.Release()
+			continue
+		}
+
+		r.cur = array.NewRecord(r.schema, rec.Columns(), rec.NumRows())
+		rec.Release()
+		return true
+	}
+	return false
+}
+
+// mergedSchema is the schema of the first partition with the query metadata
+// listing every partition's query.
+func (a *Arrow) mergedSchema(schema *arrow.Schema, parts []Partition) *arrow.Schema {
+	queries := make([]string, len(parts))
+	for i, p := range parts {
+		queries[i] = p.Query
+		if a.redactQuery != nil {
+			queries[i] = a.redactQuery(p.Query)
+		}
+	}
+
+	md := schema.Metadata()
+	keys, values := append([]string(nil), md.Keys()...), append([]string(nil), md.Values()...)
+	if i := md.FindKey(MetadataQuery); i >= 0 {
+		values[i] = strings.Join(queries, ";\n")
+	}
+	merged := arrow.NewMetadata(keys, values)
+	return arrow.NewSchema(schema.Fields(), &merged)
+}
+
+// SameColumns reports whether two schemas have the same column names and
+// types in the same order, ignoring nullability and metadata.
+func SameColumns(a, b *arrow.Schema) bool {
+	if a.NumFields() != b.NumFields() {
+		return false
+	}
+	for i := range a.Fields() {
+		fa, fb := a.Field(i), b.Field(i)
+		if fa.Name != fb.Name || !arrow.TypeEqual(fa.Type, fb.Type) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,194 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPartitionDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+
+	_, err = db.Exec(`CREATE TABLE numbers AS SELECT range AS id, range % 7 AS bucket FROM range(1000)`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestRangePartitions(t *testing.T) {
+	parts := RangePartitions("SELECT * FROM numbers;", "id", 1, 10, 3)
+	require.Len(t, parts, 3)
+	require.Equal(t, `SELECT * FROM (SELECT * FROM numbers) AS q WHERE "id" BETWEEN ? AND ?`, parts[0].Query)
+	require.Equal(t, []interface{}{int64(1), int64(4)}, parts[0].Args)
+	require.Equal(t, []interface{}{int64(5), int64(7)}, parts[1].Args)
+	require.Equal(t, []interface{}{int64(8), int64(10)}, parts[2].Args)
+
+	require.Len(t, RangePartitions("SELECT 1", "id", 1, 2, 5), 2)
+	require.Nil(t, RangePartitions("SELECT 1", "id", 2, 1, 5))
+}
+
+func TestQueryPartitioned(t *testing.T) {
+	db := setupPartitionDB(t)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	arrowInstance := NewArrow(db, WithAllocator(mem))
+	parts := RangePartitions("SELECT id, bucket FROM numbers ORDER BY id", "id", 0, 999, 8)
+	table, err := arrowInstance.QueryPartitioned(context.Background(), parts, 3)
+	require.NoError(t, err)
+	defer table.Release()
+
+	require.Equal(t, int64(1000), table.NumRows())
+	require.Equal(t, int64(2), table.NumCols())
+	require.Equal(t, "id", table.Schema().Field(0).Name)
+
+	// partition order is kept
+	tr := array.NewTableReader(table, 0)
+	defer tr.Release()
+	next := int64(0)
+	for tr.Next() {
+		ids := tr.Record().Column(0).(*array.Int64)
+		for i := 0; i < ids.Len(); i++ {
+			require.Equal(t, next, ids.Value(i))
+			next++
+		}
+	}
+	require.Equal(t, int64(1000), next)
+}
+
+func TestQueryPartitionedStream(t *testing.T) {
+	db := setupPartitionDB(t)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+	parts := []Partition{
+		{Query: "SELECT id FROM numbers WHERE bucket = ? ORDER BY id", Args: []interface{}{0}},
+		{Query: "SELECT id FROM numbers WHERE id < 0"},
+		{Query: "SELECT id FROM numbers WHERE bucket = ? ORDER BY id", Args: []interface{}{1}},
+	}
+	rdr, err := arrowInstance.QueryPartitionedStream(context.Background(), parts, 2)
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	query, _ := rdr.Schema().Metadata().GetValue(MetadataQuery)
+	require.Contains(t, query, "id < 0")
+
+	var firsts []int64
+	for rdr.Next() {
+		firsts = append(firsts, rdr.Record().Column(0).(*array.Int64).Value(0))
+		require.True(t, rdr.Schema().Equal(rdr.Record().Schema()))
+	}
+	require.NoError(t, rdr.Err())
+	// the empty partition yields no record
+	require.Equal(t, []int64{0, 1}, firsts)
+}
+
+func TestQueryPartitionedFailure(t *testing.T) {
+	db := setupPartitionDB(t)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	arrowInstance := NewArrow(db, WithAllocator(mem))
+	parts := RangePartitions("SELECT id FROM numbers", "id", 0, 999, 10)
+	parts[6] = Partition{Query: "SELECT CAST(error('partition failed') AS BIGINT) AS id"}
+
+	_, err := arrowInstance.QueryPartitioned(context.Background(), parts, 4)
+	require.ErrorContains(t, err, "partition 6")
+	require.ErrorContains(t, err, "partition failed")
+
+	_, err = arrowInstance.QueryPartitioned(context.Background(), []Partition{
+		{Query: "SELECT id FROM numbers"},
+		{Query: "SELECT bucket FROM numbers"},
+	}, 2)
+	require.ErrorContains(t, err, "does not match")
+
+	_, err = arrowInstance.QueryPartitioned(context.Background(), []Partition{{Query: "SELECT missing"}}, 1)
+	require.ErrorContains(t, err, "partition 0")
+
+	_, err = arrowInstance.QueryPartitioned(context.Background(), parts, 0)
+	require.Error(t, err)
+	_, err = arrowInstance.QueryPartitioned(context.Background(), nil, 1)
+	require.Error(t, err)
+}
+
+func TestQueryPartitionedEarlyRelease(t *testing.T) {
+	db := setupPartitionDB(t)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	arrowInstance := NewArrow(db, WithAllocator(mem))
+	rdr, err := arrowInstance.QueryPartitionedStream(context.Background(),
+		RangePartitions("SELECT id FROM numbers", "id", 0, 999, 20), 4)
+	require.NoError(t, err)
+	require.True(t, rdr.Next())
+	rdr.Release()
+}
+
+func TestParquetRowGroupPartitions(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	path := filepath.Join(t.TempDir(), "numbers.parquet")
+	_, err = db.Exec(fmt.Sprintf(`COPY (SELECT range AS id FROM range(10000)) TO '%s' (FORMAT PARQUET, ROW_GROUP_SIZE 2048)`, path))
+	require.NoError(t, err)
+
+	arrowInstance := NewArrow(db)
+	parts, err := arrowInstance.ParquetRowGroupPartitions(context.Background(), path, 3)
+	require.NoError(t, err)
+	require.Len(t, parts, 3)
+
+	table, err := arrowInstance.QueryPartitioned(context.Background(), parts, 3)
+	require.NoError(t, err)
+	defer table.Release()
+
+	require.Equal(t, int64(10000), table.NumRows())
+	require.Equal(t, int64(1), table.NumCols())
+	require.Equal(t, "id", table.Schema().Field(0).Name)
+	require.Equal(t, arrow.PrimitiveTypes.Int64, table.Schema().Field(0).Type)
+
+	_, err = arrowInstance.ParquetRowGroupPartitions(context.Background(), path, 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,205 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+)
+
+// rowReader is an array.RecordReader that converts sql.Rows into Arrow
+// records of at most batchSize rows each.
+type rowReader struct {
+	refCount int64
+
+	ctx       context.Context
+	rows      *sql.Rows
+	columns   []*sql.ColumnType
+	schema    *arrow.Schema
+	bldr      *array.RecordBuilder
+	values    []interface{}
+	batchSize int
+
+	query   string
+	mem     *queryAllocator
+	onStats func(QueryStats)
+
+	cur  arrow.Record
+	err  error
+	done bool
+}
+
+var _ array.RecordReader = (*rowReader)(nil)
+
+// newRowReader takes ownership of rows, which are closed once the reader is
+// exhausted or released. A batchSize of zero or less reads every remaining
+// row into a single record. The fields are derived from the result columns
+// unless given.
+func (a *Arrow) newRowReader(ctx context.Context, query string, src columnSources, fields []arrow.Field, rows *sql.Rows, batchSize int) (*rowReader, error) {
+	executedAt := time.Now()
+
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	if fields == nil {
+		fields = make([]arrow.Field, len(columns))
+		for i, col := range columns {
+			field, err := a.columnField(col)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("column %s: %w", col.Name(), err)
+			}
+			fields[i] = src.withMetadata(field, col.DatabaseTypeName())
+		}
+	} else if len(fields) != len(columns) {
+		rows.Close()
+		return nil, fmt.Errorf("schema has %d fields but the result has %d columns", len(fields), len(columns))
+	}
+
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+
+	mem := newQueryAllocator(a.mem, a.memLimit)
+	schema := arrow.NewSchema(fields, a.queryMetadata(query, executedAt))
+	return &rowReader{
+		refCount:  1,
+		ctx:       ctx,
+		rows:      rows,
+		columns:   columns,
+		schema:    schema,
+		bldr:      array.NewRecordBuilder(mem, schema),
+		values:    values,
+		batchSize: batchSize,
+		query:     query,
+		mem:       mem,
+		onStats:   a.onStats,
+	}, nil
+}
+
+func (r *rowReader) Retain() {
+	atomic.AddInt64(&r.refCount, 1)
+}
+
+func (r *rowReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) == 0 {
+		if r.cur != nil {
+			r.cur.Release()
+			r.cur = nil
+		}
+		r.bldr.Release()
+		r.rows.Close()
+		if r.onStats != nil {
+			r.onStats(r.mem.stats(r.query))
+		}
+	}
+}
+
+func (r *rowReader) Schema() *arrow.Schema { return r.schema }
+
+func (r *rowReader) Record() arrow.Record { return r.cur }
+
+func (r *rowReader) Err() error { return r.err }
+
+func (r *rowReader) Next() bool {
+	if r.cur != nil {
+		r.cur.Release()
+		r.cur = nil
+	}
+	if r.done {
+		return false
+	}
+
+	rec, err := r.readBatch()
+	if err != nil {
+		r.err = err
+		r.done = true
+		r.rows.Close()
+		return false
+	}
+	if rec.NumRows() == 0 {
+		rec.Release()
+		return false
+	}
+
+	r.cur = rec
+	return true
+}
+
+// readBatch reads up to batchSize rows into a new record. The record is
+// empty, but still carries the full schema, once the rows are exhausted.
+func (r *rowReader) readBatch() (rec arrow.Record, err error) {
+	defer recoverMemoryLimit(&err)
+
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	n := 0
+	for r.batchSize <= 0 || n < r.batchSize {
+		if !r.rows.Next() {
+			if err := r.rows.Err(); err != nil {
+				return nil, fmt.Errorf("failed to iterate rows: %w", err)
+			}
+			r.done = true
+			r.rows.Close()
+			break
+		}
+
+		if err := r.rows.Scan(r.values...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		for i, val := range r.values {
+			if err := appendValue(r.bldr.Field(i), *val.(*interface{})); err != nil {
+				return nil, fmt.Errorf("failed to append value for column %s: %w", r.columns[i].Name(), err)
+			}
+		}
+		n++
+	}
+
+	rec = r.bldr.NewRecord()
+	if len(r.columns) == 0 && n > 0 {
+		// Without columns the builder cannot carry the row count.
+		rec.Release()
+		rec = array.NewRecord(r.schema, nil, int64(n))
+	}
+	return rec, nil
+}
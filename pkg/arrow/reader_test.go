@@ -0,0 +1,153 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	_ "github.com/marcboeker/go-duckdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryArrowStream(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	arrowInstance := NewArrow(db, WithAllocator(mem))
+
+	rdr, err := arrowInstance.QueryArrowStream(context.Background(), 4, "SELECT i::INTEGER AS id, 'row ' || i AS name FROM range(10) t(i)")
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	require.Equal(t, 2, rdr.Schema().NumFields())
+
+	var batches []int64
+	var next int32
+	for rdr.Next() {
+		rec := rdr.Record()
+		batches = append(batches, rec.NumRows())
+		ids := rec.Column(0).(*array.Int32)
+		for i := 0; i < ids.Len(); i++ {
+			require.Equal(t, next, ids.Value(i))
+			next++
+		}
+	}
+	require.NoError(t, rdr.Err())
+	require.Equal(t, []int64{4, 4, 2}, batches)
+}
+
+func TestQueryArrowStreamCancel(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	arrowInstance := NewArrow(db, WithAllocator(mem))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rdr, err := arrowInstance.QueryArrowStream(ctx, 100, "SELECT i FROM range(100000) t(i)")
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	require.True(t, rdr.Next())
+	cancel()
+
+	for rdr.Next() {
+	}
+	require.ErrorIs(t, rdr.Err(), context.Canceled)
+}
+
+func TestQueryArrowStreamSchema(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int16},
+		{Name: "day", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+	}, nil)
+
+	arrowInstance := NewArrow(db)
+	rdr, err := arrowInstance.QueryArrowStreamSchema(context.Background(), schema, 2,
+		"SELECT i, CASE WHEN i > 0 THEN '2024-01-0' || i END FROM range(3) t(i)")
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	require.True(t, rdr.Schema().Field(0).Equal(schema.Field(0)))
+	var days []string
+	for rdr.Next() {
+		col := rdr.Record().Column(1)
+		for i := 0; i < col.Len(); i++ {
+			days = append(days, col.ValueStr(i))
+		}
+	}
+	require.NoError(t, rdr.Err())
+	require.Equal(t, []string{"(null)", "2024-01-01", "2024-01-02"}, days)
+
+	_, err = arrowInstance.QueryArrowStreamSchema(context.Background(), schema, 2, "SELECT 1")
+	require.Error(t, err)
+}
+
+func TestQueryArrowStreamInvalidBatchSize(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = NewArrow(db).QueryArrowStream(context.Background(), 0, "SELECT 1")
+	require.Error(t, err)
+}
+
+func TestQueryArrowReleasesMemory(t *testing.T) {
+	db, _, err := setupDuckDBWithArrow()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	arrowInstance := NewArrow(db, WithAllocator(mem))
+
+	record, err := arrowInstance.QueryArrow(context.Background(), "SELECT id, name, value FROM test_table")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), record.NumRows())
+	record.Release()
+}
@@ -0,0 +1,113 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+)
+
+// QuerySchema returns the Arrow schema query would produce. DuckDB describes
+// the query without running it; other databases run it wrapped so that it
+// returns no rows.
+func (a *Arrow) QuerySchema(ctx context.Context, query string) (*arrow.Schema, error) {
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\n")
+	src := a.lookupSources(ctx, query)
+	if a.dialect == DialectDuckDB {
+		return a.describeSchema(ctx, query, src)
+	}
+
+	rows, err := a.db.QueryContext(ctx, "SELECT * FROM ("+query+") AS q WHERE 1 = 0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	fields := make([]arrow.Field, len(columns))
+	for i, col := range columns {
+		field, err := a.columnField(col)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.Name(), err)
+		}
+		fields[i] = src.withMetadata(field, col.DatabaseTypeName())
+	}
+	return arrow.NewSchema(fields, a.queryMetadata(query, time.Time{})), nil
+}
+
+func (a *Arrow) describeSchema(ctx context.Context, query string, src columnSources) (*arrow.Schema, error) {
+	rows, err := a.db.QueryContext(ctx, "DESCRIBE "+query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	var fields []arrow.Field
+	for rows.Next() {
+		// column_name, column_type, null, key, default, extra
+		var name, typeName string
+		var null sql.NullString
+		dest := make([]interface{}, len(columns))
+		for i := range dest {
+			dest[i] = new(interface{})
+		}
+		dest[0], dest[1], dest[2] = &name, &typeName, &null
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		dt, ok := a.typeMap[normalizeTypeName(typeName)]
+		if !ok {
+			if dt, err = arrowType(typeName); err != nil {
+				return nil, fmt.Errorf("column %s: %w", name, err)
+			}
+		}
+		field := arrow.Field{Name: name, Type: dt, Nullable: null.String != "NO"}
+		fields = append(fields, src.withMetadata(field, typeName))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return arrow.NewSchema(fields, a.queryMetadata(query, time.Time{})), nil
+}
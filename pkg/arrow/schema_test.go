@@ -0,0 +1,115 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuerySchema(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE events (id BIGINT, tags VARCHAR[], amount DECIMAL(10,2));
+		INSERT INTO events VALUES (1, ['a'], 1.50)`)
+	require.NoError(t, err)
+
+	// A table function that would fail if the query were executed.
+	_, err = db.Exec(`CREATE MACRO boom() AS TABLE SELECT CAST(error('executed') AS INTEGER) AS id`)
+	require.NoError(t, err)
+
+	query := "SELECT events.id, tags, amount, e.id AS boom_id FROM events, boom() e;"
+	arrowInstance := NewArrow(db)
+	_, err = arrowInstance.QueryArrow(context.Background(), query)
+	require.ErrorContains(t, err, "executed")
+
+	schema, err := arrowInstance.QuerySchema(context.Background(), query)
+	require.NoError(t, err)
+
+	expected := []arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+		{Name: "amount", Type: &arrow.Decimal128Type{Precision: 10, Scale: 2}, Nullable: true},
+		{Name: "boom_id", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+	}
+	require.Equal(t, len(expected), schema.NumFields())
+	for i, f := range expected {
+		got := schema.Field(i)
+		require.Equal(t, f.Name, got.Name)
+		require.Truef(t, arrow.TypeEqual(f.Type, got.Type), "column %s: expected %s, got %s", f.Name, f.Type, got.Type)
+		require.Equal(t, f.Nullable, got.Nullable)
+	}
+
+	_, err = arrowInstance.QuerySchema(context.Background(), "SELECT missing FROM events")
+	require.Error(t, err)
+}
+
+func TestQuerySchemaSQLite(t *testing.T) {
+	db := setupSQLite(t)
+	defer db.Close()
+
+	schema, err := NewArrow(db).QuerySchema(context.Background(), "SELECT id, label FROM readings")
+	require.NoError(t, err)
+	require.Equal(t, 2, schema.NumFields())
+	require.Equal(t, arrow.PrimitiveTypes.Int64, schema.Field(0).Type)
+	require.Equal(t, arrow.BinaryTypes.String, schema.Field(1).Type)
+}
+
+func TestQueryArrowEmptyResults(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+	record, err := arrowInstance.QueryArrow(context.Background(), "SELECT 1 AS a, 'x' AS b, [1, 2] AS c WHERE false")
+	require.NoError(t, err)
+	defer record.Release()
+
+	require.Equal(t, int64(0), record.NumRows())
+	require.Equal(t, int64(3), record.NumCols())
+	require.Equal(t, arrow.ListOf(arrow.PrimitiveTypes.Int32), record.Schema().Field(2).Type)
+
+	sqliteDB := setupSQLite(t)
+	defer sqliteDB.Close()
+
+	// statements without a result set produce zero columns
+	record, err = NewArrow(sqliteDB).QueryArrow(context.Background(), "CREATE TABLE empty (a INTEGER)")
+	require.NoError(t, err)
+	defer record.Release()
+
+	require.Equal(t, int64(0), record.NumCols())
+	require.Equal(t, int64(0), record.NumRows())
+}
@@ -0,0 +1,860 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"cmp"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/decimal128"
+	"github.com/apache/arrow/go/v17/arrow/decimal256"
+	"github.com/marcboeker/go-duckdb"
+)
+
+// hugeIntPrecision is the number of decimal digits needed to hold any HUGEINT value.
+const hugeIntPrecision = 39
+
+// arrowType returns the Arrow type used to hold values of the given DuckDB column type.
+func arrowType(typeName string) (arrow.DataType, error) {
+	if typeName == "" {
+		return nil, fmt.Errorf("unsupported column type: type not reported by driver")
+	}
+	if dt, ok := scalarType(typeName); ok {
+		return dt, nil
+	}
+	return parseType(typeName)
+}
+
+// scalarType maps the name of a non-parameterized DuckDB type to its Arrow type.
+func scalarType(typeName string) (arrow.DataType, bool) {
+	switch typeName {
+	case "BOOLEAN":
+		return arrow.FixedWidthTypes.Boolean, true
+	case "TINYINT":
+		return arrow.PrimitiveTypes.Int8, true
+	case "SMALLINT":
+		return arrow.PrimitiveTypes.Int16, true
+	case "INTEGER":
+		return arrow.PrimitiveTypes.Int32, true
+	case "BIGINT":
+		return arrow.PrimitiveTypes.Int64, true
+	case "HUGEINT":
+		return &arrow.Decimal256Type{Precision: hugeIntPrecision, Scale: 0}, true
+	case "UTINYINT":
+		return arrow.PrimitiveTypes.Uint8, true
+	case "USMALLINT":
+		return arrow.PrimitiveTypes.Uint16, true
+	case "UINTEGER":
+		return arrow.PrimitiveTypes.Uint32, true
+	case "UBIGINT":
+		return arrow.PrimitiveTypes.Uint64, true
+	case "FLOAT":
+		return arrow.PrimitiveTypes.Float32, true
+	case "DOUBLE":
+		return arrow.PrimitiveTypes.Float64, true
+	case "VARCHAR":
+		return arrow.BinaryTypes.String, true
+	case "BLOB":
+		return arrow.BinaryTypes.Binary, true
+	case "UUID":
+		return &arrow.FixedSizeBinaryType{ByteWidth: 16}, true
+	case "DATE":
+		return arrow.FixedWidthTypes.Date32, true
+	case "TIME":
+		return arrow.FixedWidthTypes.Time64us, true
+	case "TIMESTAMP":
+		return &arrow.TimestampType{Unit: arrow.Microsecond}, true
+	case "TIMESTAMP_S":
+		return &arrow.TimestampType{Unit: arrow.Second}, true
+	case "TIMESTAMP_MS":
+		return &arrow.TimestampType{Unit: arrow.Millisecond}, true
+	case "TIMESTAMP_NS":
+		return &arrow.TimestampType{Unit: arrow.Nanosecond}, true
+	case "TIMESTAMPTZ":
+		return &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}, true
+	case "INTERVAL":
+		return arrow.FixedWidthTypes.MonthDayNanoInterval, true
+	}
+	return nil, false
+}
+
+// typeParser parses the composite type names reported by DuckDB, such as
+// DECIMAL(18,3), INTEGER[], STRUCT("a" INTEGER, "b" VARCHAR[]) and
+// MAP(VARCHAR, DOUBLE), into Arrow types.
+type typeParser struct {
+	s   string
+	pos int
+}
+
+func parseType(typeName string) (arrow.DataType, error) {
+	p := &typeParser{s: typeName}
+	dt, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos != len(p.s) {
+		return nil, fmt.Errorf("unsupported column type: %s", typeName)
+	}
+	return dt, nil
+}
+
+func (p *typeParser) parseType() (arrow.DataType, error) {
+	p.skipSpace()
+	name := p.parseName()
+	if name == "" {
+		return nil, fmt.Errorf("unsupported column type: %s", p.s)
+	}
+
+	var dt arrow.DataType
+	var err error
+	switch name {
+	case "DECIMAL", "NUMERIC":
+		dt, err = p.parseDecimal()
+	case "STRUCT":
+		var fields []arrow.Field
+		if fields, err = p.parseFields(); err == nil {
+			dt = arrow.StructOf(fields...)
+		}
+	case "UNION":
+		var fields []arrow.Field
+		if fields, err = p.parseFields(); err == nil {
+			codes := make([]arrow.UnionTypeCode, len(fields))
+			for i := range codes {
+				codes[i] = arrow.UnionTypeCode(i)
+			}
+			dt = arrow.DenseUnionOf(fields, codes)
+		}
+	case "MAP":
+		dt, err = p.parseMap()
+	case "ENUM":
+		// Enum members are only known once values are read, so the
+		// dictionary is built up as rows are appended.
+		p.skipArgs()
+		dt = &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}
+	default:
+		var ok bool
+		if dt, ok = scalarType(name); !ok {
+			err = fmt.Errorf("unsupported column type: %s", name)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for p.skipSpace(); p.peek() == '['; p.skipSpace() {
+		p.pos++
+		start := p.pos
+		for p.pos < len(p.s) && p.s[p.pos] != ']' {
+			p.pos++
+		}
+		if p.pos == len(p.s) {
+			return nil, fmt.Errorf("unterminated array suffix in %s", p.s)
+		}
+		size := strings.TrimSpace(p.s[start:p.pos])
+		p.pos++
+
+		if size == "" {
+			dt = arrow.ListOf(dt)
+			continue
+		}
+		n, err := strconv.ParseInt(size, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid array size in %s: %w", p.s, err)
+		}
+		dt = arrow.FixedSizeListOf(int32(n), dt)
+	}
+
+	return dt, nil
+}
+
+// parseName reads a possibly multi-word type name such as
+// "TIMESTAMP WITH TIME ZONE" up to the next delimiter.
+func (p *typeParser) parseName() string {
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune("(),[", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return strings.ToUpper(strings.TrimSpace(p.s[start:p.pos]))
+}
+
+func (p *typeParser) parseDecimal() (arrow.DataType, error) {
+	p.skipSpace()
+	if p.peek() != '(' {
+		// DuckDB's default decimal width and scale
+		return &arrow.Decimal128Type{Precision: 18, Scale: 3}, nil
+	}
+
+	start := p.pos
+	p.skipArgs()
+	args := strings.Trim(p.s[start:p.pos], "()")
+	width, scale, ok := strings.Cut(args, ",")
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal type: %s", p.s)
+	}
+
+	w, err := strconv.ParseInt(strings.TrimSpace(width), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid decimal width in %s: %w", p.s, err)
+	}
+	s, err := strconv.ParseInt(strings.TrimSpace(scale), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid decimal scale in %s: %w", p.s, err)
+	}
+
+	if w > 38 {
+		return &arrow.Decimal256Type{Precision: int32(w), Scale: int32(s)}, nil
+	}
+	return &arrow.Decimal128Type{Precision: int32(w), Scale: int32(s)}, nil
+}
+
+func (p *typeParser) parseMap() (arrow.DataType, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	key, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(','); err != nil {
+		return nil, err
+	}
+	item, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(')'); err != nil {
+		return nil, err
+	}
+	return arrow.MapOf(key, item), nil
+}
+
+// parseFields reads the member list of a STRUCT or UNION type.
+func (p *typeParser) parseFields() ([]arrow.Field, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+
+	var fields []arrow.Field
+	for {
+		name, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		dt, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, arrow.Field{Name: name, Type: dt, Nullable: true})
+
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return fields, nil
+		default:
+			return nil, fmt.Errorf("malformed member list in %s", p.s)
+		}
+	}
+}
+
+// parseIdent reads a member name, which DuckDB double-quotes when needed.
+func (p *typeParser) parseIdent() (string, error) {
+	p.skipSpace()
+	if p.peek() != '"' {
+		start := p.pos
+		for p.pos < len(p.s) && p.s[p.pos] != ' ' {
+			p.pos++
+		}
+		return p.s[start:p.pos], nil
+	}
+
+	var sb strings.Builder
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		if p.s[p.pos] == '"' {
+			if p.pos+1 < len(p.s) && p.s[p.pos+1] == '"' {
+				sb.WriteByte('"')
+				p.pos++
+				continue
+			}
+			p.pos++
+			return sb.String(), nil
+		}
+		sb.WriteByte(p.s[p.pos])
+	}
+	return "", fmt.Errorf("unterminated identifier in %s", p.s)
+}
+
+// skipArgs skips a parenthesized argument list, including quoted strings.
+func (p *typeParser) skipArgs() {
+	p.skipSpace()
+	if p.peek() != '(' {
+		return
+	}
+
+	depth := 0
+	var quote byte
+	for ; p.pos < len(p.s); p.pos++ {
+		c := p.s[p.pos]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				p.pos++
+				return
+			}
+		}
+	}
+}
+
+func (p *typeParser) expect(c byte) error {
+	p.skipSpace()
+	if p.peek() != c {
+		return fmt.Errorf("expected %q at offset %d in %s", c, p.pos, p.s)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *typeParser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *typeParser) skipSpace() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// appendValue appends a value scanned by the DuckDB driver to b, converting it
+// to b's Arrow type. A nil value is appended as null.
+func appendValue(b array.Builder, v any) error {
+	if v == nil {
+		b.AppendNull()
+		return nil
+	}
+
+	var ok bool
+	switch b := b.(type) {
+	case *array.BooleanBuilder:
+		var x bool
+		if x, ok = v.(bool); ok {
+			b.Append(x)
+		}
+	case *array.Int8Builder:
+		var x int8
+		if x, ok = v.(int8); ok {
+			b.Append(x)
+		}
+	case *array.Int16Builder:
+		var x int16
+		if x, ok = v.(int16); ok {
+			b.Append(x)
+		}
+	case *array.Int32Builder:
+		var x int32
+		if x, ok = v.(int32); ok {
+			b.Append(x)
+		}
+	case *array.Int64Builder:
+		var x int64
+		if x, ok = v.(int64); ok {
+			b.Append(x)
+		}
+	case *array.Uint8Builder:
+		var x uint8
+		if x, ok = v.(uint8); ok {
+			b.Append(x)
+		}
+	case *array.Uint16Builder:
+		var x uint16
+		if x, ok = v.(uint16); ok {
+			b.Append(x)
+		}
+	case *array.Uint32Builder:
+		var x uint32
+		if x, ok = v.(uint32); ok {
+			b.Append(x)
+		}
+	case *array.Uint64Builder:
+		var x uint64
+		if x, ok = v.(uint64); ok {
+			b.Append(x)
+		}
+	case *array.Float32Builder:
+		var x float32
+		if x, ok = v.(float32); ok {
+			b.Append(x)
+		}
+	case *array.Float64Builder:
+		var x float64
+		if x, ok = v.(float64); ok {
+			b.Append(x)
+		}
+	case *array.StringBuilder:
+		var x string
+		if x, ok = v.(string); ok {
+			b.Append(x)
+		}
+	case *array.BinaryBuilder:
+		var x []byte
+		if x, ok = v.([]byte); ok {
+			b.Append(x)
+		}
+	case *array.FixedSizeBinaryBuilder:
+		var x []byte
+		if x, ok = v.([]byte); ok {
+			b.Append(x)
+		}
+	case *array.Date32Builder:
+		var x time.Time
+		if x, ok = v.(time.Time); ok {
+			b.Append(arrow.Date32FromTime(x))
+		}
+	case *array.Time64Builder:
+		var x time.Time
+		if x, ok = v.(time.Time); ok {
+			unit := b.Type().(*arrow.Time64Type).Unit
+			b.Append(arrow.Time64(timeOfDay(x) / unit.Multiplier()))
+		}
+	case *array.TimestampBuilder:
+		var x time.Time
+		if x, ok = v.(time.Time); ok {
+			ts, err := arrow.TimestampFromTime(x, b.Type().(*arrow.TimestampType).Unit)
+			if err != nil {
+				return err
+			}
+			b.Append(ts)
+		}
+	case *array.MonthDayNanoIntervalBuilder:
+		var x duckdb.Interval
+		if x, ok = v.(duckdb.Interval); ok {
+			b.Append(arrow.MonthDayNanoInterval{
+				Months:      x.Months,
+				Days:        x.Days,
+				Nanoseconds: x.Micros * int64(time.Microsecond),
+			})
+		}
+	case *array.Decimal128Builder:
+		var x *big.Int
+		if x, ok = decimalValue(v); ok {
+			b.Append(decimal128.FromBigInt(x))
+		}
+	case *array.Decimal256Builder:
+		var x *big.Int
+		if x, ok = decimalValue(v); ok {
+			b.Append(decimal256.FromBigInt(x))
+		}
+	case *array.BinaryDictionaryBuilder:
+		var x string
+		if x, ok = v.(string); ok {
+			if err := b.AppendString(x); err != nil {
+				return err
+			}
+		}
+	case *array.ListBuilder:
+		var x []any
+		if x, ok = v.([]any); ok {
+			b.Append(true)
+			for _, elem := range x {
+				if err := appendValue(b.ValueBuilder(), elem); err != nil {
+					return err
+				}
+			}
+		}
+	case *array.FixedSizeListBuilder:
+		var x []any
+		if x, ok = v.([]any); ok {
+			n := int(b.Type().(*arrow.FixedSizeListType).Len())
+			if len(x) != n {
+				return fmt.Errorf("expected %d elements for %s column, got %d", n, b.Type(), len(x))
+			}
+			b.Append(true)
+			for _, elem := range x {
+				if err := appendValue(b.ValueBuilder(), elem); err != nil {
+					return err
+				}
+			}
+		}
+	case *array.StructBuilder:
+		var x map[string]any
+		if x, ok = v.(map[string]any); ok {
+			b.Append(true)
+			st := b.Type().(*arrow.StructType)
+			for i, f := range st.Fields() {
+				if err := appendValue(b.FieldBuilder(i), x[f.Name]); err != nil {
+					return fmt.Errorf("field %s: %w", f.Name, err)
+				}
+			}
+		}
+	case *array.MapBuilder:
+		var x duckdb.Map
+		if x, ok = v.(duckdb.Map); ok {
+			b.Append(true)
+			for _, key := range sortedKeys(x) {
+				if err := appendValue(b.KeyBuilder(), key); err != nil {
+					return err
+				}
+				if err := appendValue(b.ItemBuilder(), x[key]); err != nil {
+					return err
+				}
+			}
+		}
+	case *array.DenseUnionBuilder:
+		var x map[string]any
+		if x, ok = v.(map[string]any); ok {
+			return appendUnion(b, x)
+		}
+	default:
+		return fmt.Errorf("unsupported arrow type: %s", b.Type())
+	}
+
+	if !ok {
+		return appendConverted(b, v)
+	}
+	return nil
+}
+
+// decimalValue returns the unscaled integer behind a DECIMAL or HUGEINT value.
+func decimalValue(v any) (*big.Int, bool) {
+	switch x := v.(type) {
+	case duckdb.Decimal:
+		return x.Value, true
+	case *big.Int:
+		return x, true
+	}
+	return nil, false
+}
+
+// appendUnion appends a union value, given as a map holding exactly one
+// non-null member keyed by its tag, the way DuckDB stores unions internally.
+func appendUnion(b *array.DenseUnionBuilder, v map[string]any) error {
+	ut := b.Type().(*arrow.DenseUnionType)
+	for i, f := range ut.Fields() {
+		member, ok := v[f.Name]
+		if !ok || member == nil {
+			continue
+		}
+		b.Append(ut.TypeCodes()[i])
+		return appendValue(b.Child(i), member)
+	}
+	b.AppendNull()
+	return nil
+}
+
+// sortedKeys returns the keys of a DuckDB map in a deterministic order, since
+// the driver hands maps back as unordered Go maps.
+func sortedKeys(m duckdb.Map) []any {
+	keys := make([]any, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return compareKeys(keys[i], keys[j]) < 0
+	})
+	return keys
+}
+
+func compareKeys(a, b any) int {
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y)
+		}
+	case int8:
+		if y, ok := b.(int8); ok {
+			return cmp.Compare(x, y)
+		}
+	case int16:
+		if y, ok := b.(int16); ok {
+			return cmp.Compare(x, y)
+		}
+	case int32:
+		if y, ok := b.(int32); ok {
+			return cmp.Compare(x, y)
+		}
+	case int64:
+		if y, ok := b.(int64); ok {
+			return cmp.Compare(x, y)
+		}
+	case uint8:
+		if y, ok := b.(uint8); ok {
+			return cmp.Compare(x, y)
+		}
+	case uint16:
+		if y, ok := b.(uint16); ok {
+			return cmp.Compare(x, y)
+		}
+	case uint32:
+		if y, ok := b.(uint32); ok {
+			return cmp.Compare(x, y)
+		}
+	case uint64:
+		if y, ok := b.(uint64); ok {
+			return cmp.Compare(x, y)
+		}
+	case float32:
+		if y, ok := b.(float32); ok {
+			return cmp.Compare(x, y)
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			return cmp.Compare(x, y)
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// duckdbType returns the DuckDB column type used to store values of the given
+// Arrow type. It is the inverse of arrowType for the types QueryArrow builds.
+func duckdbType(dt arrow.DataType) (string, error) {
+	switch dt := dt.(type) {
+	case *arrow.BooleanType:
+		return "BOOLEAN", nil
+	case *arrow.Int8Type:
+		return "TINYINT", nil
+	case *arrow.Int16Type:
+		return "SMALLINT", nil
+	case *arrow.Int32Type:
+		return "INTEGER", nil
+	case *arrow.Int64Type:
+		return "BIGINT", nil
+	case *arrow.Uint8Type:
+		return "UTINYINT", nil
+	case *arrow.Uint16Type:
+		return "USMALLINT", nil
+	case *arrow.Uint32Type:
+		return "UINTEGER", nil
+	case *arrow.Uint64Type:
+		return "UBIGINT", nil
+	case *arrow.Float16Type, *arrow.Float32Type:
+		return "FLOAT", nil
+	case *arrow.Float64Type:
+		return "DOUBLE", nil
+	case *arrow.StringType, *arrow.LargeStringType:
+		return "VARCHAR", nil
+	case *arrow.BinaryType, *arrow.LargeBinaryType, *arrow.FixedSizeBinaryType:
+		return "BLOB", nil
+	case *arrow.Date32Type, *arrow.Date64Type:
+		return "DATE", nil
+	case *arrow.Time32Type, *arrow.Time64Type:
+		return "TIME", nil
+	case *arrow.TimestampType:
+		if dt.TimeZone != "" {
+			return "TIMESTAMPTZ", nil
+		}
+		switch dt.Unit {
+		case arrow.Second:
+			return "TIMESTAMP_S", nil
+		case arrow.Millisecond:
+			return "TIMESTAMP_MS", nil
+		case arrow.Nanosecond:
+			return "TIMESTAMP_NS", nil
+		}
+		return "TIMESTAMP", nil
+	case *arrow.MonthDayNanoIntervalType, *arrow.DayTimeIntervalType, *arrow.MonthIntervalType:
+		return "INTERVAL", nil
+	case *arrow.Decimal128Type:
+		return fmt.Sprintf("DECIMAL(%d,%d)", dt.Precision, dt.Scale), nil
+	case *arrow.Decimal256Type:
+		if dt.Precision == hugeIntPrecision && dt.Scale == 0 {
+			return "HUGEINT", nil
+		}
+		if dt.Precision <= 38 {
+			return fmt.Sprintf("DECIMAL(%d,%d)", dt.Precision, dt.Scale), nil
+		}
+	case *arrow.DictionaryType:
+		return duckdbType(dt.ValueType)
+	case *arrow.MapType:
+		key, err := duckdbType(dt.KeyType())
+		if err != nil {
+			return "", err
+		}
+		item, err := duckdbType(dt.ItemType())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("MAP(%s, %s)", key, item), nil
+	case arrow.ListLikeType:
+		elem, err := duckdbType(dt.Elem())
+		if err != nil {
+			return "", err
+		}
+		return elem + "[]", nil
+	case *arrow.StructType:
+		members := make([]string, dt.NumFields())
+		for i, f := range dt.Fields() {
+			ft, err := duckdbType(f.Type)
+			if err != nil {
+				return "", err
+			}
+			members[i] = QuoteIdent(f.Name) + " " + ft
+		}
+		return "STRUCT(" + strings.Join(members, ", ") + ")", nil
+	}
+
+	return "", fmt.Errorf("unsupported arrow type: %s", dt)
+}
+
+// goValue returns the i-th value of arr in the form the DuckDB driver accepts
+// when appending rows, or nil if the value is null.
+func goValue(arr arrow.Array, i int) (any, error) {
+	if arr.IsNull(i) {
+		return nil, nil
+	}
+
+	switch arr := arr.(type) {
+	case *array.Boolean:
+		return arr.Value(i), nil
+	case *array.Int8:
+		return arr.Value(i), nil
+	case *array.Int16:
+		return arr.Value(i), nil
+	case *array.Int32:
+		return arr.Value(i), nil
+	case *array.Int64:
+		return arr.Value(i), nil
+	case *array.Uint8:
+		return arr.Value(i), nil
+	case *array.Uint16:
+		return arr.Value(i), nil
+	case *array.Uint32:
+		return arr.Value(i), nil
+	case *array.Uint64:
+		return arr.Value(i), nil
+	case *array.Float16:
+		return arr.Value(i).Float32(), nil
+	case *array.Float32:
+		return arr.Value(i), nil
+	case *array.Float64:
+		return arr.Value(i), nil
+	case *array.String:
+		return arr.Value(i), nil
+	case *array.LargeString:
+		return arr.Value(i), nil
+	case *array.Binary:
+		return arr.Value(i), nil
+	case *array.LargeBinary:
+		return arr.Value(i), nil
+	case *array.FixedSizeBinary:
+		return arr.Value(i), nil
+	case *array.Date32:
+		return arr.Value(i).ToTime(), nil
+	case *array.Date64:
+		return arr.Value(i).ToTime(), nil
+	case *array.Time32:
+		return arr.Value(i).ToTime(arr.DataType().(*arrow.Time32Type).Unit), nil
+	case *array.Time64:
+		return arr.Value(i).ToTime(arr.DataType().(*arrow.Time64Type).Unit), nil
+	case *array.Timestamp:
+		return arr.Value(i).ToTime(arr.DataType().(*arrow.TimestampType).Unit), nil
+	case *array.MonthDayNanoInterval:
+		v := arr.Value(i)
+		return duckdb.Interval{Months: v.Months, Days: v.Days, Micros: v.Nanoseconds / int64(time.Microsecond)}, nil
+	case *array.DayTimeInterval:
+		v := arr.Value(i)
+		return duckdb.Interval{Days: v.Days, Micros: int64(v.Milliseconds) * 1000}, nil
+	case *array.MonthInterval:
+		return duckdb.Interval{Months: int32(arr.Value(i))}, nil
+	case *array.Decimal128:
+		dt := arr.DataType().(*arrow.Decimal128Type)
+		return duckdb.Decimal{Width: uint8(dt.Precision), Scale: uint8(dt.Scale), Value: arr.Value(i).BigInt()}, nil
+	case *array.Decimal256:
+		dt := arr.DataType().(*arrow.Decimal256Type)
+		if dt.Precision == hugeIntPrecision && dt.Scale == 0 {
+			return arr.Value(i).BigInt(), nil
+		}
+		return duckdb.Decimal{Width: uint8(dt.Precision), Scale: uint8(dt.Scale), Value: arr.Value(i).BigInt()}, nil
+	case *array.Dictionary:
+		return goValue(arr.Dictionary(), arr.GetValueIndex(i))
+	case *array.Map:
+		start, end := arr.ValueOffsets(i)
+		m := make(duckdb.Map, end-start)
+		for j := int(start); j < int(end); j++ {
+			key, err := goValue(arr.Keys(), j)
+			if err != nil {
+				return nil, err
+			}
+			item, err := goValue(arr.Items(), j)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = item
+		}
+		return m, nil
+	case array.ListLike:
+		start, end := arr.ValueOffsets(i)
+		values := make([]any, 0, end-start)
+		for j := int(start); j < int(end); j++ {
+			v, err := goValue(arr.ListValues(), j)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case *array.Struct:
+		st := arr.DataType().(*arrow.StructType)
+		m := make(map[string]any, arr.NumField())
+		for j := 0; j < arr.NumField(); j++ {
+			v, err := goValue(arr.Field(j), i)
+			if err != nil {
+				return nil, err
+			}
+			m[st.Field(j).Name] = v
+		}
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("unsupported arrow type: %s", arr.DataType())
+}
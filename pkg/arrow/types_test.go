@@ -0,0 +1,327 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	_ "github.com/marcboeker/go-duckdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryArrowScalarTypes(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+
+	query := `
+		SELECT
+			true::BOOLEAN AS b,
+			-8::TINYINT AS i8,
+			-16::SMALLINT AS i16,
+			-32::INTEGER AS i32,
+			-64::BIGINT AS i64,
+			170141183460469231731687303715884105727::HUGEINT AS i128,
+			8::UTINYINT AS u8,
+			16::USMALLINT AS u16,
+			32::UINTEGER AS u32,
+			18446744073709551615::UBIGINT AS u64,
+			1.5::FLOAT AS f32,
+			2.25::DOUBLE AS f64,
+			123.45::DECIMAL(5,2) AS dec,
+			'2024-05-06'::DATE AS d,
+			'12:34:56.789'::TIME AS tm,
+			'2024-05-06 12:34:56.789'::TIMESTAMP AS ts,
+			'2024-05-06 12:34:56'::TIMESTAMP_S AS ts_s,
+			'2024-05-06 12:34:56.789'::TIMESTAMP_MS AS ts_ms,
+			'2024-05-06 12:34:56.789'::TIMESTAMP_NS AS ts_ns,
+			'2024-05-06 12:34:56.789+00'::TIMESTAMPTZ AS tstz,
+			INTERVAL '1 month 2 days 3 seconds' AS iv,
+			'\xAA\xBB'::BLOB AS bl,
+			'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11'::UUID AS id,
+			'hello' AS s
+	`
+
+	record, err := arrowInstance.QueryArrow(context.Background(), query)
+	require.NoError(t, err)
+	defer record.Release()
+
+	require.Equal(t, int64(1), record.NumRows())
+
+	expectedTypes := []arrow.DataType{
+		arrow.FixedWidthTypes.Boolean,
+		arrow.PrimitiveTypes.Int8,
+		arrow.PrimitiveTypes.Int16,
+		arrow.PrimitiveTypes.Int32,
+		arrow.PrimitiveTypes.Int64,
+		&arrow.Decimal256Type{Precision: 39, Scale: 0},
+		arrow.PrimitiveTypes.Uint8,
+		arrow.PrimitiveTypes.Uint16,
+		arrow.PrimitiveTypes.Uint32,
+		arrow.PrimitiveTypes.Uint64,
+		arrow.PrimitiveTypes.Float32,
+		arrow.PrimitiveTypes.Float64,
+		&arrow.Decimal128Type{Precision: 5, Scale: 2},
+		arrow.FixedWidthTypes.Date32,
+		arrow.FixedWidthTypes.Time64us,
+		&arrow.TimestampType{Unit: arrow.Microsecond},
+		&arrow.TimestampType{Unit: arrow.Second},
+		&arrow.TimestampType{Unit: arrow.Millisecond},
+		&arrow.TimestampType{Unit: arrow.Nanosecond},
+		&arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"},
+		arrow.FixedWidthTypes.MonthDayNanoInterval,
+		arrow.BinaryTypes.Binary,
+		&arrow.FixedSizeBinaryType{ByteWidth: 16},
+		arrow.BinaryTypes.String,
+	}
+	require.Equal(t, int64(len(expectedTypes)), record.NumCols())
+	for i, dt := range expectedTypes {
+		require.Truef(t, arrow.TypeEqual(dt, record.Column(i).DataType()),
+			"column %s: expected %s, got %s", record.ColumnName(i), dt, record.Column(i).DataType())
+	}
+
+	require.True(t, record.Column(0).(*array.Boolean).Value(0))
+	require.Equal(t, int8(-8), record.Column(1).(*array.Int8).Value(0))
+	require.Equal(t, int16(-16), record.Column(2).(*array.Int16).Value(0))
+	require.Equal(t, int32(-32), record.Column(3).(*array.Int32).Value(0))
+	require.Equal(t, int64(-64), record.Column(4).(*array.Int64).Value(0))
+	require.Equal(t, "170141183460469231731687303715884105727", record.Column(5).(*array.Decimal256).Value(0).BigInt().String())
+	require.Equal(t, uint8(8), record.Column(6).(*array.Uint8).Value(0))
+	require.Equal(t, uint16(16), record.Column(7).(*array.Uint16).Value(0))
+	require.Equal(t, uint32(32), record.Column(8).(*array.Uint32).Value(0))
+	require.Equal(t, uint64(18446744073709551615), record.Column(9).(*array.Uint64).Value(0))
+	require.Equal(t, float32(1.5), record.Column(10).(*array.Float32).Value(0))
+	require.Equal(t, 2.25, record.Column(11).(*array.Float64).Value(0))
+	require.Equal(t, "123.45", record.Column(12).(*array.Decimal128).ValueStr(0))
+
+	ts := time.Date(2024, 5, 6, 12, 34, 56, 789000000, time.UTC)
+	require.Equal(t, "2024-05-06", record.Column(13).(*array.Date32).Value(0).FormattedString())
+	require.Equal(t, "12:34:56.789000", record.Column(14).(*array.Time64).Value(0).FormattedString(arrow.Microsecond))
+	require.Equal(t, ts, record.Column(15).(*array.Timestamp).Value(0).ToTime(arrow.Microsecond))
+	require.Equal(t, ts.Truncate(time.Second), record.Column(16).(*array.Timestamp).Value(0).ToTime(arrow.Second))
+	require.Equal(t, ts, record.Column(17).(*array.Timestamp).Value(0).ToTime(arrow.Millisecond))
+	require.Equal(t, ts, record.Column(18).(*array.Timestamp).Value(0).ToTime(arrow.Nanosecond))
+	require.Equal(t, ts, record.Column(19).(*array.Timestamp).Value(0).ToTime(arrow.Microsecond))
+	require.Equal(t, arrow.MonthDayNanoInterval{Months: 1, Days: 2, Nanoseconds: 3 * int64(time.Second)},
+		record.Column(20).(*array.MonthDayNanoInterval).Value(0))
+	require.Equal(t, []byte{0xAA, 0xBB}, record.Column(21).(*array.Binary).Value(0))
+	require.Equal(t, []byte{0xa0, 0xee, 0xbc, 0x99, 0x9c, 0x0b, 0x4e, 0xf8, 0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11},
+		record.Column(22).(*array.FixedSizeBinary).Value(0))
+	require.Equal(t, "hello", record.Column(23).(*array.String).Value(0))
+}
+
+func TestQueryArrowScalarNulls(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+
+	query := `
+		SELECT * FROM (VALUES
+			(1::BIGINT, 1::UTINYINT, 1.0::DECIMAL(18,3), '2024-01-01'::DATE, '2024-01-01'::TIMESTAMPTZ, 'x'::BLOB),
+			(NULL, NULL, NULL, NULL, NULL, NULL)
+		) t(a, b, c, d, e, f)
+	`
+
+	record, err := arrowInstance.QueryArrow(context.Background(), query)
+	require.NoError(t, err)
+	defer record.Release()
+
+	require.Equal(t, int64(2), record.NumRows())
+	for i := 0; i < int(record.NumCols()); i++ {
+		col := record.Column(i)
+		require.Truef(t, col.IsValid(0), "column %s row 0 should be valid", record.ColumnName(i))
+		require.Truef(t, col.IsNull(1), "column %s row 1 should be null", record.ColumnName(i))
+		require.Equal(t, 1, col.NullN())
+	}
+}
+
+func TestQueryArrowParquetBigint(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+
+	record, err := arrowInstance.QueryArrow(context.Background(), "SELECT * FROM read_parquet('../../data/nation.parquet')")
+	require.NoError(t, err)
+	defer record.Release()
+
+	require.Equal(t, int64(25), record.NumRows())
+}
+
+func TestQueryArrowNestedTypes(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TYPE mood AS ENUM ('sad', 'ok', 'happy');
+		CREATE TABLE nested (
+			l INTEGER[],
+			s STRUCT(a INTEGER, b VARCHAR[]),
+			m MAP(VARCHAR, DECIMAL(2,1)),
+			e mood,
+			deep STRUCT(n INTEGER)[][]
+		);
+		INSERT INTO nested VALUES
+			([1, 2, NULL], {'a': 1, 'b': ['x', 'y']}, MAP {'k1': 1.5, 'k2': 2.5}, 'happy', [[{'n': 1}], []]),
+			(NULL, NULL, NULL, NULL, NULL),
+			([], {'a': NULL, 'b': NULL}, MAP {}, 'sad', [NULL]);
+	`)
+	require.NoError(t, err)
+
+	arrowInstance := NewArrow(db)
+
+	query := "SELECT l, s, m, e, deep FROM nested"
+
+	record, err := arrowInstance.QueryArrow(context.Background(), query)
+	require.NoError(t, err)
+	defer record.Release()
+
+	require.Equal(t, int64(3), record.NumRows())
+
+	expectedTypes := []arrow.DataType{
+		arrow.ListOf(arrow.PrimitiveTypes.Int32),
+		arrow.StructOf(
+			arrow.Field{Name: "a", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+			arrow.Field{Name: "b", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+		),
+		arrow.MapOf(arrow.BinaryTypes.String, &arrow.Decimal128Type{Precision: 2, Scale: 1}),
+		&arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String},
+		arrow.ListOf(arrow.ListOf(arrow.StructOf(arrow.Field{Name: "n", Type: arrow.PrimitiveTypes.Int32, Nullable: true}))),
+	}
+	for i, dt := range expectedTypes {
+		require.Truef(t, arrow.TypeEqual(dt, record.Column(i).DataType()),
+			"column %s: expected %s, got %s", record.ColumnName(i), dt, record.Column(i).DataType())
+	}
+
+	list := record.Column(0).(*array.List)
+	require.Equal(t, `[[1 2 (null)] (null) []]`, list.String())
+
+	st := record.Column(1).(*array.Struct)
+	require.True(t, st.IsNull(1))
+	require.Equal(t, int32(1), st.Field(0).(*array.Int32).Value(0))
+	require.Equal(t, `["x","y"]`, st.Field(1).(*array.List).ValueStr(0))
+	require.True(t, st.Field(0).IsNull(2))
+
+	m := record.Column(2).(*array.Map)
+	require.True(t, m.IsNull(1))
+	keys := m.Keys().(*array.String)
+	require.Equal(t, 2, keys.Len())
+	require.Equal(t, "k1", keys.Value(0))
+	require.Equal(t, "k2", keys.Value(1))
+	require.Equal(t, "2.5", m.Items().(*array.Decimal128).ValueStr(1))
+
+	enum := record.Column(3).(*array.Dictionary)
+	dict := enum.Dictionary().(*array.String)
+	require.Equal(t, "happy", dict.Value(enum.GetValueIndex(0)))
+	require.True(t, enum.IsNull(1))
+	require.Equal(t, "sad", dict.Value(enum.GetValueIndex(2)))
+
+	deep := record.Column(4).(*array.List)
+	require.Equal(t, `[[{"n":1}],[]]`, deep.ValueStr(0))
+	require.True(t, deep.IsNull(1))
+
+	structs, err := arrowInstance.QueryArrow(context.Background(),
+		`SELECT s FROM (VALUES ({'a': 1, 'b': 'x'}), (NULL), (NULL), ({'a': 4, 'b': 'w'})) t(s)`)
+	require.NoError(t, err)
+	defer structs.Release()
+
+	st = structs.Column(0).(*array.Struct)
+	require.Equal(t, 4, st.Len())
+	for i := 0; i < st.NumField(); i++ {
+		require.Equal(t, st.Len(), st.Field(i).Len())
+	}
+	require.True(t, st.IsNull(1))
+	require.True(t, st.IsNull(2))
+	require.Equal(t, int32(4), st.Field(0).(*array.Int32).Value(3))
+	require.Equal(t, "w", st.Field(1).(*array.String).Value(3))
+}
+
+func TestArrowTypeParsing(t *testing.T) {
+	tests := []struct {
+		typeName string
+		expected arrow.DataType
+	}{
+		{"DECIMAL(38,10)", &arrow.Decimal128Type{Precision: 38, Scale: 10}},
+		{"INTEGER[3]", arrow.FixedSizeListOf(3, arrow.PrimitiveTypes.Int32)},
+		{"MAP(VARCHAR, INTEGER[])", arrow.MapOf(arrow.BinaryTypes.String, arrow.ListOf(arrow.PrimitiveTypes.Int32))},
+		{`STRUCT("weird ""name""" VARCHAR, plain BIGINT)`, arrow.StructOf(
+			arrow.Field{Name: `weird "name"`, Type: arrow.BinaryTypes.String, Nullable: true},
+			arrow.Field{Name: "plain", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		)},
+		{"UNION(num INTEGER, str VARCHAR)", arrow.DenseUnionOf([]arrow.Field{
+			{Name: "num", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+			{Name: "str", Type: arrow.BinaryTypes.String, Nullable: true},
+		}, []arrow.UnionTypeCode{0, 1})},
+		{"ENUM('a', 'b(c)')", &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}},
+	}
+
+	for _, tt := range tests {
+		dt, err := arrowType(tt.typeName)
+		require.NoError(t, err, tt.typeName)
+		require.Truef(t, arrow.TypeEqual(tt.expected, dt), "%s: expected %s, got %s", tt.typeName, tt.expected, dt)
+	}
+
+	for _, bad := range []string{"", "NOT_A_TYPE", "STRUCT(a INTEGER", "MAP(VARCHAR)"} {
+		_, err := arrowType(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestAppendUnionValues(t *testing.T) {
+	dt, err := arrowType("UNION(num INTEGER, str VARCHAR)")
+	require.NoError(t, err)
+
+	b := array.NewBuilder(memory.NewGoAllocator(), dt)
+	defer b.Release()
+
+	require.NoError(t, appendValue(b, map[string]any{"num": int32(7), "str": nil}))
+	require.NoError(t, appendValue(b, map[string]any{"num": nil, "str": "seven"}))
+	require.NoError(t, appendValue(b, nil))
+
+	arr := b.NewArray().(*array.DenseUnion)
+	defer arr.Release()
+
+	require.Equal(t, 3, arr.Len())
+	require.Equal(t, int32(7), arr.Field(0).(*array.Int32).Value(int(arr.ValueOffset(0))))
+	require.Equal(t, "seven", arr.Field(1).(*array.String).Value(int(arr.ValueOffset(1))))
+	require.True(t, arr.Field(0).IsNull(int(arr.ValueOffset(2))))
+}
@@ -0,0 +1,187 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/marcboeker/go-duckdb"
+)
+
+// RegisterView exposes the records of rdr as a DuckDB relation called name,
+// so Arrow data produced in Go can be queried and joined with file-based
+// sources through QueryArrow or join.JoinDataSourcesWithDB. The records are
+// appended into a table owned by the database, which consumes rdr but does
+// not release it. Call UnregisterView to drop the relation and free its memory.
+func (a *Arrow) RegisterView(ctx context.Context, name string, rdr array.RecordReader) error {
+	return a.RegisterViewIn(ctx, "", name, rdr)
+}
+
+// RegisterViewIn is like RegisterView, but creates the relation in the given
+// DuckDB schema, which must already exist. An empty schema means the default
+// one. Call UnregisterViewIn to drop the relation.
+func (a *Arrow) RegisterViewIn(ctx context.Context, schemaName, name string, rdr array.RecordReader) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	table := qualifiedName(schemaName, name)
+	if _, ok := a.views[table]; ok {
+		return fmt.Errorf("view %s is already registered", table)
+	}
+
+	schema := rdr.Schema()
+	columns := make([]string, schema.NumFields())
+	for i, f := range schema.Fields() {
+		dt, err := duckdbType(f.Type)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", f.Name, err)
+		}
+		columns[i] = QuoteIdent(f.Name) + " " + dt
+	}
+
+	ddl := fmt.Sprintf("CREATE TABLE %s (%s)", table, strings.Join(columns, ", "))
+	if _, err := a.db.ExecContext(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to create view table: %w", err)
+	}
+
+	err := a.commentColumns(ctx, table, schema)
+	if err == nil {
+		err = a.appendRecords(ctx, schemaName, name, rdr)
+	}
+	if err != nil {
+		if _, dropErr := a.db.ExecContext(ctx, "DROP TABLE "+table); dropErr != nil {
+			return fmt.Errorf("%w (cleanup failed: %v)", err, dropErr)
+		}
+		return err
+	}
+
+	a.views[table] = struct{}{}
+	return nil
+}
+
+// UnregisterView drops a relation created by RegisterView.
+func (a *Arrow) UnregisterView(ctx context.Context, name string) error {
+	return a.UnregisterViewIn(ctx, "", name)
+}
+
+// UnregisterViewIn drops a relation created by RegisterViewIn.
+func (a *Arrow) UnregisterViewIn(ctx context.Context, schemaName, name string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	table := qualifiedName(schemaName, name)
+	if _, ok := a.views[table]; !ok {
+		return fmt.Errorf("view %s is not registered", table)
+	}
+
+	if _, err := a.db.ExecContext(ctx, "DROP TABLE "+table); err != nil {
+		return fmt.Errorf("failed to drop view table: %w", err)
+	}
+
+	delete(a.views, table)
+	return nil
+}
+
+// qualifiedName quotes name, prefixed by its schema when one is given.
+func qualifiedName(schemaName, name string) string {
+	if schemaName == "" {
+		return QuoteIdent(name)
+	}
+	return QuoteIdent(schemaName) + "." + QuoteIdent(name)
+}
+
+// commentColumns keeps the column comments carried in the field metadata of
+// query results.
+func (a *Arrow) commentColumns(ctx context.Context, table string, schema *arrow.Schema) error {
+	for _, f := range schema.Fields() {
+		comment, ok := f.Metadata.GetValue(MetadataComment)
+		if !ok {
+			continue
+		}
+		stmt := fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s", table, QuoteIdent(f.Name), QuoteString(comment))
+		if _, err := a.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to comment column %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+func (a *Arrow) appendRecords(ctx context.Context, schemaName, name string, rdr array.RecordReader) error {
+	conn, err := a.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		appender, err := duckdb.NewAppenderFromConn(driverConn.(driver.Conn), schemaName, name)
+		if err != nil {
+			return fmt.Errorf("failed to create appender: %w", err)
+		}
+
+		if err := appendRows(ctx, appender, rdr); err != nil {
+			appender.Close()
+			return err
+		}
+		if err := appender.Close(); err != nil {
+			return fmt.Errorf("failed to flush appender: %w", err)
+		}
+		return nil
+	})
+}
+
+func appendRows(ctx context.Context, appender *duckdb.Appender, rdr array.RecordReader) error {
+	for rdr.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rec := rdr.Record()
+		row := make([]driver.Value, rec.NumCols())
+		for i := 0; i < int(rec.NumRows()); i++ {
+			for j, col := range rec.Columns() {
+				v, err := goValue(col, i)
+				if err != nil {
+					return fmt.Errorf("column %s: %w", rec.ColumnName(j), err)
+				}
+				row[j] = v
+			}
+			if err := appender.AppendRow(row...); err != nil {
+				return fmt.Errorf("failed to append row: %w", err)
+			}
+		}
+	}
+	return rdr.Err()
+}
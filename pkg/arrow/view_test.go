@@ -0,0 +1,164 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package arrow
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	_ "github.com/marcboeker/go-duckdb"
+	"github.com/stretchr/testify/require"
+)
+
+func newNationKeysReader(t *testing.T) array.RecordReader {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "n_nationkey", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "score", Type: &arrow.Decimal128Type{Precision: 9, Scale: 2}, Nullable: true},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+		{Name: "info", Type: arrow.StructOf(arrow.Field{Name: "ok", Type: arrow.FixedWidthTypes.Boolean, Nullable: true}), Nullable: true},
+		{Name: "seen", Type: &arrow.TimestampType{Unit: arrow.Millisecond}, Nullable: true},
+	}, nil)
+
+	rec, _, err := array.RecordFromJSON(memory.NewGoAllocator(), schema, strings.NewReader(`[
+		{"n_nationkey": 0, "score": "1.50", "tags": ["a", "b"], "info": {"ok": true}, "seen": "2024-01-02T03:04:05.006"},
+		{"n_nationkey": 1, "score": null, "tags": [], "info": null, "seen": null},
+		{"n_nationkey": 2, "score": "-2.25", "tags": null, "info": {"ok": false}, "seen": "2024-01-02T00:00:00"}
+	]`))
+	require.NoError(t, err)
+	defer rec.Release()
+
+	rdr, err := array.NewRecordReader(schema, []arrow.Record{rec})
+	require.NoError(t, err)
+	return rdr
+}
+
+func TestRegisterView(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	arrowInstance := NewArrow(db)
+	ctx := context.Background()
+
+	rdr := newNationKeysReader(t)
+	defer rdr.Release()
+	require.NoError(t, arrowInstance.RegisterView(ctx, "scores", rdr))
+
+	record, err := arrowInstance.QueryArrow(ctx, `
+		SELECT n.n_name, s.score, s.tags, s.info.ok AS ok, s.seen
+		FROM read_parquet('../../data/nation.parquet') n
+		JOIN scores s USING (n_nationkey)
+		ORDER BY n.n_nationkey
+	`)
+	require.NoError(t, err)
+	defer record.Release()
+
+	require.Equal(t, int64(3), record.NumRows())
+	require.Equal(t, "ALGERIA", record.Column(0).(*array.String).Value(0))
+	require.Equal(t, "1.5", record.Column(1).(*array.Decimal128).ValueStr(0))
+	require.True(t, record.Column(1).IsNull(1))
+	require.Equal(t, "-2.25", record.Column(1).(*array.Decimal128).ValueStr(2))
+	require.Equal(t, `["a","b"]`, record.Column(2).(*array.List).ValueStr(0))
+	require.True(t, record.Column(2).IsNull(2))
+	require.True(t, record.Column(3).(*array.Boolean).Value(0))
+	require.True(t, record.Column(3).IsNull(1))
+	require.Equal(t, "2024-01-02 03:04:05.006Z", record.Column(4).(*array.Timestamp).ValueStr(0))
+
+	require.Error(t, arrowInstance.RegisterView(ctx, "scores", newNationKeysReader(t)))
+
+	require.NoError(t, arrowInstance.UnregisterView(ctx, "scores"))
+	_, err = arrowInstance.QueryArrow(ctx, "SELECT * FROM scores")
+	require.Error(t, err)
+	require.Error(t, arrowInstance.UnregisterView(ctx, "scores"))
+}
+
+func TestRegisterViewIn(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, err = db.Exec("CREATE SCHEMA lake")
+	require.NoError(t, err)
+
+	arrowInstance := NewArrow(db)
+	rdr := newNationKeysReader(t)
+	defer rdr.Release()
+	require.NoError(t, arrowInstance.RegisterViewIn(ctx, "lake", "scores", rdr))
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM lake.scores").Scan(&count))
+	require.Equal(t, 3, count)
+
+	// The same name in the default schema is a different relation.
+	other := newNationKeysReader(t)
+	defer other.Release()
+	require.NoError(t, arrowInstance.RegisterView(ctx, "scores", other))
+	require.Error(t, arrowInstance.RegisterViewIn(ctx, "lake", "scores", newNationKeysReader(t)))
+
+	require.NoError(t, arrowInstance.UnregisterViewIn(ctx, "lake", "scores"))
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM scores").Scan(&count))
+	require.Equal(t, 3, count)
+	require.Error(t, db.QueryRow("SELECT count(*) FROM lake.scores").Scan(&count))
+}
+
+func TestRegisterViewUnsupportedType(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "d", Type: arrow.FixedWidthTypes.Duration_s}}, nil)
+	rdr, err := array.NewRecordReader(schema, nil)
+	require.NoError(t, err)
+	defer rdr.Release()
+
+	err = NewArrow(db).RegisterView(context.Background(), "durations", rdr)
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "unsupported arrow type"))
+}
+
+func TestDuckDBTypeRoundTrip(t *testing.T) {
+	for _, typeName := range []string{
+		"BOOLEAN", "TINYINT", "HUGEINT", "UBIGINT", "DOUBLE", "VARCHAR", "BLOB", "DATE", "TIME",
+		"TIMESTAMP", "TIMESTAMP_S", "TIMESTAMP_MS", "TIMESTAMP_NS", "TIMESTAMPTZ", "INTERVAL",
+		"DECIMAL(18,3)", "INTEGER[]", `STRUCT("a" INTEGER, "b" VARCHAR[])`, "MAP(VARCHAR, DOUBLE)",
+	} {
+		dt, err := arrowType(typeName)
+		require.NoError(t, err, typeName)
+		got, err := duckdbType(dt)
+		require.NoError(t, err, typeName)
+		require.Equal(t, typeName, got)
+	}
+}
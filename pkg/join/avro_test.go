@@ -0,0 +1,101 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/TFMV/arrowlake/internal/fixture"
+	_ "github.com/marcboeker/go-duckdb"
+)
+
+const customersAvroSchema = `{
+	"type": "record", "name": "Customer",
+	"fields": [
+		{"name": "c_custkey", "type": "long"},
+		{"name": "n_nationkey", "type": "long"},
+		{"name": "segment", "type": {"type": "enum", "name": "Segment", "symbols": ["RETAIL", "WHOLESALE"]}},
+		{"name": "email", "type": ["null", "string"]}
+	]
+}`
+
+func TestJoinAvroSources(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatalf("failed to connect to DuckDB: %v", err)
+	}
+	defer db.Close()
+
+	dir := t.TempDir()
+	for i, segment := range []string{"RETAIL", "WHOLESALE"} {
+		var rows []map[string]any
+		for j := 0; j < 30; j++ {
+			key := int64(i*30 + j)
+			var email any
+			if j%3 == 0 {
+				email = map[string]any{"string": "c@example.com"}
+			}
+			rows = append(rows, map[string]any{"c_custkey": key, "n_nationkey": key % 25, "segment": segment, "email": email})
+		}
+		writeFixture(t, filepath.Join(dir, segment+".avro"), func(w io.Writer) error {
+			return fixture.WriteAvro(w, customersAvroSchema, rows)
+		})
+	}
+
+	config := &Config{
+		Sources: []DataSource{
+			{Type: "parquet", TableName: "nation", FilePath: "../../data/nation.parquet"},
+			{Type: "avro", TableName: "customers", FilePath: filepath.Join(dir, "*.avro")},
+		},
+		Query: QueryConfig{
+			SQL: "SELECT count(*) FROM customers JOIN nation USING (n_nationkey)",
+		},
+	}
+
+	ctx := context.Background()
+	if err := joinWithDB(ctx, db, config); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+
+	var total, wholesale, emails int
+	err = db.QueryRow(`
+		SELECT count(*), count(*) FILTER (WHERE segment = 'WHOLESALE'), count(email)
+		FROM customers JOIN nation USING (n_nationkey)`).Scan(&total, &wholesale, &emails)
+	if err != nil {
+		t.Fatalf("failed to query customers: %v", err)
+	}
+	if total != 60 || wholesale != 30 || emails != 20 {
+		t.Fatalf("expected 60 rows, 30 wholesale and 20 emails, got %d, %d and %d", total, wholesale, emails)
+	}
+}
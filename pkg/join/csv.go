@@ -0,0 +1,178 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
+)
+
+// rejectsSeq numbers the tables DuckDB stores rejected CSV rows in.
+var rejectsSeq int64
+
+// registerCSV loads the CSV files matched by source.FilePath into a table
+// named source.TableName with DuckDB's CSV reader. Rows that cannot be
+// parsed are collected by DuckDB and then handled by the source's OnError
+// policy.
+func registerCSV(ctx context.Context, db *sql.DB, source DataSource) (err error) {
+	if !recursiveGlob(source.FilePath) {
+		if _, err := globFiles(source.FilePath); err != nil {
+			return err
+		}
+	}
+	params, err := csvParams(source.Options)
+	if err != nil {
+		return err
+	}
+	policy, err := newRejectPolicy(source.Options)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := policy.close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	// The rejects tables are temporary, so they are only visible on the
+	// connection that ran the scan.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	n := atomic.AddInt64(&rejectsSeq, 1)
+	errorsTable := fmt.Sprintf("arrowlake_reject_errors_%d", n)
+	scansTable := fmt.Sprintf("arrowlake_reject_scans_%d", n)
+	params = append(params, "store_rejects = true",
+		"rejects_table = "+lakearrow.QuoteString(errorsTable), "rejects_scan = "+lakearrow.QuoteString(scansTable))
+
+	query := fmt.Sprintf("CREATE TABLE %s AS SELECT * FROM read_csv(%s, %s)",
+		lakearrow.QuoteIdent(source.TableName), lakearrow.QuoteString(source.FilePath), strings.Join(params, ", "))
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to read CSV: %w", err)
+	}
+	defer conn.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s; DROP TABLE IF EXISTS %s", lakearrow.QuoteIdent(errorsTable), lakearrow.QuoteIdent(scansTable)))
+
+	rejects, err := csvRejects(ctx, conn, errorsTable, scansTable)
+	if err != nil {
+		return err
+	}
+	for i, e := range rejects {
+		if err := policy.handle(e); err != nil {
+			if _, dropErr := conn.ExecContext(ctx, "DROP TABLE "+lakearrow.QuoteIdent(source.TableName)); dropErr != nil {
+				return fmt.Errorf("%w (cleanup failed: %v)", err, dropErr)
+			}
+			if more := len(rejects) - i - 1; more > 0 {
+				return fmt.Errorf("%w (and %d more rejected rows)", err, more)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// csvParams returns the read_csv parameters for opts.
+func csvParams(opts FileOptions) ([]string, error) {
+	var params []string
+	if opts.Delimiter != "" {
+		params = append(params, "delim = "+lakearrow.QuoteString(opts.Delimiter))
+	}
+	if opts.Quote != "" {
+		params = append(params, "quote = "+lakearrow.QuoteString(opts.Quote))
+	}
+	if opts.Header != nil {
+		params = append(params, fmt.Sprintf("header = %t", *opts.Header))
+	}
+	if len(opts.NullStrings) > 0 {
+		nulls := make([]string, len(opts.NullStrings))
+		for i, s := range opts.NullStrings {
+			nulls[i] = lakearrow.QuoteString(s)
+		}
+		params = append(params, "nullstr = ["+strings.Join(nulls, ", ")+"]")
+	}
+	if opts.DateFormat != "" {
+		params = append(params, "dateformat = "+lakearrow.QuoteString(opts.DateFormat))
+	}
+	if opts.TimestampFormat != "" {
+		params = append(params, "timestampformat = "+lakearrow.QuoteString(opts.TimestampFormat))
+	}
+	if opts.Compression != "" {
+		params = append(params, "compression = "+lakearrow.QuoteString(opts.Compression))
+	}
+	if len(opts.Columns) > 0 {
+		columns := make([]string, len(opts.Columns))
+		for i, col := range opts.Columns {
+			if err := checkColumnType(col); err != nil {
+				return nil, err
+			}
+			columns[i] = lakearrow.QuoteString(col.Name) + ": " + lakearrow.QuoteString(col.Type)
+		}
+		params = append(params, "columns = {"+strings.Join(columns, ", ")+"}")
+	}
+	return params, nil
+}
+
+// csvRejects returns the first error of each row DuckDB rejected.
+func csvRejects(ctx context.Context, conn *sql.Conn, errorsTable, scansTable string) ([]*lakearrow.ParseError, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`
+		SELECT s.file_path, e.line, e.column_idx, coalesce(e.column_name, ''), e.error_message, e.csv_line
+		FROM %s e JOIN %s s USING (scan_id, file_id)
+		ORDER BY s.file_path, e.line, e.column_idx`, lakearrow.QuoteIdent(errorsTable), lakearrow.QuoteIdent(scansTable)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rejected rows: %w", err)
+	}
+	defer rows.Close()
+
+	var rejects []*lakearrow.ParseError
+	for rows.Next() {
+		e := &lakearrow.ParseError{}
+		var column int64
+		if err := rows.Scan(&e.File, &e.Line, &column, &e.ColumnName, &e.Message, &e.Row); err != nil {
+			return nil, fmt.Errorf("failed to scan rejected row: %w", err)
+		}
+		if last := len(rejects) - 1; last >= 0 && rejects[last].File == e.File && rejects[last].Line == e.Line {
+			continue
+		}
+		e.Column = int(column)
+		e.Row = strings.TrimRight(e.Row, "\r\n")
+		rejects = append(rejects, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rejected rows: %w", err)
+	}
+	return rejects, nil
+}
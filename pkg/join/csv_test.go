@@ -0,0 +1,158 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/marcboeker/go-duckdb"
+)
+
+func TestCSVSourceOptions(t *testing.T) {
+	db := openTestDB(t)
+	dir := t.TempDir()
+
+	noHeader := false
+	writeGzipFile(t, filepath.Join(dir, "orders.csv.gz"), "1;|a;b|;02/01/2024;-\n2;plain;03/01/2024;7.5\n")
+
+	config := &Config{
+		Sources: []DataSource{{
+			Type: "csv", TableName: "orders", FilePath: filepath.Join(dir, "orders.csv.gz"),
+			Options: FileOptions{
+				Delimiter: ";", Quote: "|", Header: &noHeader, NullStrings: []string{"-"},
+				DateFormat: "%d/%m/%Y", Compression: "gzip",
+				Columns: []ColumnType{
+					{Name: "id", Type: "INTEGER"}, {Name: "label", Type: "VARCHAR"},
+					{Name: "day", Type: "DATE"}, {Name: "amount", Type: "DECIMAL(10, 2)"},
+				},
+			},
+		}},
+		Query: QueryConfig{SQL: "SELECT count(*) FROM orders"},
+	}
+	if err := joinWithDB(context.Background(), db, config); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+
+	var label, day, amountType string
+	var nullAmount bool
+	err := db.QueryRow(`SELECT label, day::VARCHAR, amount IS NULL, typeof(amount) FROM orders WHERE id = 1`).Scan(&label, &day, &nullAmount, &amountType)
+	if err != nil {
+		t.Fatalf("failed to query orders: %v", err)
+	}
+	if label != "a;b" || day != "2024-01-02" || !nullAmount || amountType != "DECIMAL(10,2)" {
+		t.Fatalf("unexpected row: %q %q %v %q", label, day, nullAmount, amountType)
+	}
+}
+
+func TestCSVSourceInference(t *testing.T) {
+	db := openTestDB(t)
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.csv"), "id,name,n_nationkey\n1,x,3\n2,y,4\n")
+	writeTestFile(t, filepath.Join(dir, "b.csv"), "id,name,n_nationkey\n3,z,5\n")
+
+	config := &Config{
+		Sources: []DataSource{
+			{Type: "parquet", TableName: "nation", FilePath: "../../data/nation.parquet"},
+			{Type: "csv", TableName: "people", FilePath: filepath.Join(dir, "*.csv")},
+		},
+		Query: QueryConfig{SQL: "SELECT count(*) FROM people JOIN nation USING (n_nationkey)"},
+	}
+	if err := joinWithDB(context.Background(), db, config); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+
+	var count int
+	var idType string
+	if err := db.QueryRow(`SELECT count(*), any_value(typeof(id)) FROM people JOIN nation USING (n_nationkey)`).Scan(&count, &idType); err != nil {
+		t.Fatalf("failed to query people: %v", err)
+	}
+	if count != 3 || idType != "BIGINT" {
+		t.Fatalf("expected 3 joined rows with BIGINT ids, got %d and %s", count, idType)
+	}
+}
+
+const badCSV = "id,amount\n1,2.5\n2,abc\n3,4\n4,5,extra\n"
+
+func csvPolicyConfig(path string, opts FileOptions) *Config {
+	opts.Columns = []ColumnType{{Name: "id", Type: "INTEGER"}, {Name: "amount", Type: "DOUBLE"}}
+	return &Config{
+		Sources: []DataSource{{Type: "csv", TableName: "amounts", FilePath: path, Options: opts}},
+		Query:   QueryConfig{SQL: "SELECT count(*) FROM amounts"},
+	}
+}
+
+func TestCSVSourceErrorPolicies(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "amounts.csv")
+	writeTestFile(t, path, badCSV)
+	ctx := context.Background()
+
+	db := openTestDB(t)
+	err := joinWithDB(ctx, db, csvPolicyConfig(path, FileOptions{}))
+	want := path + `:3:2: column amount: Error when converting column "amount". Could not convert string "abc" to 'DOUBLE' (and 1 more rejected rows)`
+	if err == nil || !strings.HasSuffix(err.Error(), want) {
+		t.Fatalf("expected error ending in %q, got %v", want, err)
+	}
+	if _, err := db.Exec("SELECT * FROM amounts"); err == nil {
+		t.Fatalf("expected the table of a failed source to be dropped")
+	}
+
+	if err := joinWithDB(ctx, db, csvPolicyConfig(path, FileOptions{OnError: OnErrorSkip})); err != nil {
+		t.Fatalf("failed to skip bad rows: %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM amounts").Scan(&count); err != nil || count != 2 {
+		t.Fatalf("expected 2 rows, got %d (%v)", count, err)
+	}
+
+	db = openTestDB(t)
+	rejectFile := filepath.Join(dir, "rejects.csv")
+	if err := joinWithDB(ctx, db, csvPolicyConfig(path, FileOptions{OnError: OnErrorReject, RejectFile: rejectFile})); err != nil {
+		t.Fatalf("failed to reject bad rows: %v", err)
+	}
+	rejects := readRejects(t, rejectFile)
+	if len(rejects) != 3 {
+		t.Fatalf("expected a header and 2 rejected rows, got %q", rejects)
+	}
+	if got := strings.Join(rejects[1][:4], ","); got != path+",3,2,amount" || rejects[1][5] != "2,abc" {
+		t.Fatalf("unexpected reject %q", rejects[1])
+	}
+	if got := strings.Join(rejects[2][:3], ","); got != path+",5,3" || rejects[2][5] != "4,5,extra" {
+		t.Fatalf("unexpected reject %q", rejects[2])
+	}
+
+	err = joinWithDB(ctx, openTestDB(t), csvPolicyConfig(path, FileOptions{OnError: OnErrorReject}))
+	if err == nil || !strings.Contains(err.Error(), "requires a reject_file") {
+		t.Fatalf("expected a missing reject file error, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"sync/atomic"
+
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
+)
+
+// attachSeq numbers the catalogs that filtered duckdb sources are attached
+// under while their tables are copied.
+var attachSeq int64
+
+// attachDuckDB attaches the DuckDB database file at source.FilePath under the
+// alias source.TableName, so its tables are queried as alias.schema.table.
+// The file is attached read-only unless source.ReadWrite is set.
+//
+// When Schemas, Tables or ExcludeTables restrict what is visible, the file is
+// attached read-only under an internal name, the selected tables and views
+// are copied into an in-memory catalog named alias, and the file is detached
+// again, so that excluded tables cannot be queried at all. DuckDB binds the
+// tables a view refers to against the default catalog, so views of the file
+// that do not name their catalog cannot be selected and must be excluded.
+func attachDuckDB(ctx context.Context, db *sql.DB, source DataSource) error {
+	if _, err := os.Stat(source.FilePath); err != nil {
+		return err
+	}
+
+	filtered := len(source.Schemas) > 0 || len(source.Tables) > 0 || len(source.ExcludeTables) > 0
+	if !filtered {
+		return attach(ctx, db, source.FilePath, source.TableName, !source.ReadWrite)
+	}
+	if source.ReadWrite {
+		return fmt.Errorf("schemas and tables cannot be restricted on a read-write source")
+	}
+
+	catalog := fmt.Sprintf("arrowlake_attached_%d", atomic.AddInt64(&attachSeq, 1))
+	if err := attach(ctx, db, source.FilePath, catalog, true); err != nil {
+		return err
+	}
+	tables, err := duckdbTables(ctx, db, catalog, source)
+	if err == nil {
+		err = copyTables(ctx, db, catalog, source.TableName, tables)
+	}
+	if _, detachErr := db.ExecContext(ctx, "DETACH "+lakearrow.QuoteIdent(catalog)); detachErr != nil {
+		if err != nil {
+			return fmt.Errorf("%w (cleanup failed: %v)", err, detachErr)
+		}
+		return fmt.Errorf("failed to detach %s: %w", source.FilePath, detachErr)
+	}
+	return err
+}
+
+func attach(ctx context.Context, db *sql.DB, path, alias string, readOnly bool) error {
+	stmt := fmt.Sprintf("ATTACH %s AS %s", lakearrow.QuoteString(path), lakearrow.QuoteIdent(alias))
+	if readOnly {
+		stmt += " (READ_ONLY)"
+	}
+	if _, err := db.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to attach %s: %w", path, err)
+	}
+	return nil
+}
+
+// duckdbTable is a table or view of an attached database.
+type duckdbTable struct {
+	schema, name string
+}
+
+// duckdbTables lists the tables and views of catalog that are in one of the
+// source's schemas and selected by its table patterns, which match names
+// qualified as schema.table.
+func duckdbTables(ctx context.Context, db *sql.DB, catalog string, source DataSource) ([]duckdbTable, error) {
+	rows, err := db.QueryContext(ctx, `SELECT table_schema, table_name FROM information_schema.tables
+		WHERE table_catalog = ? ORDER BY table_schema, table_name`, catalog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var schemas []string
+	tables := make(map[string]duckdbTable)
+	for rows.Next() {
+		var t duckdbTable
+		if err := rows.Scan(&t.schema, &t.name); err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+		schemas = append(schemas, t.schema)
+		tables[t.schema+"."+t.name] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	visible, err := matchSchemas(schemas, source.Schemas)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name, t := range tables {
+		if visible[t.schema] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	names, err = matchTables(names, source.Tables, source.ExcludeTables)
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]duckdbTable, len(names))
+	for i, name := range names {
+		selected[i] = tables[name]
+	}
+	return selected, nil
+}
+
+// copyTables creates the in-memory catalog alias with a copy of each of
+// tables in catalog.
+func copyTables(ctx context.Context, db *sql.DB, catalog, alias string, tables []duckdbTable) error {
+	if _, err := db.ExecContext(ctx, "ATTACH ':memory:' AS "+lakearrow.QuoteIdent(alias)); err != nil {
+		return fmt.Errorf("failed to create catalog %s: %w", alias, err)
+	}
+
+	for _, t := range tables {
+		if err := copyTable(ctx, db, catalog, alias, t); err != nil {
+			if _, detachErr := db.ExecContext(ctx, "DETACH "+lakearrow.QuoteIdent(alias)); detachErr != nil {
+				return fmt.Errorf("%w (cleanup failed: %v)", err, detachErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func copyTable(ctx context.Context, db *sql.DB, catalog, alias string, t duckdbTable) error {
+	schema := lakearrow.QuoteIdent(alias) + "." + lakearrow.QuoteIdent(t.schema)
+	if _, err := db.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+schema); err != nil {
+		return fmt.Errorf("failed to create schema %s: %w", t.schema, err)
+	}
+	stmt := fmt.Sprintf("CREATE TABLE %s.%s AS SELECT * FROM %s.%s.%s", schema, lakearrow.QuoteIdent(t.name),
+		lakearrow.QuoteIdent(catalog), lakearrow.QuoteIdent(t.schema), lakearrow.QuoteIdent(t.name))
+	if _, err := db.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to expose %s.%s, which may need to be excluded: %w", t.schema, t.name, err)
+	}
+	return nil
+}
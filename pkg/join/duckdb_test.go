@@ -0,0 +1,179 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// duckDBFixture holds the DDL of the DuckDB test database.
+const duckDBFixture = `
+	CREATE SCHEMA sales;
+	CREATE TABLE sales.orders (id INTEGER, n_nationkey INTEGER);
+	INSERT INTO sales.orders VALUES (1, 3), (2, 4), (3, 30);
+	CREATE TABLE sales.refunds (id INTEGER);
+	CREATE VIEW sales.big_orders AS SELECT * FROM sales.orders WHERE id > 1;
+	CREATE SCHEMA hr;
+	CREATE TABLE hr.salaries (id INTEGER, amount INTEGER);
+	CREATE TABLE main.notes (note VARCHAR);
+`
+
+func visibleTables(t *testing.T, db *sql.DB, catalog string) string {
+	t.Helper()
+	var tables sql.NullString
+	err := db.QueryRow(`SELECT string_agg(table_schema || '.' || table_name, ',' ORDER BY table_schema, table_name)
+		FROM information_schema.tables WHERE table_catalog = ?`, catalog).Scan(&tables)
+	if err != nil {
+		t.Fatalf("failed to list tables of %s: %v", catalog, err)
+	}
+	return tables.String
+}
+
+func TestDuckDBSource(t *testing.T) {
+	db := openTestDB(t)
+	path := filepath.Join(t.TempDir(), "curated.duckdb")
+	writeDBFixture(t, "duckdb", path, duckDBFixture)
+
+	config := &Config{
+		Sources: []DataSource{
+			{Type: "parquet", TableName: "nation", FilePath: "../../data/nation.parquet"},
+			{Type: "duckdb", TableName: "curated", FilePath: path},
+		},
+		Query: QueryConfig{SQL: "SELECT count(*) FROM curated.sales.orders JOIN nation USING (n_nationkey)"},
+	}
+	if err := joinWithDB(context.Background(), db, config); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+	if got := visibleTables(t, db, "curated"); got != "hr.salaries,main.notes,sales.big_orders,sales.orders,sales.refunds" {
+		t.Fatalf("unexpected tables: %s", got)
+	}
+	if _, err := db.Exec("INSERT INTO curated.sales.orders VALUES (4, 5)"); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Fatalf("expected a read-only error, got %v", err)
+	}
+}
+
+func TestDuckDBSourceReadWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "curated.duckdb")
+	writeDBFixture(t, "duckdb", path, duckDBFixture)
+
+	db := openTestDB(t)
+	config := &Config{
+		Sources: []DataSource{{Type: "duckdb", TableName: "curated", FilePath: path, ReadWrite: true}},
+		Query:   QueryConfig{SQL: "SELECT count(*) FROM curated.sales.orders"},
+	}
+	if err := joinWithDB(context.Background(), db, config); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO curated.sales.orders VALUES (4, 5)"); err != nil {
+		t.Fatalf("failed to write to attached database: %v", err)
+	}
+	if _, err := db.Exec("DETACH curated"); err != nil {
+		t.Fatalf("failed to detach database: %v", err)
+	}
+
+	var count int
+	if err := openTestDB(t).QueryRow("ATTACH '" + path + "' AS c (READ_ONLY); SELECT count(*) FROM c.sales.orders").Scan(&count); err != nil {
+		t.Fatalf("failed to read back database: %v", err)
+	}
+	if count != 4 {
+		t.Fatalf("expected 4 orders, got %d", count)
+	}
+
+	config.Sources[0].Schemas = []string{"sales"}
+	if err := joinWithDB(context.Background(), openTestDB(t), config); err == nil || !strings.Contains(err.Error(), "read-write") {
+		t.Fatalf("expected a read-write error, got %v", err)
+	}
+}
+
+func TestDuckDBSourceVisibility(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "curated.duckdb")
+	writeDBFixture(t, "duckdb", path, duckDBFixture)
+
+	for _, tc := range []struct {
+		name   string
+		source DataSource
+		want   string
+	}{
+		{"schemas", DataSource{Schemas: []string{"sales", "ma*"}, ExcludeTables: []string{"big_*"}}, "main.notes,sales.orders,sales.refunds"},
+		{"tables", DataSource{Tables: []string{"orders", "hr.*"}}, "hr.salaries,sales.orders"},
+		{"exclude", DataSource{Schemas: []string{"sales"}, ExcludeTables: []string{"big_*", "sales.refunds"}}, "sales.orders"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := openTestDB(t)
+			source := tc.source
+			source.Type, source.TableName, source.FilePath = "duckdb", "curated", path
+			config := &Config{Sources: []DataSource{source}, Query: QueryConfig{SQL: "SELECT 1"}}
+			if err := joinWithDB(context.Background(), db, config); err != nil {
+				t.Fatalf("failed to join data sources: %v", err)
+			}
+			if got := visibleTables(t, db, "curated"); got != tc.want {
+				t.Fatalf("unexpected tables: got %s, want %s", got, tc.want)
+			}
+			// The file itself is detached, so excluded tables are out of reach.
+			var catalogs string
+			if err := db.QueryRow("SELECT string_agg(database_name, ',' ORDER BY database_name) FROM duckdb_databases() WHERE NOT internal").Scan(&catalogs); err != nil {
+				t.Fatalf("failed to list catalogs: %v", err)
+			}
+			if catalogs != "curated,memory" {
+				t.Fatalf("unexpected catalogs: %s", catalogs)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		source DataSource
+		want   string
+	}{
+		{DataSource{Schemas: []string{"finance"}}, `no schemas match "finance"`},
+		{DataSource{Tables: []string{"sales.returns"}}, `no tables match "sales.returns"`},
+		// The view refers to sales.orders, which is not in the default catalog.
+		{DataSource{Schemas: []string{"sales"}}, "sales.big_orders, which may need to be excluded"},
+		{DataSource{FilePath: filepath.Join(t.TempDir(), "missing.duckdb")}, "no such file"},
+	} {
+		source := tc.source
+		source.Type, source.TableName = "duckdb", "curated"
+		if source.FilePath == "" {
+			source.FilePath = path
+		}
+		db := openTestDB(t)
+		err := joinWithDB(context.Background(), db, &Config{Sources: []DataSource{source}, Query: QueryConfig{SQL: "SELECT 1"}})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("expected an error containing %q, got %v", tc.want, err)
+		}
+		var catalogs int
+		if err := db.QueryRow("SELECT count(*) FROM duckdb_databases() WHERE NOT internal AND database_name <> 'memory'").Scan(&catalogs); err != nil || catalogs != 0 {
+			t.Errorf("expected failed sources to be detached, got %d catalogs (%v)", catalogs, err)
+		}
+	}
+}
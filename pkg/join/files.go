@@ -0,0 +1,231 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/klauspost/compress/zstd"
+)
+
+// fileOpener returns a reader over the records of the data file at path.
+type fileOpener func(path string, r io.Reader) (array.RecordReader, error)
+
+// registerFiles loads the files matched by source.FilePath into a relation
+// named source.TableName, decompressing them as compression says. All files
+// must share the same columns. The files are streamed into the relation one
+// record at a time.
+func registerFiles(ctx context.Context, lake *lakearrow.Arrow, source DataSource, compression string, open fileOpener) error {
+	paths, err := globFiles(source.FilePath)
+	if err != nil {
+		return err
+	}
+
+	rdr, err := newFilesReader(paths, compression, open)
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	return lake.RegisterView(ctx, source.TableName, rdr)
+}
+
+// filesReader reads the records of several files in turn, keeping only one
+// of them open.
+type filesReader struct {
+	refs        int64
+	paths       []string
+	compression string
+	open        fileOpener
+	schema      *arrow.Schema
+
+	// next is the index of the next file to open.
+	next int
+	file *os.File
+	body io.ReadCloser
+	cur  array.RecordReader
+	err  error
+}
+
+// newFilesReader opens the first of paths to learn the schema.
+func newFilesReader(paths []string, compression string, open fileOpener) (*filesReader, error) {
+	r := &filesReader{refs: 1, paths: paths, compression: compression, open: open}
+	if err := r.openNext(); err != nil {
+		r.closeFile()
+		return nil, err
+	}
+	r.schema = r.cur.Schema()
+	return r, nil
+}
+
+func (r *filesReader) openNext() error {
+	path := r.paths[r.next]
+	r.next++
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	r.file = f
+
+	if r.body, err = decompress(path, r.compression, f); err != nil {
+		return err
+	}
+	if r.cur, err = r.open(path, r.body); err != nil {
+		return fileError(path, err)
+	}
+	if r.schema != nil && !lakearrow.SameColumns(r.schema, r.cur.Schema()) {
+		return fmt.Errorf("schema of %s does not match %s: %s", path, r.paths[0], r.cur.Schema())
+	}
+	return nil
+}
+
+func (r *filesReader) closeFile() {
+	if r.cur != nil {
+		r.cur.Release()
+		r.cur = nil
+	}
+	if r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+}
+
+func (r *filesReader) Schema() *arrow.Schema { return r.schema }
+
+func (r *filesReader) Next() bool {
+	for r.err == nil {
+		if r.cur.Next() {
+			return true
+		}
+		if err := r.cur.Err(); err != nil {
+			r.err = fileError(r.paths[r.next-1], err)
+			break
+		}
+		if r.next == len(r.paths) {
+			return false
+		}
+		r.closeFile()
+		r.err = r.openNext()
+	}
+	return false
+}
+
+func (r *filesReader) Record() arrow.Record { return r.cur.Record() }
+
+func (r *filesReader) Err() error { return r.err }
+
+func (r *filesReader) Retain() { atomic.AddInt64(&r.refs, 1) }
+
+func (r *filesReader) Release() {
+	if atomic.AddInt64(&r.refs, -1) == 0 {
+		r.closeFile()
+	}
+}
+
+// fileError prefixes err with path unless it is a parse error, which
+// already names the file.
+func fileError(path string, err error) error {
+	var perr *lakearrow.ParseError
+	if errors.As(err, &perr) && perr.File != "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// decompress wraps r to decompress it. Compression is one of auto, the
+// default, which picks a codec from the file extension, none, gzip or zstd.
+func decompress(path, compression string, r io.Reader) (io.ReadCloser, error) {
+	if compression == "" || compression == "auto" {
+		switch {
+		case strings.HasSuffix(path, ".gz"):
+			compression = "gzip"
+		case strings.HasSuffix(path, ".zst"), strings.HasSuffix(path, ".zstd"):
+			compression = "zstd"
+		default:
+			compression = "none"
+		}
+	}
+
+	switch compression {
+	case "none":
+		return io.NopCloser(r), nil
+	case "gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to read gzip data: %w", path, err)
+		}
+		return zr, nil
+	case "zstd":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to read zstd data: %w", path, err)
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unsupported compression: %s", compression)
+}
+
+// recursiveGlob reports whether pattern uses **, which filepath.Glob does
+// not support but DuckDB expands to any number of directories.
+func recursiveGlob(pattern string) bool {
+	return strings.Contains(pattern, "**")
+}
+
+// globFiles expands a file path that may contain glob patterns, and fails
+// when nothing matches.
+func globFiles(pattern string) ([]string, error) {
+	if recursiveGlob(pattern) {
+		return nil, fmt.Errorf("invalid file path %q: ** is only supported by parquet and csv sources", pattern)
+	}
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file path %q: %w", pattern, err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no files match %q", pattern)
+	}
+	return paths, nil
+}
@@ -0,0 +1,127 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"encoding/csv"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	_ "github.com/marcboeker/go-duckdb"
+	_ "modernc.org/sqlite"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatalf("failed to connect to DuckDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// writeFixture creates the file at path with the data write produces.
+func writeFixture(t *testing.T, path string, write func(w io.Writer) error) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", path, err)
+	}
+	defer f.Close()
+	if err := write(f); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func writeTestFile(t *testing.T, path, data string) {
+	t.Helper()
+	writeFixture(t, path, func(w io.Writer) error {
+		_, err := io.WriteString(w, data)
+		return err
+	})
+}
+
+func writeGzipFile(t *testing.T, path, data string) {
+	t.Helper()
+	writeFixture(t, path, func(w io.Writer) error {
+		zw := gzip.NewWriter(w)
+		if _, err := io.WriteString(zw, data); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+}
+
+func writeZstdFile(t *testing.T, path, data string) {
+	t.Helper()
+	writeFixture(t, path, func(w io.Writer) error {
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(zw, data); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+}
+
+// writeDBFixture creates a database file at path with driver and runs ddl
+// on it.
+func writeDBFixture(t *testing.T, driver, path, ddl string) {
+	t.Helper()
+	db, err := sql.Open(driver, path)
+	if err != nil {
+		t.Fatalf("failed to open %s database: %v", driver, err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(ddl); err != nil {
+		t.Fatalf("failed to create %s fixture: %v", driver, err)
+	}
+}
+
+func readRejects(t *testing.T, path string) [][]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open reject file: %v", err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read reject file: %v", err)
+	}
+	return records
+}
@@ -0,0 +1,184 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	_ "github.com/marcboeker/go-duckdb"
+)
+
+// writeIPCFixture exports query to path in the Arrow IPC file or stream
+// format.
+func writeIPCFixture(t *testing.T, lake *lakearrow.Arrow, path string, stream bool, query string) {
+	t.Helper()
+	writeFixture(t, path, func(w io.Writer) error {
+		if stream {
+			return lake.ExportIPCStream(context.Background(), w, lakearrow.IPCOptions{}, query)
+		}
+		return lake.ExportIPCFile(context.Background(), w, lakearrow.IPCOptions{}, query)
+	})
+}
+
+func TestJoinArrowIPCSources(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatalf("failed to connect to DuckDB: %v", err)
+	}
+	defer db.Close()
+
+	lake := lakearrow.NewArrow(db)
+	dir := t.TempDir()
+	writeIPCFixture(t, lake, filepath.Join(dir, "orders-1.arrow"), false,
+		`SELECT i AS order_id, i % 25 AS n_nationkey, 'first' AS batch FROM range(0, 100) t(i)`)
+	writeIPCFixture(t, lake, filepath.Join(dir, "orders-2.arrow"), true,
+		`SELECT i AS order_id, i % 25 AS n_nationkey, 'second' AS batch FROM range(100, 150) t(i)`)
+	writeIPCFixture(t, lake, filepath.Join(dir, "empty.arrow"), false,
+		`SELECT i AS order_id, i AS n_nationkey, 'none' AS batch FROM range(0) t(i)`)
+
+	config := &Config{
+		Sources: []DataSource{
+			{Type: "parquet", TableName: "nation", FilePath: "../../data/nation.parquet"},
+			{Type: "arrow_ipc", TableName: "orders", FilePath: filepath.Join(dir, "orders-*.arrow")},
+			{Type: "arrow_ipc", TableName: "no_orders", FilePath: filepath.Join(dir, "empty.arrow")},
+		},
+		Query: QueryConfig{
+			SQL: "SELECT count(*) FROM orders JOIN nation USING (n_nationkey)",
+		},
+	}
+
+	ctx := context.Background()
+	if err := joinWithDB(ctx, db, config); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+
+	var total, second, empty int
+	err = db.QueryRow(`SELECT count(*), count(*) FILTER (WHERE batch = 'second') FROM orders JOIN nation USING (n_nationkey)`).Scan(&total, &second)
+	if err != nil {
+		t.Fatalf("failed to query orders: %v", err)
+	}
+	if total != 150 || second != 50 {
+		t.Fatalf("expected 150 joined rows with 50 from the stream file, got %d and %d", total, second)
+	}
+
+	if err := db.QueryRow(`SELECT count(*) FROM no_orders`).Scan(&empty); err != nil {
+		t.Fatalf("failed to query empty table: %v", err)
+	}
+	if empty != 0 {
+		t.Fatalf("expected an empty table, got %d rows", empty)
+	}
+}
+
+func TestJoinArrowIPCSourceErrors(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatalf("failed to connect to DuckDB: %v", err)
+	}
+	defer db.Close()
+
+	lake := lakearrow.NewArrow(db)
+	dir := t.TempDir()
+	writeIPCFixture(t, lake, filepath.Join(dir, "a.arrow"), false, `SELECT 1 AS id`)
+	writeIPCFixture(t, lake, filepath.Join(dir, "b.arrow"), true, `SELECT 'one' AS id`)
+	if err := os.WriteFile(filepath.Join(dir, "bad.arrow"), []byte("not arrow"), 0o644); err != nil {
+		t.Fatalf("failed to write bad file: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(dir, "missing-*.arrow"), "no files match"},
+		{filepath.Join(dir, "[ab].arrow"), "does not match"},
+		{filepath.Join(dir, "bad.arrow"), "bad.arrow"},
+	}
+
+	for i, tt := range tests {
+		config := &Config{
+			Sources: []DataSource{{Type: "arrow_ipc", TableName: fmt.Sprintf("t%d", i), FilePath: tt.path}},
+			Query:   QueryConfig{SQL: "SELECT 1"},
+		}
+		err := joinWithDB(context.Background(), db, config)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.path, tt.want, err)
+		}
+	}
+}
+
+func TestFilesReaderStreams(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatalf("failed to connect to DuckDB: %v", err)
+	}
+	defer db.Close()
+
+	lake := lakearrow.NewArrow(db)
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < 3; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("part-%d.arrow", i))
+		writeIPCFixture(t, lake, path, true, fmt.Sprintf(`SELECT i AS id FROM range(%d, %d) t(i)`, i*10, i*10+10))
+		paths = append(paths, path)
+	}
+
+	var opened []string
+	open := func(path string, r io.Reader) (array.RecordReader, error) {
+		opened = append(opened, filepath.Base(path))
+		return lake.OpenIPC(r)
+	}
+	rdr, err := newFilesReader(paths, "none", open)
+	if err != nil {
+		t.Fatalf("failed to open files: %v", err)
+	}
+	defer rdr.Release()
+
+	var rows int64
+	for rdr.Next() {
+		rows += rdr.Record().NumRows()
+		if rows == 10 && len(opened) != 1 {
+			t.Fatalf("expected files to be opened one at a time, got %q", opened)
+		}
+	}
+	if err := rdr.Err(); err != nil {
+		t.Fatalf("failed to read files: %v", err)
+	}
+	if rows != 30 || len(opened) != 3 {
+		t.Fatalf("expected 30 rows from 3 files, got %d from %q", rows, opened)
+	}
+}
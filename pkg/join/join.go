@@ -33,35 +33,93 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strings"
-
+	"io"
 	"os"
+	"sync/atomic"
 
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
 	_ "github.com/marcboeker/go-duckdb"
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 type DataSource struct {
-	Type             string `yaml:"type"`
-	TableName        string `yaml:"table_name"`
-	FilePath         string `yaml:"file_path,omitempty"`
-	ConnectionString string `yaml:"connection_string,omitempty"`
+	Type             string      `yaml:"type"`
+	TableName        string      `yaml:"table_name"`
+	FilePath         string      `yaml:"file_path,omitempty"`
+	ConnectionString string      `yaml:"connection_string,omitempty"`
+	Options          FileOptions `yaml:"options,omitempty"`
+	// Tables and ExcludeTables select the tables of a sqlite or duckdb
+	// source by name, with path.Match patterns such as orders_*. DuckDB
+	// tables may be matched as schema.table. By default every table and
+	// view is visible.
+	Tables        []string `yaml:"tables,omitempty"`
+	ExcludeTables []string `yaml:"exclude_tables,omitempty"`
+	// Schemas selects the schemas of a duckdb source that are visible.
+	Schemas []string `yaml:"schemas,omitempty"`
+	// ReadWrite attaches a duckdb source for writing. It is read-only by
+	// default.
+	ReadWrite bool `yaml:"read_write,omitempty"`
+}
+
+// FileOptions configures how csv and json sources are parsed. Options that
+// are left unset are detected from the data.
+type FileOptions struct {
+	// Delimiter, Quote and Header apply to csv sources only.
+	Delimiter string `yaml:"delimiter,omitempty"`
+	Quote     string `yaml:"quote,omitempty"`
+	Header    *bool  `yaml:"header,omitempty"`
+	// NullStrings are values read as null.
+	NullStrings []string `yaml:"null_strings,omitempty"`
+	// DateFormat and TimestampFormat are strptime-style formats such as
+	// %d/%m/%Y.
+	DateFormat      string `yaml:"date_format,omitempty"`
+	TimestampFormat string `yaml:"timestamp_format,omitempty"`
+	// Compression is auto, none, gzip or zstd. Auto picks a codec from the
+	// file extension.
+	Compression string `yaml:"compression,omitempty"`
+	// Columns lists the columns to read with their DuckDB types. When empty,
+	// the columns and types are inferred.
+	Columns []ColumnType `yaml:"columns,omitempty"`
+	// OnError is the policy for rows that cannot be parsed: fail, the
+	// default, skip, or reject, which skips them and records them in
+	// RejectFile.
+	OnError    string `yaml:"on_error,omitempty"`
+	RejectFile string `yaml:"reject_file,omitempty"`
+}
+
+// ColumnType declares the type of a column of a csv or json source.
+type ColumnType struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
 }
 
 type QueryConfig struct {
 	JoinColumns   []JoinColumn `yaml:"join_columns"`
 	SelectColumns []string     `yaml:"select_columns"`
-	SQL           string       `yaml:"sql"`
+	// Params are named values the SQL template can refer to.
+	Params map[string]any `yaml:"params,omitempty"`
+	// SQL is a template, see the syntax in template.go.
+	SQL string `yaml:"sql,omitempty"`
+	// Join declares the join to generate the SQL from, in place of SQL.
+	Join *JoinSpec `yaml:"join,omitempty"`
 }
 
 type JoinColumn struct {
 	Source string `yaml:"source"`
 	Column string `yaml:"column"`
+	// As renames an output column of a JoinSpec.
+	As string `yaml:"as,omitempty"`
 }
 
 type Config struct {
 	Sources []DataSource `yaml:"sources"`
 	Query   QueryConfig  `yaml:"query"`
+
+	// src locates the settings of a config read by LoadConfig.
+	src *configSource
+	// secrets are the values LoadConfig read from secret files.
+	secrets []string
 }
 
 func LoadConfig(configPath string) (*Config, error) {
@@ -70,26 +128,124 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("unable to read config file: %v", err)
 	}
 
-	var config Config
-	err = yaml.Unmarshal(data, &config)
-	if err != nil {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
 		return nil, fmt.Errorf("unable to unmarshal config file: %v", err)
 	}
 
+	secrets, err := interpolate(configPath, &root)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := root.Decode(&config); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal config file: %v", redact(err.Error(), secrets))
+	}
+	config.src = newConfigSource(configPath, data, &root)
+	config.secrets = secrets
+
 	return &config, nil
 }
 
-func JoinDataSources(ctx context.Context, config *Config) error {
+// recordBatchSize is the number of rows per record of join results and of
+// the sources read through Arrow.
+const recordBatchSize = 2048
+
+// JoinDataSources loads the sources of config into a new in-memory DuckDB
+// database and runs the join query on it. The returned reader streams the
+// result and owns the database, which is closed once the reader is released.
+func JoinDataSources(ctx context.Context, config *Config) (array.RecordReader, error) {
 	db, err := sql.Open("duckdb", "")
 	if err != nil {
-		return fmt.Errorf("failed to open DuckDB: %w", err)
+		return nil, fmt.Errorf("failed to open DuckDB: %w", err)
+	}
+
+	rdr, err := JoinDataSourcesWithDB(ctx, db, config)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &dbRecordReader{RecordReader: rdr, db: db, refs: 1}, nil
+}
+
+// Sink consumes the result of a join, such as by writing it out.
+type Sink interface {
+	Consume(ctx context.Context, rdr array.RecordReader) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, rdr array.RecordReader) error
+
+func (f SinkFunc) Consume(ctx context.Context, rdr array.RecordReader) error {
+	return f(ctx, rdr)
+}
+
+// JoinDataSourcesTo runs the join like JoinDataSources and passes its result
+// to sink.
+func JoinDataSourcesTo(ctx context.Context, config *Config, sink Sink) error {
+	rdr, err := JoinDataSources(ctx, config)
+	if err != nil {
+		return err
 	}
-	defer db.Close()
+	defer rdr.Release()
+
+	if err := sink.Consume(ctx, rdr); err != nil {
+		return fmt.Errorf("failed to consume join result: %w", err)
+	}
+	return nil
+}
+
+// JoinDataSourcesWithDB runs the join against an existing DuckDB database, so
+// the query can also reference relations registered on it beforehand, such as
+// Arrow views. The returned reader streams the result and must be released
+// before db is closed.
+func JoinDataSourcesWithDB(ctx context.Context, db *sql.DB, config *Config) (array.RecordReader, error) {
+	rdr, err := joinDataSources(ctx, db, config)
+	return rdr, config.redactError(err)
+}
+
+func joinDataSources(ctx context.Context, db *sql.DB, config *Config) (array.RecordReader, error) {
+	lake := lakearrow.NewArrow(db, lakearrow.WithQueryRedactor(config.Redact))
+	if err := registerSources(ctx, db, lake, config.Sources); err != nil {
+		return nil, err
+	}
+
+	var query string
+	var err error
+	if config.Query.Join != nil {
+		query, err = joinSQL(config.Sources, *config.Query.Join, func(ref string) ([]joinField, error) {
+			return relationFields(ctx, db, ref)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid join: %w", err)
+		}
+	} else {
+		query, err = renderSQL(config.Sources, config.Query, func(table string) ([]string, error) {
+			return tableColumns(ctx, db, table)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to render query: %w", err)
+		}
+	}
+
+	rdr, err := lake.QueryArrowStream(ctx, recordBatchSize, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute join query: %w", err)
+	}
+	return rdr, nil
+}
+
+func registerSources(ctx context.Context, db *sql.DB, lake *lakearrow.Arrow, sources []DataSource) error {
+	var err error
+	for _, source := range sources {
+		if err = checkSource(source); err != nil {
+			return fmt.Errorf("invalid %s source: %w", source.Type, err)
+		}
 
-	for _, source := range config.Sources {
 		switch source.Type {
 		case "parquet":
-			_, err = db.Exec(fmt.Sprintf(`CREATE TABLE %s AS SELECT * FROM read_parquet('%s')`, source.TableName, source.FilePath))
+			_, err = db.Exec(fmt.Sprintf(`CREATE TABLE %s AS SELECT * FROM read_parquet(%s)`, lakearrow.QuoteIdent(source.TableName), lakearrow.QuoteString(source.FilePath)))
 			if err != nil {
 				return fmt.Errorf("failed to create Parquet table: %w", err)
 			}
@@ -99,42 +255,116 @@ func JoinDataSources(ctx context.Context, config *Config) error {
 				return fmt.Errorf("failed to install and load PostgreSQL extension: %w", err)
 			}
 
-			attachCmd := fmt.Sprintf(`ATTACH '%s' AS %s (TYPE POSTGRES);`, source.ConnectionString, source.TableName)
+			attachCmd := fmt.Sprintf(`ATTACH %s AS %s (TYPE POSTGRES);`, lakearrow.QuoteString(source.ConnectionString), lakearrow.QuoteIdent(source.TableName))
 			_, err = db.Exec(attachCmd)
 			if err != nil {
 				return fmt.Errorf("failed to attach PostgreSQL database: %w", err)
 			}
+		case "arrow_ipc":
+			open := func(_ string, r io.Reader) (array.RecordReader, error) { return lake.OpenIPC(r) }
+			if err = registerFiles(ctx, lake, source, "none", open); err != nil {
+				return fmt.Errorf("failed to register Arrow IPC table: %w", err)
+			}
+		case "avro":
+			open := func(_ string, r io.Reader) (array.RecordReader, error) { return lake.ReadAvro(r) }
+			if err = registerFiles(ctx, lake, source, "none", open); err != nil {
+				return fmt.Errorf("failed to register Avro table: %w", err)
+			}
+		case "csv":
+			if err = registerCSV(ctx, db, source); err != nil {
+				return fmt.Errorf("failed to register CSV table: %w", err)
+			}
+		case "json":
+			if err = registerJSON(ctx, lake, source); err != nil {
+				return fmt.Errorf("failed to register JSON table: %w", err)
+			}
+		case "sqlite":
+			if err = registerSQLite(ctx, db, lake, source); err != nil {
+				return fmt.Errorf("failed to register SQLite database: %w", err)
+			}
+		case "duckdb":
+			if err = attachDuckDB(ctx, db, source); err != nil {
+				return fmt.Errorf("failed to attach DuckDB database: %w", err)
+			}
+		default:
+			return fmt.Errorf("unsupported source type %q for %s", source.Type, source.TableName)
 		}
 	}
 
-	joinColumns := make([]string, len(config.Query.JoinColumns))
-	for i, col := range config.Query.JoinColumns {
-		joinColumns[i] = fmt.Sprintf("%s.%s", col.Source, col.Column)
-	}
+	return nil
+}
 
-	query := config.Query.SQL
-	query = strings.Replace(query, "{select_columns}", strings.Join(config.Query.SelectColumns, ", "), -1)
-	for _, col := range config.Query.JoinColumns {
-		placeholder := fmt.Sprintf("{%s.%s}", col.Source, col.Column)
-		query = strings.Replace(query, placeholder, fmt.Sprintf("%s.%s", col.Source, col.Column), -1)
+// RenderSQL renders the SQL template of the query, or generates the SQL of
+// its join, without loading the sources, so references to their columns are
+// not checked.
+func (c *Config) RenderSQL() (string, error) {
+	if c.Query.Join != nil {
+		query, err := joinSQL(c.Sources, *c.Query.Join, nil)
+		if err != nil {
+			return "", c.redactError(fmt.Errorf("invalid join: %w", err))
+		}
+		return query, nil
 	}
+	query, err := renderSQL(c.Sources, c.Query, nil)
+	return query, c.redactError(err)
+}
 
-	rows, err := db.Query(query)
+// tableColumns lists the columns of a table of the default schema.
+func tableColumns(ctx context.Context, db *sql.DB, table string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT column_name FROM information_schema.columns
+		WHERE table_catalog = current_database() AND table_schema = current_schema() AND table_name = ?
+		ORDER BY ordinal_position`, table)
 	if err != nil {
-		return fmt.Errorf("failed to execute join query: %w", err)
+		return nil, fmt.Errorf("failed to list columns: %w", err)
 	}
 	defer rows.Close()
 
-	// Process the results
-	var count int
-	if rows.Next() {
-		err := rows.Scan(&count)
-		if err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to list columns: %w", err)
 		}
+		columns = append(columns, name)
 	}
+	return columns, rows.Err()
+}
 
-	fmt.Printf("Count of rows: %d\n", count)
+// relationFields lists the columns of a relation with their DuckDB types.
+func relationFields(ctx context.Context, db *sql.DB, ref string) ([]joinField, error) {
+	rows, err := db.QueryContext(ctx, "SELECT * FROM "+ref+" LIMIT 0")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	return nil
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]joinField, len(types))
+	for i, t := range types {
+		fields[i] = joinField{name: t.Name(), typ: t.DatabaseTypeName()}
+	}
+	return fields, nil
+}
+
+// dbRecordReader closes the database its result comes from once it is
+// released.
+type dbRecordReader struct {
+	array.RecordReader
+	db   *sql.DB
+	refs int64
+}
+
+func (r *dbRecordReader) Retain() {
+	atomic.AddInt64(&r.refs, 1)
+	r.RecordReader.Retain()
+}
+
+func (r *dbRecordReader) Release() {
+	r.RecordReader.Release()
+	if atomic.AddInt64(&r.refs, -1) == 0 {
+		r.db.Close()
+	}
 }
@@ -30,10 +30,17 @@
 package join
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
 	_ "github.com/marcboeker/go-duckdb"
 )
 
@@ -71,6 +78,19 @@ func setupTestEnvironment(db *sql.DB) error {
 	return nil
 }
 
+// joinWithDB runs a join and drains its result.
+func joinWithDB(ctx context.Context, db *sql.DB, config *Config) error {
+	rdr, err := JoinDataSourcesWithDB(ctx, db, config)
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	for rdr.Next() {
+	}
+	return rdr.Err()
+}
+
 func TestJoinParquetWithPostgres(t *testing.T) {
 	// Connect to DuckDB
 	db, err := sql.Open("duckdb", "")
@@ -153,3 +173,110 @@ func TestJoinParquetWithPostgres(t *testing.T) {
 		t.Fatalf("expected %+v, got %+v", expected, results[0])
 	}
 }
+
+func TestJoinDataSourcesWithRegisteredView(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatalf("failed to connect to DuckDB: %v", err)
+	}
+	defer db.Close()
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "n_nationkey", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	rec, _, err := array.RecordFromJSON(memory.NewGoAllocator(), schema, strings.NewReader(`[{"n_nationkey": 1}, {"n_nationkey": 2}]`))
+	if err != nil {
+		t.Fatalf("failed to build record: %v", err)
+	}
+	defer rec.Release()
+
+	rdr, err := array.NewRecordReader(schema, []arrow.Record{rec})
+	if err != nil {
+		t.Fatalf("failed to build record reader: %v", err)
+	}
+	defer rdr.Release()
+
+	ctx := context.Background()
+	if err := lakearrow.NewArrow(db).RegisterView(ctx, "selected_nations", rdr); err != nil {
+		t.Fatalf("failed to register view: %v", err)
+	}
+
+	config := &Config{
+		Sources: []DataSource{
+			{Type: "parquet", TableName: "nation", FilePath: "../../data/nation.parquet"},
+		},
+		Query: QueryConfig{
+			SQL: "SELECT count(*) FROM nation JOIN selected_nations USING (n_nationkey)",
+		},
+	}
+
+	if err := joinWithDB(ctx, db, config); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+}
+
+func TestJoinDataSourcesResult(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "region.csv")
+	writeTestFile(t, path, "r_regionkey,r_name\n0,AFRICA\n1,AMERICA\n2,ASIA\n3,EUROPE\n4,MIDDLE EAST\n")
+	config := &Config{
+		Sources: []DataSource{
+			{Type: "parquet", TableName: "nation", FilePath: "../../data/nation.parquet"},
+			{Type: "csv", TableName: "region", FilePath: path},
+		},
+		Query: QueryConfig{
+			SelectColumns: []string{"n.n_name", "r.r_name"},
+			SQL:           "SELECT {select_columns} FROM nation n JOIN region r ON n.n_regionkey = r.r_regionkey ORDER BY n.n_nationkey",
+		},
+	}
+
+	rdr, err := JoinDataSources(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+	defer rdr.Release()
+
+	if names := rdr.Schema().Field(0).Name + "," + rdr.Schema().Field(1).Name; names != "n_name,r_name" {
+		t.Fatalf("unexpected columns: %s", names)
+	}
+	var rows int64
+	var first string
+	for rdr.Next() {
+		rec := rdr.Record()
+		if rows == 0 {
+			first = rec.Column(0).ValueStr(0) + " " + rec.Column(1).ValueStr(0)
+		}
+		rows += rec.NumRows()
+	}
+	if err := rdr.Err(); err != nil {
+		t.Fatalf("failed to read join result: %v", err)
+	}
+	if rows != 25 || first != "ALGERIA AFRICA" {
+		t.Fatalf("unexpected result: %d rows, first %q", rows, first)
+	}
+}
+
+func TestJoinDataSourcesTo(t *testing.T) {
+	config := &Config{
+		Sources: []DataSource{{Type: "parquet", TableName: "nation", FilePath: "../../data/nation.parquet"}},
+		Query:   QueryConfig{SQL: "SELECT n_name FROM nation WHERE n_nationkey < 3 ORDER BY n_nationkey"},
+	}
+
+	var out strings.Builder
+	sink := SinkFunc(func(ctx context.Context, rdr array.RecordReader) error {
+		return lakearrow.WriteCSV(&out, rdr, lakearrow.CSVOptions{})
+	})
+	if err := JoinDataSourcesTo(context.Background(), config, sink); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+	if got := out.String(); got != "n_name\nALGERIA\nARGENTINA\nBRAZIL\n" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+
+	failing := SinkFunc(func(ctx context.Context, rdr array.RecordReader) error { return fmt.Errorf("disk full") })
+	if err := JoinDataSourcesTo(context.Background(), config, failing); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("expected the sink error, got %v", err)
+	}
+
+	config.Query.SQL = "SELECT * FROM missing"
+	if err := JoinDataSourcesTo(context.Background(), config, sink); err == nil || !strings.Contains(err.Error(), "failed to execute join query") {
+		t.Fatalf("expected a query error, got %v", err)
+	}
+}
@@ -0,0 +1,448 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"fmt"
+	"strings"
+
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
+)
+
+// JoinSpec declares a join of several sources. The query SQL is generated
+// from it instead of being written by hand.
+type JoinSpec struct {
+	// From is the first table of the join. Joins are joined to it in order.
+	From  JoinTable   `yaml:"from"`
+	Joins []JoinTable `yaml:"joins"`
+	// Columns are the output columns, where Source is a table alias and
+	// Column may be * for every column of the table. When empty, every
+	// column of the join is selected.
+	Columns []JoinColumn `yaml:"columns,omitempty"`
+}
+
+// JoinTable is a table of a JoinSpec.
+type JoinTable struct {
+	Source string `yaml:"source"`
+	// Table names a table of a postgres, sqlite or duckdb source, such as
+	// public.orders.
+	Table string `yaml:"table,omitempty"`
+	// Alias names the table in keys and columns. It defaults to the last
+	// part of Table, or else to Source.
+	Alias string `yaml:"alias,omitempty"`
+	// Type is inner, the default, left, right, full, semi or anti. Semi and
+	// anti joins filter the tables joined before them and add no columns.
+	Type string `yaml:"type,omitempty"`
+	// On are the keys the table is joined on, which must all match.
+	On []JoinKey `yaml:"on,omitempty"`
+}
+
+// JoinKey matches Left, a column of a table joined before, given as
+// alias.column, with Right, a column of the table being joined. Left may
+// omit the alias when a single table precedes.
+type JoinKey struct {
+	Left  string `yaml:"left"`
+	Right string `yaml:"right"`
+}
+
+var joinTypes = map[string]string{
+	"":      "JOIN",
+	"inner": "JOIN",
+	"left":  "LEFT JOIN",
+	"right": "RIGHT JOIN",
+	"full":  "FULL JOIN",
+	"semi":  "SEMI JOIN",
+	"anti":  "ANTI JOIN",
+}
+
+// joinField is a column of a table of a join and its DuckDB type.
+type joinField struct {
+	name, typ string
+}
+
+// joinError is a problem with a setting of a JoinSpec, such as
+// joins[0].on[1].left.
+type joinError struct {
+	setting string
+	message string
+}
+
+func (e *joinError) Error() string {
+	return e.setting + ": " + e.message
+}
+
+// joinPlan is a JoinSpec resolved against the sources of a config.
+type joinPlan struct {
+	tables  []*planTable
+	aliases map[string]*planTable
+	columns []string
+	errs    []*joinError
+	// describe returns the columns of a relation. When nil, columns and key
+	// types are not checked.
+	describe func(ref string) ([]joinField, error)
+}
+
+type planTable struct {
+	JoinTable
+	setting string
+	alias   string
+	// ref is the relation in SQL.
+	ref string
+	// fields is nil when the columns of the table are not known.
+	fields []joinField
+	keys   []string
+}
+
+// joinSQL generates the query SQL of spec. Columns and key types are checked
+// with describe unless it is nil.
+func joinSQL(sources []DataSource, spec JoinSpec, describe func(ref string) ([]joinField, error)) (string, error) {
+	p := planJoin(sources, spec, describe)
+	if len(p.errs) > 0 {
+		return "", p.errs[0]
+	}
+	return p.sql(), nil
+}
+
+// checkJoin reports every problem of spec that can be found without loading
+// the sources.
+func checkJoin(sources []DataSource, spec JoinSpec) []*joinError {
+	return planJoin(sources, spec, nil).errs
+}
+
+func planJoin(sources []DataSource, spec JoinSpec, describe func(ref string) ([]joinField, error)) *joinPlan {
+	p := &joinPlan{aliases: make(map[string]*planTable), describe: describe}
+	byName := make(map[string]DataSource)
+	for _, source := range sources {
+		byName[source.TableName] = source
+	}
+
+	p.table("from", spec.From, byName)
+	for i, t := range spec.Joins {
+		p.table(fmt.Sprintf("joins[%d]", i), t, byName)
+	}
+	for i, t := range p.tables {
+		p.keys(i, t)
+	}
+
+	names := make(map[string]bool)
+	for i, col := range spec.Columns {
+		if expr, name := p.column(fmt.Sprintf("columns[%d]", i), col); expr != "" {
+			if name != "" && names[strings.ToLower(name)] {
+				p.errorf(fmt.Sprintf("columns[%d]", i), "duplicate output column %s", name)
+			}
+			names[strings.ToLower(name)] = true
+			p.columns = append(p.columns, expr)
+		}
+	}
+	return p
+}
+
+func (p *joinPlan) errorf(setting, format string, args ...any) {
+	p.errs = append(p.errs, &joinError{setting: setting, message: fmt.Sprintf(format, args...)})
+}
+
+// table resolves the source, alias and type of a table.
+func (p *joinPlan) table(setting string, t JoinTable, sources map[string]DataSource) {
+	pt := &planTable{JoinTable: t, setting: setting, alias: t.Alias}
+	p.tables = append(p.tables, pt)
+
+	if setting == "from" {
+		if t.Type != "" {
+			p.errorf(setting+".type", "the first table has no join type")
+		}
+		if len(t.On) > 0 {
+			p.errorf(setting+".on", "the first table has no join keys")
+		}
+	} else {
+		if _, ok := joinTypes[t.Type]; !ok {
+			p.errorf(setting+".type", "unknown join type %q, expected inner, left, right, full, semi or anti", t.Type)
+		}
+		if len(t.On) == 0 {
+			p.errorf(setting+".on", "missing join keys")
+		}
+	}
+
+	source, ok := sources[t.Source]
+	switch {
+	case t.Source == "":
+		p.errorf(setting+".source", "missing source")
+	case !ok:
+		p.errorf(setting+".source", "unknown source %s", t.Source)
+	}
+
+	var parts []string
+	switch {
+	case !ok:
+		// The table of an unknown source cannot be checked.
+	case source.Type == "postgres", source.Type == "sqlite", source.Type == "duckdb":
+		if t.Table == "" {
+			p.errorf(setting+".table", "missing table of %s source %s", source.Type, t.Source)
+			break
+		}
+		parts = strings.Split(t.Table, ".")
+		if len(parts) > 3 {
+			p.errorf(setting+".table", "table %s has too many parts", t.Table)
+		}
+	default:
+		if t.Table != "" {
+			p.errorf(setting+".table", "table applies to postgres, sqlite and duckdb sources only")
+		}
+	}
+	if pt.alias == "" {
+		pt.alias = t.Source
+		if len(parts) > 0 {
+			pt.alias = parts[len(parts)-1]
+		}
+	}
+
+	if err := checkIdent("alias", pt.alias); err != nil {
+		p.errorf(setting+".alias", "%v", err)
+	} else if strings.Contains(pt.alias, ".") {
+		p.errorf(setting+".alias", "alias %s contains a dot", pt.alias)
+	} else if _, dup := p.aliases[strings.ToLower(pt.alias)]; dup {
+		p.errorf(setting+".alias", "duplicate alias %s", pt.alias)
+	} else {
+		p.aliases[strings.ToLower(pt.alias)] = pt
+	}
+
+	if !ok {
+		return
+	}
+	quoted := []string{lakearrow.QuoteIdent(t.Source)}
+	for _, part := range parts {
+		if err := checkIdent("table", part); err != nil {
+			p.errorf(setting+".table", "%v", err)
+			return
+		}
+		quoted = append(quoted, lakearrow.QuoteIdent(part))
+	}
+	pt.ref = strings.Join(quoted, ".")
+
+	if p.describe != nil {
+		fields, err := p.describe(pt.ref)
+		if err != nil {
+			p.errorf(setting, "failed to read columns of %s: %v", pt.ref, err)
+			return
+		}
+		pt.fields = fields
+	}
+}
+
+// keys resolves the join keys of the i-th table.
+func (p *joinPlan) keys(i int, t *planTable) {
+	for j, key := range t.On {
+		setting := fmt.Sprintf("%s.on[%d]", t.setting, j)
+
+		left, lcol, lf, lok := p.leftKey(setting+".left", i, key.Left)
+		right := key.Right
+		if alias, col, ok := strings.Cut(right, "."); ok && strings.EqualFold(alias, t.alias) {
+			right = col
+		}
+		var rf joinField
+		rok := false
+		switch {
+		case right == "":
+			p.errorf(setting+".right", "missing column")
+		case strings.Contains(right, "."):
+			p.errorf(setting+".right", "%s is not a column of %s", key.Right, t.alias)
+			right = ""
+		case t.fields != nil:
+			if rf, rok = findField(t.fields, right); !rok {
+				p.errorf(setting+".right", "%s has no column %s", t.alias, right)
+			}
+		}
+
+		if lok && rok && keyFamily(lf.typ) != keyFamily(rf.typ) {
+			p.errorf(setting, "%s.%s (%s) and %s.%s (%s) have incompatible types",
+				left.alias, lf.name, lf.typ, t.alias, rf.name, rf.typ)
+		}
+		if left != nil && right != "" {
+			t.keys = append(t.keys, lakearrow.QuoteIdent(left.alias)+"."+lakearrow.QuoteIdent(lcol)+" = "+lakearrow.QuoteIdent(t.alias)+"."+lakearrow.QuoteIdent(right))
+		}
+	}
+}
+
+// leftKey resolves the left column of a key of the i-th table to its table
+// and column name. The field is only valid when ok is true.
+func (p *joinPlan) leftKey(setting string, i int, ref string) (t *planTable, col string, f joinField, ok bool) {
+	alias, col, qualified := strings.Cut(ref, ".")
+	if !qualified {
+		alias, col = "", ref
+	}
+	if col == "" {
+		p.errorf(setting, "missing column")
+		return nil, "", f, false
+	}
+
+	if !qualified {
+		var visible []*planTable
+		for _, prev := range p.tables[:i] {
+			if !filterJoin(prev.Type) {
+				visible = append(visible, prev)
+			}
+		}
+		if len(visible) != 1 {
+			p.errorf(setting, "%s must be given as alias.column", ref)
+			return nil, "", f, false
+		}
+		t = visible[0]
+	} else {
+		t = p.aliases[strings.ToLower(alias)]
+		switch {
+		case t == nil:
+			p.errorf(setting, "unknown table alias %s", alias)
+			return nil, "", f, false
+		case p.index(t) >= i:
+			p.errorf(setting, "%s is not joined before %s", alias, p.tables[i].alias)
+			return nil, "", f, false
+		case filterJoin(t.Type):
+			p.errorf(setting, "%s is a %s join and has no columns", alias, t.Type)
+			return nil, "", f, false
+		}
+	}
+
+	if t.fields == nil {
+		return t, col, f, false
+	}
+	if f, ok = findField(t.fields, col); !ok {
+		p.errorf(setting, "%s has no column %s", t.alias, col)
+	}
+	return t, col, f, ok
+}
+
+// column resolves an output column, and returns its SQL and output name.
+func (p *joinPlan) column(setting string, col JoinColumn) (expr, name string) {
+	if col.Column == "" {
+		p.errorf(setting+".column", "missing column")
+		return "", ""
+	}
+	if col.Source == "" {
+		p.errorf(setting+".source", "missing table alias")
+		return "", ""
+	}
+	t := p.aliases[strings.ToLower(col.Source)]
+	switch {
+	case t == nil:
+		p.errorf(setting+".source", "unknown table alias %s", col.Source)
+		return "", ""
+	case filterJoin(t.Type):
+		p.errorf(setting+".source", "%s is a %s join and has no columns", col.Source, t.Type)
+		return "", ""
+	}
+
+	if col.Column == "*" {
+		if col.As != "" {
+			p.errorf(setting+".as", "* cannot be renamed")
+		}
+		return lakearrow.QuoteIdent(t.alias) + ".*", ""
+	}
+	if t.fields != nil {
+		if _, ok := findField(t.fields, col.Column); !ok {
+			p.errorf(setting+".column", "%s has no column %s", t.alias, col.Column)
+		}
+	}
+
+	expr = lakearrow.QuoteIdent(t.alias) + "." + lakearrow.QuoteIdent(col.Column)
+	name = col.Column
+	if col.As != "" {
+		if err := checkIdent("alias", col.As); err != nil {
+			p.errorf(setting+".as", "%v", err)
+		}
+		expr += " AS " + lakearrow.QuoteIdent(col.As)
+		name = col.As
+	}
+	return expr, name
+}
+
+func (p *joinPlan) index(t *planTable) int {
+	for i, pt := range p.tables {
+		if pt == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// sql returns the query of a plan without errors.
+func (p *joinPlan) sql() string {
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	if len(p.columns) == 0 {
+		b.WriteString("*")
+	} else {
+		b.WriteString(strings.Join(p.columns, ", "))
+	}
+	for i, t := range p.tables {
+		if i == 0 {
+			fmt.Fprintf(&b, "\nFROM %s AS %s", t.ref, lakearrow.QuoteIdent(t.alias))
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s %s AS %s ON %s", joinTypes[t.Type], t.ref, lakearrow.QuoteIdent(t.alias), strings.Join(t.keys, " AND "))
+	}
+	return b.String()
+}
+
+// filterJoin reports whether a join type only filters the tables before it.
+func filterJoin(typ string) bool {
+	return typ == "semi" || typ == "anti"
+}
+
+func findField(fields []joinField, name string) (joinField, bool) {
+	for _, f := range fields {
+		if strings.EqualFold(f.name, name) {
+			return f, true
+		}
+	}
+	return joinField{}, false
+}
+
+// keyFamilies groups the DuckDB types that compare with each other without
+// an explicit cast.
+var keyFamilies = map[string]string{
+	"TINYINT": "number", "SMALLINT": "number", "INTEGER": "number", "BIGINT": "number", "HUGEINT": "number",
+	"UTINYINT": "number", "USMALLINT": "number", "UINTEGER": "number", "UBIGINT": "number", "UHUGEINT": "number",
+	"FLOAT": "number", "DOUBLE": "number", "DECIMAL": "number",
+	"VARCHAR": "text", "ENUM": "text",
+	"DATE": "timestamp", "TIMESTAMP": "timestamp", "TIMESTAMP_S": "timestamp", "TIMESTAMP_MS": "timestamp",
+	"TIMESTAMP_NS": "timestamp", "TIMESTAMPTZ": "timestamp",
+}
+
+// keyFamily returns the family of a DuckDB type name. Types outside the
+// families only match themselves.
+func keyFamily(typ string) string {
+	name := strings.ToUpper(strings.TrimSpace(typ))
+	if i := strings.IndexAny(name, "("); i >= 0 {
+		name = name[:i]
+	}
+	if family, ok := keyFamilies[name]; ok {
+		return family
+	}
+	return strings.ToUpper(typ)
+}
@@ -0,0 +1,223 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func joinSpecSources(t *testing.T) []DataSource {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "customers.csv"), "id,country,region,name\n1,3,1,ann\n2,3,2,bob\n3,7,3,cy\n4,99,0,dee\n")
+	writeTestFile(t, filepath.Join(dir, "orders.csv"), "order_id,customer,total\n10,1,5.5\n11,1,2\n12,3,1\n")
+	writeTestFile(t, filepath.Join(dir, "blocked.csv"), "customer\n3\n")
+	return []DataSource{
+		{Type: "parquet", TableName: "nation", FilePath: "../../data/nation.parquet"},
+		{Type: "csv", TableName: "customers", FilePath: filepath.Join(dir, "customers.csv")},
+		{Type: "csv", TableName: "orders", FilePath: filepath.Join(dir, "orders.csv")},
+		{Type: "csv", TableName: "blocked", FilePath: filepath.Join(dir, "blocked.csv")},
+	}
+}
+
+func TestJoinSpec(t *testing.T) {
+	spec := &JoinSpec{
+		From: JoinTable{Source: "customers", Alias: "c"},
+		Joins: []JoinTable{
+			{Source: "nation", Alias: "n", Type: "left", On: []JoinKey{
+				{Left: "country", Right: "n_nationkey"}, {Left: "c.region", Right: "n.n_regionkey"},
+			}},
+			{Source: "blocked", Type: "anti", On: []JoinKey{{Left: "c.id", Right: "customer"}}},
+			{Source: "orders", Alias: "o", Type: "semi", On: []JoinKey{{Left: "c.id", Right: "customer"}}},
+		},
+		Columns: []JoinColumn{
+			{Source: "c", Column: "name", As: "customer"},
+			{Source: "n", Column: "n_name", As: "nation"},
+		},
+	}
+	config := &Config{Sources: joinSpecSources(t), Query: QueryConfig{Join: spec}}
+
+	query, err := config.RenderSQL()
+	if err != nil {
+		t.Fatalf("failed to render join: %v", err)
+	}
+	want := `SELECT "c"."name" AS "customer", "n"."n_name" AS "nation"
+FROM "customers" AS "c"
+LEFT JOIN "nation" AS "n" ON "c"."country" = "n"."n_nationkey" AND "c"."region" = "n"."n_regionkey"
+ANTI JOIN "blocked" AS "blocked" ON "c"."id" = "blocked"."customer"
+SEMI JOIN "orders" AS "o" ON "c"."id" = "o"."customer"`
+	if query != want {
+		t.Fatalf("unexpected SQL:\n%s\nwant:\n%s", query, want)
+	}
+
+	rdr, err := JoinDataSourcesWithDB(context.Background(), openTestDB(t), config)
+	if err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+	defer rdr.Release()
+	if !rdr.Next() {
+		t.Fatalf("expected a join result")
+	}
+	rec := rdr.Record()
+	if rec.NumRows() != 1 || rec.Schema().Field(0).Name != "customer" || rec.Schema().Field(1).Name != "nation" ||
+		rec.Column(0).ValueStr(0) != "ann" || rec.Column(1).ValueStr(0) != "CANADA" {
+		t.Fatalf("unexpected join result: %v", rec)
+	}
+}
+
+func TestJoinSpecFullJoin(t *testing.T) {
+	config := &Config{
+		Sources: joinSpecSources(t),
+		Query: QueryConfig{Join: &JoinSpec{
+			From:    JoinTable{Source: "orders"},
+			Joins:   []JoinTable{{Source: "customers", Type: "full", On: []JoinKey{{Left: "customer", Right: "id"}}}},
+			Columns: []JoinColumn{{Source: "orders", Column: "*"}, {Source: "customers", Column: "id", As: "customer_id"}},
+		}},
+	}
+	rdr, err := JoinDataSourcesWithDB(context.Background(), openTestDB(t), config)
+	if err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+	defer rdr.Release()
+	if !rdr.Next() || rdr.Record().NumRows() != 5 || rdr.Record().NumCols() != 4 {
+		t.Fatalf("expected 5 rows of 4 columns")
+	}
+}
+
+func TestJoinSpecErrors(t *testing.T) {
+	for _, tc := range []struct {
+		spec JoinSpec
+		want string
+	}{
+		{JoinSpec{From: JoinTable{Source: "orders"}, Joins: []JoinTable{{Source: "customers", On: []JoinKey{{Left: "customer", Right: "nmae"}}}}},
+			"joins[0].on[0].right: customers has no column nmae"},
+		{JoinSpec{From: JoinTable{Source: "orders"}, Joins: []JoinTable{{Source: "customers", On: []JoinKey{{Left: "orders.customr", Right: "id"}}}}},
+			"joins[0].on[0].left: orders has no column customr"},
+		{JoinSpec{From: JoinTable{Source: "orders"}, Joins: []JoinTable{{Source: "nation", On: []JoinKey{{Left: "customer", Right: "n_name"}}}}},
+			"joins[0].on[0]: orders.customer (BIGINT) and nation.n_name (VARCHAR) have incompatible types"},
+		{JoinSpec{From: JoinTable{Source: "orders"}, Columns: []JoinColumn{{Source: "orders", Column: "total", As: "t"}, {Source: "orders", Column: "order_id", As: "T"}}},
+			"columns[1]: duplicate output column T"},
+	} {
+		config := &Config{Sources: joinSpecSources(t), Query: QueryConfig{Join: &tc.spec}}
+		_, err := JoinDataSourcesWithDB(context.Background(), openTestDB(t), config)
+		if err == nil || !strings.Contains(err.Error(), "invalid join: "+tc.want) {
+			t.Errorf("expected an error containing %q, got %v", tc.want, err)
+		}
+	}
+}
+
+func TestCheckJoin(t *testing.T) {
+	sources := []DataSource{
+		{Type: "parquet", TableName: "nation"},
+		{Type: "postgres", TableName: "pg"},
+	}
+	spec := JoinSpec{
+		From: JoinTable{Source: "nation", Type: "left"},
+		Joins: []JoinTable{
+			{Source: "pg", On: []JoinKey{{Left: "n_nationkey", Right: "nation_id"}, {Left: "n_nationkey", Right: "other.nation_id"}}},
+			{Source: "pg", Table: "public.customers", Alias: "c", Type: "outer", On: []JoinKey{{Left: "x.id", Right: "id"}}},
+			{Source: "nation", Table: "t", Alias: "n2", Type: "semi", On: []JoinKey{{Left: "id", Right: "id"}}},
+			{Source: "shop", Table: "public.orders", Alias: "c"},
+			{Source: "nation", Alias: "n3", On: []JoinKey{{Left: "n2.id", Right: "id"}, {Left: "z.id", Right: ""}}},
+		},
+		Columns: []JoinColumn{{Source: "n2", Column: "id"}, {Source: "c", Column: "*", As: "all"}, {Column: "id"}},
+	}
+
+	var got []string
+	for _, err := range checkJoin(sources, spec) {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"from.type: the first table has no join type",
+		"joins[0].table: missing table of postgres source pg",
+		"joins[1].type: unknown join type \"outer\", expected inner, left, right, full, semi or anti",
+		"joins[2].table: table applies to postgres, sqlite and duckdb sources only",
+		"joins[3].on: missing join keys",
+		"joins[3].source: unknown source shop",
+		"joins[3].alias: duplicate alias c",
+		"joins[0].on[1].right: other.nation_id is not a column of pg",
+		"joins[1].on[0].left: unknown table alias x",
+		"joins[2].on[0].left: id must be given as alias.column",
+		"joins[4].on[0].left: n2 is a semi join and has no columns",
+		"joins[4].on[1].left: unknown table alias z",
+		"joins[4].on[1].right: missing column",
+		"columns[0].source: n2 is a semi join and has no columns",
+		"columns[1].as: * cannot be renamed",
+		"columns[2].source: missing table alias",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidateJoin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeTestFile(t, path, `sources:
+  - type: parquet
+    table_name: nation
+    file_path: ../../data/nation.parquet
+query:
+  sql: SELECT 1
+  join:
+    from:
+      source: nation
+      alias: n
+    joins:
+      - source: nation
+        alias: r
+        type: left
+        on:
+          - left: n.n_regionkey
+            right: r_regionkey
+          - left: x.id
+            right: id
+    columns:
+      - source: n
+        column: n_name
+        as: name
+`)
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	var errs ValidationErrors
+	if err := config.Validate(); !errors.As(err, &errs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	want := path + ":6:8: query.sql: sql cannot be given with join\n" +
+		path + ":18:19: query.join.joins[0].on[1].left: unknown table alias x"
+	if errs.Error() != want {
+		t.Fatalf("unexpected validation errors:\n%s\nwant:\n%s", errs, want)
+	}
+}
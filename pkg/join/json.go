@@ -0,0 +1,108 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+)
+
+// registerJSON loads the newline-delimited JSON files matched by
+// source.FilePath into a relation named source.TableName. Without an
+// explicit column list, the columns are inferred from the first file.
+//
+// Unlike CSV, JSON is parsed in Go rather than by DuckDB: read_json lives in
+// the json extension, which the bundled DuckDB does not include and cannot
+// always install, and it has no store_rejects option to feed the OnError
+// policy. Both readers report rejected rows through the same rejectPolicy.
+func registerJSON(ctx context.Context, lake *lakearrow.Arrow, source DataSource) (err error) {
+	opts := source.Options
+	if opts.Delimiter != "" || opts.Quote != "" || opts.Header != nil {
+		return fmt.Errorf("delimiter, quote and header do not apply to json sources")
+	}
+
+	var schema *arrow.Schema
+	if len(opts.Columns) > 0 {
+		if schema, err = columnSchema(ctx, lake, opts.Columns); err != nil {
+			return err
+		}
+	}
+
+	policy, err := newRejectPolicy(opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := policy.close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	open := func(path string, r io.Reader) (array.RecordReader, error) {
+		rdr, err := lake.ReadNDJSON(r, lakearrow.NDJSONOptions{
+			Schema:          schema,
+			NullStrings:     opts.NullStrings,
+			DateFormat:      opts.DateFormat,
+			TimestampFormat: opts.TimestampFormat,
+			OnError: func(e *lakearrow.ParseError) error {
+				e.File = path
+				return policy.handle(e)
+			},
+		})
+		if err == nil && schema == nil {
+			schema = rdr.Schema()
+		}
+		return rdr, err
+	}
+	return registerFiles(ctx, lake, source, opts.Compression, open)
+}
+
+// columnSchema resolves the DuckDB types of an explicit column list.
+func columnSchema(ctx context.Context, lake *lakearrow.Arrow, columns []ColumnType) (*arrow.Schema, error) {
+	exprs := make([]string, len(columns))
+	for i, col := range columns {
+		if err := checkColumnType(col); err != nil {
+			return nil, err
+		}
+		exprs[i] = fmt.Sprintf("CAST(NULL AS %s) AS %s", col.Type, lakearrow.QuoteIdent(col.Name))
+	}
+
+	schema, err := lake.QuerySchema(ctx, "SELECT "+strings.Join(exprs, ", "))
+	if err != nil {
+		return nil, fmt.Errorf("invalid column types: %w", err)
+	}
+	return schema, nil
+}
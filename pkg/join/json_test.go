@@ -0,0 +1,156 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONSources(t *testing.T) {
+	db := openTestDB(t)
+	dir := t.TempDir()
+	writeGzipFile(t, filepath.Join(dir, "events-1.json.gz"),
+		`{"id": 1, "n_nationkey": 3, "day": "02/01/2024", "note": "NONE"}`+"\n"+
+			`{"id": 2, "n_nationkey": 4, "day": "03/01/2024", "note": "late"}`+"\n")
+	// The second file lacks the note key; its columns come from the first.
+	writeZstdFile(t, filepath.Join(dir, "events-2.json.zst"), `{"id": 3, "n_nationkey": 30, "day": "04/01/2024"}`+"\n")
+
+	config := &Config{
+		Sources: []DataSource{
+			{Type: "parquet", TableName: "nation", FilePath: "../../data/nation.parquet"},
+			{Type: "json", TableName: "events", FilePath: filepath.Join(dir, "events-*"),
+				Options: FileOptions{DateFormat: "%d/%m/%Y", NullStrings: []string{"NONE"}}},
+		},
+		Query: QueryConfig{SQL: "SELECT count(*) FROM events JOIN nation USING (n_nationkey)"},
+	}
+	if err := joinWithDB(context.Background(), db, config); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+
+	var total, notes int
+	var lastDay, dayType string
+	err := db.QueryRow(`SELECT count(*), count(note), max(day)::VARCHAR, any_value(typeof(day)) FROM events`).Scan(&total, &notes, &lastDay, &dayType)
+	if err != nil {
+		t.Fatalf("failed to query events: %v", err)
+	}
+	if total != 3 || notes != 1 || lastDay != "2024-01-04" || dayType != "DATE" {
+		t.Fatalf("unexpected events: %d rows, %d notes, last day %s of type %s", total, notes, lastDay, dayType)
+	}
+}
+
+func TestJSONSourceColumns(t *testing.T) {
+	db := openTestDB(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "readings.json")
+	writeTestFile(t, path, `{"id": 1, "value": "1.25", "tags": ["a", "b"], "ignored": true}`+"\n"+
+		`{"id": 2, "value": 3, "tags": []}`+"\n")
+
+	config := &Config{
+		Sources: []DataSource{{Type: "json", TableName: "readings", FilePath: path,
+			Options: FileOptions{Columns: []ColumnType{
+				{Name: "id", Type: "SMALLINT"}, {Name: "value", Type: "DECIMAL(8,2)"}, {Name: "tags", Type: "VARCHAR[]"},
+			}}}},
+		Query: QueryConfig{SQL: "SELECT count(*) FROM readings"},
+	}
+	if err := joinWithDB(context.Background(), db, config); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+
+	var types, tags string
+	var sum float64
+	err := db.QueryRow(`SELECT string_agg(DISTINCT typeof(id) || ' ' || typeof(value), ','), sum(value)::DOUBLE, array_to_string(flatten(list(tags)), ',') FROM readings`).Scan(&types, &sum, &tags)
+	if err != nil {
+		t.Fatalf("failed to query readings: %v", err)
+	}
+	if types != "SMALLINT DECIMAL(8,2)" || sum != 4.25 || tags != "a,b" {
+		t.Fatalf("unexpected readings: %q %v %q", types, sum, tags)
+	}
+
+	config.Sources[0].TableName = "bad_readings"
+	for _, typ := range []string{
+		"INTEGER; DROP TABLE readings",
+		"INTEGER) AS id, (SELECT CAST(NULL AS INTEGER",
+		"DECIMAL(8,2))",
+		"VARCHAR[)",
+	} {
+		config.Sources[0].Options.Columns[0].Type = typ
+		if err := joinWithDB(context.Background(), db, config); err == nil || !strings.Contains(err.Error(), "invalid type") {
+			t.Fatalf("%s: expected an invalid type error, got %v", typ, err)
+		}
+	}
+}
+
+func TestJSONSourceErrorPolicies(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "amounts.json")
+	writeTestFile(t, path, `{"id": 1, "amount": 2.5}`+"\n"+`{"id": 2, "amount": "abc"}`+"\n"+`{"id": 3`+"\n"+`{"id": 4, "amount": 4}`+"\n")
+	columns := []ColumnType{{Name: "id", Type: "INTEGER"}, {Name: "amount", Type: "DOUBLE"}}
+	config := func(opts FileOptions) *Config {
+		opts.Columns = columns
+		return &Config{
+			Sources: []DataSource{{Type: "json", TableName: "amounts", FilePath: path, Options: opts}},
+			Query:   QueryConfig{SQL: "SELECT count(*) FROM amounts"},
+		}
+	}
+	ctx := context.Background()
+
+	err := joinWithDB(ctx, openTestDB(t), config(FileOptions{}))
+	want := path + `:2:2: column amount: could not convert "abc" to float64`
+	if err == nil || !strings.HasSuffix(err.Error(), want) {
+		t.Fatalf("expected error ending in %q, got %v", want, err)
+	}
+
+	db := openTestDB(t)
+	if err := joinWithDB(ctx, db, config(FileOptions{OnError: OnErrorSkip})); err != nil {
+		t.Fatalf("failed to skip bad rows: %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM amounts").Scan(&count); err != nil || count != 2 {
+		t.Fatalf("expected 2 rows, got %d (%v)", count, err)
+	}
+
+	rejectFile := filepath.Join(dir, "rejects.csv")
+	if err := joinWithDB(ctx, openTestDB(t), config(FileOptions{OnError: OnErrorReject, RejectFile: rejectFile})); err != nil {
+		t.Fatalf("failed to reject bad rows: %v", err)
+	}
+	rejects := readRejects(t, rejectFile)
+	if len(rejects) != 3 {
+		t.Fatalf("expected a header and 2 rejected rows, got %q", rejects)
+	}
+	if got := strings.Join(rejects[1][:4], ","); got != path+",2,2,amount" {
+		t.Fatalf("unexpected reject %q", rejects[1])
+	}
+	if got := strings.Join(rejects[2][:3], ","); got != path+",3," || rejects[2][5] != `{"id": 3` {
+		t.Fatalf("unexpected reject %q", rejects[2])
+	}
+}
@@ -0,0 +1,184 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// SQL generated from a DataSource quotes every identifier with
+// lakearrow.QuoteIdent and every string with lakearrow.QuoteString. Values
+// that cannot be quoted safely, such as those containing NUL bytes, are
+// rejected by checkSource first.
+
+// checkSource validates the fields of source that end up in generated SQL.
+func checkSource(source DataSource) error {
+	if err := checkIdent("table name", source.TableName); err != nil {
+		return err
+	}
+	if err := checkText("file path", source.FilePath); err != nil {
+		return err
+	}
+	if source.Type == "postgres" {
+		if err := checkConnectionString(source.ConnectionString); err != nil {
+			return err
+		}
+	}
+	for _, col := range source.Options.Columns {
+		if err := checkColumnType(col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkIdent reports whether name can be quoted as an identifier. Any
+// character may appear in a quoted identifier except NUL.
+func checkIdent(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("missing %s", kind)
+	}
+	return checkText(kind, name)
+}
+
+// checkText reports whether s can be quoted as a string literal.
+func checkText(kind, s string) error {
+	if !utf8.ValidString(s) {
+		return fmt.Errorf("%s %q is not valid UTF-8", kind, s)
+	}
+	if strings.ContainsRune(s, 0) {
+		return fmt.Errorf("%s %q contains a NUL byte", kind, s)
+	}
+	return nil
+}
+
+// columnTypeRe matches the DuckDB type names allowed in column lists, such
+// as DECIMAL(10, 2), VARCHAR[] or STRUCT(a INTEGER).
+var columnTypeRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_ ,()\[\]]*$`)
+
+func checkColumnType(col ColumnType) error {
+	if err := checkIdent("column name", col.Name); err != nil {
+		return err
+	}
+	if !columnTypeRe.MatchString(col.Type) || !balanced(col.Type) {
+		return fmt.Errorf("column %s: invalid type %q", col.Name, col.Type)
+	}
+	return nil
+}
+
+// balanced reports whether the parentheses and brackets of s pair up, so
+// that a type spliced into an expression cannot close it.
+func balanced(s string) bool {
+	var closers []byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '(':
+			closers = append(closers, ')')
+		case '[':
+			closers = append(closers, ']')
+		case ')', ']':
+			if len(closers) == 0 || closers[len(closers)-1] != c {
+				return false
+			}
+			closers = closers[:len(closers)-1]
+		}
+	}
+	return len(closers) == 0
+}
+
+// checkConnectionString reports whether s is a PostgreSQL connection string,
+// either a postgres:// URI or a list of keyword = value settings.
+func checkConnectionString(s string) error {
+	if err := checkText("connection string", s); err != nil {
+		return err
+	}
+	if strings.HasPrefix(s, "postgres://") || strings.HasPrefix(s, "postgresql://") {
+		if _, err := url.Parse(s); err != nil {
+			return fmt.Errorf("invalid connection string: %w", err)
+		}
+		return nil
+	}
+
+	// Settings follow libpq's rules: values may be single-quoted, and a
+	// backslash escapes the next character.
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return fmt.Errorf("invalid connection string: missing = after %q", rest)
+		}
+		key := strings.TrimSpace(rest[:eq])
+		if !connKeyRe.MatchString(key) {
+			return fmt.Errorf("invalid connection string: invalid keyword %q", key)
+		}
+
+		rest = strings.TrimLeft(rest[eq+1:], " \t\n\r")
+		n, err := connValueLen(rest)
+		if err != nil {
+			return fmt.Errorf("invalid connection string: %s: %w", key, err)
+		}
+		rest = strings.TrimLeft(rest[n:], " \t\n\r")
+	}
+	return nil
+}
+
+var connKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// connValueLen returns the length of the value at the start of s.
+func connValueLen(s string) (int, error) {
+	quoted := strings.HasPrefix(s, "'")
+	i := 0
+	if quoted {
+		i = 1
+	}
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == '\\':
+			if i+1 == len(s) {
+				return 0, fmt.Errorf("trailing backslash")
+			}
+			i += 2
+			continue
+		case quoted && c == '\'':
+			return i + 1, nil
+		case !quoted && (c == ' ' || c == '\t' || c == '\n' || c == '\r'):
+			return i, nil
+		}
+		i++
+	}
+	if quoted {
+		return 0, fmt.Errorf("unterminated quoted value")
+	}
+	return i, nil
+}
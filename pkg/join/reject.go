@@ -0,0 +1,114 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
+)
+
+// Policies for rows of csv and json sources that cannot be parsed.
+const (
+	OnErrorFail   = "fail"
+	OnErrorSkip   = "skip"
+	OnErrorReject = "reject"
+)
+
+// rejectHeader is the header of reject files.
+var rejectHeader = []string{"file", "line", "column", "column_name", "error", "row"}
+
+// rejectPolicy applies FileOptions.OnError to parse errors.
+type rejectPolicy struct {
+	mode string
+	file *os.File
+	w    *csv.Writer
+}
+
+func newRejectPolicy(opts FileOptions) (*rejectPolicy, error) {
+	p := &rejectPolicy{mode: opts.OnError}
+	switch p.mode {
+	case "":
+		p.mode = OnErrorFail
+	case OnErrorFail, OnErrorSkip:
+	case OnErrorReject:
+		if opts.RejectFile == "" {
+			return nil, fmt.Errorf("on_error %s requires a reject_file", OnErrorReject)
+		}
+		f, err := os.Create(opts.RejectFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create reject file: %w", err)
+		}
+		p.file = f
+		p.w = csv.NewWriter(f)
+		if err := p.w.Write(rejectHeader); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to write reject file: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown on_error policy: %s", opts.OnError)
+	}
+	return p, nil
+}
+
+// handle returns e when rows that cannot be parsed should fail the source,
+// and otherwise skips the row, recording it in the reject file if there is
+// one.
+func (p *rejectPolicy) handle(e *lakearrow.ParseError) error {
+	switch p.mode {
+	case OnErrorFail:
+		return e
+	case OnErrorReject:
+		column := ""
+		if e.Column > 0 {
+			column = strconv.Itoa(e.Column)
+		}
+		record := []string{e.File, strconv.FormatInt(e.Line, 10), column, e.ColumnName, e.Message, e.Row}
+		if err := p.w.Write(record); err != nil {
+			return fmt.Errorf("failed to write reject file: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *rejectPolicy) close() error {
+	if p.file == nil {
+		return nil
+	}
+	p.w.Flush()
+	if err := p.w.Error(); err != nil {
+		p.file.Close()
+		return fmt.Errorf("failed to write reject file: %w", err)
+	}
+	return p.file.Close()
+}
@@ -0,0 +1,360 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
+	"gopkg.in/yaml.v3"
+)
+
+// The string settings of a config file may refer to the environment and to
+// secret files:
+//
+//	${NAME}            the environment variable NAME, which must be set
+//	${NAME:-default}   NAME, or default when NAME is unset or empty
+//	${file:path}       the contents of the file at path, without trailing
+//	                   newlines
+//	$${                a literal ${
+//
+// Values read from files and the passwords of connection strings, however
+// they are given, are secrets, which Config.Redact masks. Other environment
+// variables are not, so a secret used elsewhere, such as in a param, must be
+// read from a file.
+
+const redacted = "***"
+
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// interpolator resolves the references of a config file.
+type interpolator struct {
+	file    string
+	secrets []string
+	errs    ValidationErrors
+}
+
+// interpolate resolves the references in the string values of the config
+// document root, and returns the secrets they read.
+func interpolate(file string, root *yaml.Node) ([]string, error) {
+	in := &interpolator{file: file}
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		in.walk(root.Content[0], "")
+	}
+	if len(in.errs) > 0 {
+		return nil, in.errs
+	}
+	return in.secrets, nil
+}
+
+func (in *interpolator) walk(node *yaml.Node, setting string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			child := node.Content[i].Value
+			if setting != "" {
+				child = setting + "." + child
+			}
+			in.walk(node.Content[i+1], child)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			in.walk(item, fmt.Sprintf("%s[%d]", setting, i))
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${") {
+			return
+		}
+		value, secrets, err := expand(node.Value)
+		if err != nil {
+			in.errs = append(in.errs, &ValidationError{
+				File:     in.file,
+				Position: Position{node.Line, node.Column},
+				Path:     setting,
+				Message:  err.Error(),
+			})
+			return
+		}
+		node.Value = value
+		in.secrets = append(in.secrets, secrets...)
+		// Plain values are typed again once resolved, so that a param such
+		// as ${LIMIT:-10} is still a number.
+		if node.Style == 0 && (node.Tag == "!!str" || node.Tag == "") {
+			node.Tag = ""
+		}
+	}
+}
+
+// expand resolves the references in s, and returns the values read from
+// files as secrets.
+func expand(s string) (string, []string, error) {
+	var b strings.Builder
+	var secrets []string
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), secrets, nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", nil, fmt.Errorf("unterminated reference %s", s[i:])
+		}
+		ref := s[i+2 : i+end]
+		s = s[i+end+1:]
+
+		value, fromFile, err := resolveRef(ref)
+		if err != nil {
+			return "", nil, err
+		}
+		if fromFile {
+			secrets = append(secrets, value)
+		}
+		b.WriteString(value)
+	}
+}
+
+// resolveRef returns the value of the reference ${ref}.
+func resolveRef(ref string) (value string, fromFile bool, err error) {
+	if path, ok := strings.CutPrefix(ref, "file:"); ok {
+		if path == "" {
+			return "", false, fmt.Errorf("missing path in ${%s}", ref)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read secret: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	}
+
+	name, def, hasDefault := strings.Cut(ref, ":-")
+	if !envNameRe.MatchString(name) {
+		return "", false, fmt.Errorf("invalid reference ${%s}", ref)
+	}
+	value, ok := os.LookupEnv(name)
+	switch {
+	case hasDefault && value == "":
+		return def, false, nil
+	case !ok:
+		return "", false, fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, false, nil
+}
+
+// connSecrets returns the passwords of a PostgreSQL connection string, both
+// as they appear in it and decoded.
+func connSecrets(s string) []string {
+	var secrets []string
+	for _, span := range connPasswords(s) {
+		secrets = append(secrets, s[span[0]:span[1]])
+	}
+	if strings.HasPrefix(s, "postgres://") || strings.HasPrefix(s, "postgresql://") {
+		if u, err := url.Parse(s); err == nil {
+			if password, ok := u.User.Password(); ok && !slices.Contains(secrets, password) {
+				secrets = append(secrets, password)
+			}
+			if password := u.Query().Get("password"); password != "" && !slices.Contains(secrets, password) {
+				secrets = append(secrets, password)
+			}
+		}
+	}
+	return secrets
+}
+
+// redactConn masks the passwords of a PostgreSQL connection string.
+func redactConn(s string) string {
+	spans := connPasswords(s)
+	for i := len(spans) - 1; i >= 0; i-- {
+		s = s[:spans[i][0]] + redacted + s[spans[i][1]:]
+	}
+	return s
+}
+
+// connPasswords returns the offsets of the passwords in a PostgreSQL
+// connection string, in order.
+func connPasswords(s string) [][2]int {
+	var spans [][2]int
+	if strings.HasPrefix(s, "postgres://") || strings.HasPrefix(s, "postgresql://") {
+		start := strings.Index(s, "://") + 3
+		end := len(s)
+		if i := strings.IndexAny(s[start:], "/?#"); i >= 0 {
+			end = start + i
+		}
+		if at := strings.LastIndexByte(s[start:end], '@'); at >= 0 {
+			if colon := strings.IndexByte(s[start:start+at], ':'); colon >= 0 {
+				spans = append(spans, [2]int{start + colon + 1, start + at})
+			}
+		}
+		if q := strings.IndexByte(s, '?'); q >= 0 {
+			for off := q + 1; off < len(s); {
+				n := strings.IndexAny(s[off:], "&#")
+				if n < 0 {
+					n = len(s) - off
+				}
+				if strings.HasPrefix(s[off:off+n], "password=") {
+					spans = append(spans, [2]int{off + len("password="), off + n})
+				}
+				if off+n < len(s) && s[off+n] == '#' {
+					break
+				}
+				off += n + 1
+			}
+		}
+		return spans
+	}
+
+	off := len(s) - len(strings.TrimLeft(s, " \t\n\r"))
+	for off < len(s) {
+		eq := strings.IndexByte(s[off:], '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(s[off : off+eq])
+		off += eq + 1
+		off += len(s[off:]) - len(strings.TrimLeft(s[off:], " \t\n\r"))
+		n, err := connValueLen(s[off:])
+		if err != nil {
+			n = len(s) - off
+		}
+		if key == "password" && n > 0 {
+			spans = append(spans, [2]int{off, off + n})
+		}
+		off += n
+		off += len(s[off:]) - len(strings.TrimLeft(s[off:], " \t\n\r"))
+	}
+	return spans
+}
+
+// secretValues returns the secrets of the config, longest first so that
+// secrets containing others are masked whole.
+func (c *Config) secretValues() []string {
+	secrets := append([]string(nil), c.secrets...)
+	for _, source := range c.Sources {
+		secrets = append(secrets, connSecrets(source.ConnectionString)...)
+	}
+
+	kept := secrets[:0]
+	for _, s := range secrets {
+		if s != "" && s != redacted {
+			kept = append(kept, s)
+		}
+	}
+	// Params render as SQL literals, which double their quotes.
+	for _, s := range kept {
+		if strings.Contains(s, "'") {
+			quoted := lakearrow.QuoteString(s)
+			kept = append(kept, quoted[1:len(quoted)-1])
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return len(kept[i]) > len(kept[j]) })
+	return kept
+}
+
+// Redact masks the secrets of the config in s, such as a message to log.
+func (c *Config) Redact(s string) string {
+	return redact(s, c.secretValues())
+}
+
+func redact(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+	return s
+}
+
+// redactError masks the secrets of the config in the message of err. The
+// original error is still available through errors.Unwrap.
+func (c *Config) redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := c.Redact(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+// String returns the config as YAML with its secrets masked, and with the
+// references of a config file resolved.
+func (c *Config) String() string {
+	masked := *c
+	masked.Sources = append([]DataSource(nil), c.Sources...)
+	for i := range masked.Sources {
+		masked.Sources[i].ConnectionString = redactConn(masked.Sources[i].ConnectionString)
+	}
+
+	var root yaml.Node
+	if err := root.Encode(&masked); err != nil {
+		return fmt.Sprintf("invalid config: %v", c.Redact(err.Error()))
+	}
+	var mask func(*yaml.Node)
+	mask = func(node *yaml.Node) {
+		if node.Kind == yaml.ScalarNode {
+			if value := redact(node.Value, c.secrets); value != node.Value {
+				node.Value, node.Tag = value, "!!str"
+			}
+		}
+		for _, child := range node.Content {
+			mask(child)
+		}
+	}
+	mask(&root)
+
+	data, err := yaml.Marshal(&root)
+	if err != nil {
+		return fmt.Sprintf("invalid config: %v", c.Redact(err.Error()))
+	}
+	return string(data)
+}
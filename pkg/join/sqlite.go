@@ -0,0 +1,321 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
+	"github.com/apache/arrow/go/v17/arrow"
+	_ "modernc.org/sqlite"
+)
+
+// registerSQLite loads the tables and views of the SQLite database at
+// source.FilePath into a DuckDB schema named source.TableName, so they are
+// queried as <table_name>.<table>. The database is read with a pure Go
+// driver, so DuckDB's sqlite extension is not needed.
+func registerSQLite(ctx context.Context, db *sql.DB, lake *lakearrow.Arrow, source DataSource) error {
+	if _, err := os.Stat(source.FilePath); err != nil {
+		return fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	dsn := "file:" + (&url.URL{Path: filepath.ToSlash(source.FilePath)}).EscapedPath() + "?mode=ro"
+	sdb, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	defer sdb.Close()
+
+	tables, err := sqliteTables(ctx, sdb, source.Tables, source.ExcludeTables)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+lakearrow.QuoteIdent(source.TableName)); err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	slake := lakearrow.NewArrow(sdb)
+	for _, table := range tables {
+		if err := registerSQLiteTable(ctx, sdb, slake, lake, source.TableName, table); err != nil {
+			return fmt.Errorf("table %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// sqliteTables lists the tables and views of the database selected by the
+// include and exclude patterns.
+func sqliteTables(ctx context.Context, sdb *sql.DB, include, exclude []string) ([]string, error) {
+	rows, err := sdb.QueryContext(ctx, `SELECT name FROM sqlite_master
+		WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	return matchTables(names, include, exclude)
+}
+
+func registerSQLiteTable(ctx context.Context, sdb *sql.DB, slake, lake *lakearrow.Arrow, schemaName, table string) error {
+	columns, err := sqliteColumns(ctx, sdb, table)
+	if err != nil {
+		return err
+	}
+
+	fields := make([]arrow.Field, len(columns))
+	exprs := make([]string, len(columns))
+	for i, col := range columns {
+		dt, cast := col.arrowType()
+		fields[i] = arrow.Field{
+			Name:     col.name,
+			Type:     dt,
+			Nullable: !col.notNull,
+			Metadata: arrow.NewMetadata(
+				[]string{lakearrow.MetadataDBType, lakearrow.MetadataSourceTable, lakearrow.MetadataSourceColumn},
+				[]string{col.declared, table, col.name}),
+		}
+		exprs[i] = lakearrow.QuoteIdent(col.name)
+		if cast != "" {
+			exprs[i] = fmt.Sprintf("CAST(%s AS %s)", exprs[i], cast)
+		}
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(exprs, ", "), lakearrow.QuoteIdent(table))
+	rdr, err := slake.QueryArrowStreamSchema(ctx, arrow.NewSchema(fields, nil), recordBatchSize, query)
+	if err != nil {
+		return err
+	}
+	defer rdr.Release()
+
+	return lake.RegisterViewIn(ctx, schemaName, table, rdr)
+}
+
+// sqliteColumn describes a column by its declared type and by the storage
+// classes of the values it actually holds, since SQLite does not enforce
+// declared types.
+type sqliteColumn struct {
+	name     string
+	declared string
+	notNull  bool
+
+	hasInteger, hasReal, hasText, hasBlob bool
+	// badTime is set when a text value is not a date or time ParseTime can
+	// read.
+	badTime bool
+}
+
+func sqliteColumns(ctx context.Context, sdb *sql.DB, table string) ([]*sqliteColumn, error) {
+	rows, err := sdb.QueryContext(ctx, "PRAGMA table_info("+lakearrow.QuoteIdent(table)+")")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []*sqliteColumn
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, declared   string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &declared, &notNull, &dflt, &pk); err != nil {
+			return nil, fmt.Errorf("failed to read columns: %w", err)
+		}
+		columns = append(columns, &sqliteColumn{name: name, declared: declared, notNull: notNull != 0})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table has no columns")
+	}
+
+	exprs := make([]string, 0, 4*len(columns))
+	for _, col := range columns {
+		c := lakearrow.QuoteIdent(col.name)
+		exprs = append(exprs,
+			fmt.Sprintf("coalesce(max(typeof(%s) = 'integer'), 0)", c),
+			fmt.Sprintf("coalesce(max(typeof(%s) = 'real'), 0)", c),
+			fmt.Sprintf("coalesce(max(typeof(%s) = 'text'), 0)", c),
+			fmt.Sprintf("coalesce(max(typeof(%s) = 'blob'), 0)", c))
+	}
+
+	flags := make([]bool, len(exprs))
+	dest := make([]any, len(exprs))
+	for i := range flags {
+		dest[i] = &flags[i]
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(exprs, ", "), lakearrow.QuoteIdent(table))
+	if err := sdb.QueryRowContext(ctx, query).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to inspect column values: %w", err)
+	}
+	for i, col := range columns {
+		f := flags[4*i:]
+		col.hasInteger, col.hasReal, col.hasText, col.hasBlob = f[0], f[1], f[2], f[3]
+		if col.hasText && col.isTime() {
+			if col.badTime, err = badTimes(ctx, sdb, table, col.name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return columns, nil
+}
+
+// badTimes reports whether a text value of column is not a date or time
+// the row path can parse. SQLite's own date functions accept more, such as
+// 'now' and Julian day numbers, so the values are checked in Go.
+func badTimes(ctx context.Context, sdb *sql.DB, table, column string) (bool, error) {
+	// The cast keeps the driver from parsing the values of DATETIME columns.
+	c := lakearrow.QuoteIdent(column)
+	rows, err := sdb.QueryContext(ctx, fmt.Sprintf("SELECT DISTINCT CAST(%s AS TEXT) FROM %s WHERE typeof(%s) = 'text'", c, lakearrow.QuoteIdent(table), c))
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect column values: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return false, fmt.Errorf("failed to inspect column values: %w", err)
+		}
+		if _, err := lakearrow.ParseTime(s); err != nil {
+			return true, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to inspect column values: %w", err)
+	}
+	return false, nil
+}
+
+var sqliteDecimalRe = regexp.MustCompile(`^(?:DECIMAL|NUMERIC)\s*\(\s*(\d+)\s*(?:,\s*(\d+)\s*)?\)$`)
+
+// arrowType maps the column to an Arrow type following SQLite's rules for
+// column affinity, refined by a few common declared types such as BOOLEAN,
+// DATE and DECIMAL(p, s). When the stored values do not fit that type, it
+// falls back to a type that holds all of them, returning the SQLite type the
+// values must be cast to.
+func (c *sqliteColumn) arrowType() (arrow.DataType, string) {
+	declared := strings.ToUpper(strings.TrimSpace(c.declared))
+	numbersOnly := !c.hasText && !c.hasBlob
+
+	switch {
+	case strings.Contains(declared, "INT"):
+		if numbersOnly && !c.hasReal {
+			return arrow.PrimitiveTypes.Int64, ""
+		}
+	case strings.Contains(declared, "CHAR"), strings.Contains(declared, "CLOB"), strings.Contains(declared, "TEXT"):
+		if !c.hasBlob {
+			return arrow.BinaryTypes.String, "TEXT"
+		}
+	case strings.Contains(declared, "BLOB"):
+		if !c.hasInteger && !c.hasReal && !c.hasText {
+			return arrow.BinaryTypes.Binary, ""
+		}
+	case declared == "":
+	case strings.Contains(declared, "REAL"), strings.Contains(declared, "FLOA"), strings.Contains(declared, "DOUB"):
+		if numbersOnly {
+			return arrow.PrimitiveTypes.Float64, "REAL"
+		}
+	case strings.Contains(declared, "BOOL"):
+		if numbersOnly && !c.hasReal {
+			return arrow.FixedWidthTypes.Boolean, ""
+		}
+	case strings.Contains(declared, "DATETIME"), strings.Contains(declared, "TIMESTAMP"):
+		if c.textTimes() {
+			return &arrow.TimestampType{Unit: arrow.Microsecond}, ""
+		}
+	case strings.Contains(declared, "DATE"):
+		if c.textTimes() {
+			return arrow.FixedWidthTypes.Date32, ""
+		}
+	default:
+		if m := sqliteDecimalRe.FindStringSubmatch(declared); m != nil && numbersOnly {
+			precision, _ := strconv.Atoi(m[1])
+			scale, _ := strconv.Atoi(m[2])
+			if precision >= 1 && precision <= 38 && scale <= precision {
+				return &arrow.Decimal128Type{Precision: int32(precision), Scale: int32(scale)}, ""
+			}
+		}
+	}
+	return c.storedType()
+}
+
+// isTime reports whether the declared type makes arrowType read the column
+// as dates or timestamps.
+func (c *sqliteColumn) isTime() bool {
+	declared := strings.ToUpper(c.declared)
+	return strings.Contains(declared, "DATE") || strings.Contains(declared, "TIMESTAMP")
+}
+
+// textTimes reports whether every stored value is a date or time string.
+// Numbers are not converted: SQLite stores them as Julian days, Unix seconds
+// or whatever unit the application chose, and nothing records which, so such
+// columns keep their stored values.
+func (c *sqliteColumn) textTimes() bool {
+	return !c.hasInteger && !c.hasReal && !c.hasBlob && !c.badTime
+}
+
+// storedType returns the narrowest type that holds every stored value.
+func (c *sqliteColumn) storedType() (arrow.DataType, string) {
+	switch {
+	case c.hasBlob && !c.hasInteger && !c.hasReal && !c.hasText:
+		return arrow.BinaryTypes.Binary, ""
+	case c.hasBlob:
+		return arrow.BinaryTypes.Binary, "BLOB"
+	case c.hasText:
+		return arrow.BinaryTypes.String, "TEXT"
+	case c.hasReal:
+		return arrow.PrimitiveTypes.Float64, "REAL"
+	case c.hasInteger:
+		return arrow.PrimitiveTypes.Int64, ""
+	}
+	return arrow.BinaryTypes.String, "TEXT"
+}
@@ -0,0 +1,213 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteFixture holds the DDL of the SQLite test database.
+const sqliteFixture = `
+	CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL, n_nationkey INT,
+		active BOOLEAN, joined DATE, seen DATETIME, balance DECIMAL(10, 2), score REAL, avatar BLOB, extra);
+	INSERT INTO customers VALUES
+		(1, 'Ann', 3, 1, '2024-01-02', '2024-01-02 10:30:00', 12.5, 4, x'0102', 7),
+		(2, 'Bob', 4, 0, '2024-02-03', '2024-02-03T08:00:00Z', '3.25', 2.5, NULL, 'seven'),
+		(3, 'Cy', 30, NULL, NULL, NULL, NULL, NULL, NULL, NULL);
+	CREATE TABLE mixed (a INTEGER, b REAL, c DATE, d VARCHAR(10), e BLOB);
+	INSERT INTO mixed VALUES (1, 1.5, '2024-01-02', 'x', x'00'), (2.5, 'n/a', 'someday', 5, 'text');
+	CREATE TABLE audit_log (id INTEGER);
+	CREATE VIEW active_customers AS SELECT id, name FROM customers WHERE active;
+`
+
+func TestSQLiteSource(t *testing.T) {
+	db := openTestDB(t)
+	path := filepath.Join(t.TempDir(), "shop.db")
+	writeDBFixture(t, "sqlite", path, sqliteFixture)
+
+	config := &Config{
+		Sources: []DataSource{
+			{Type: "parquet", TableName: "nation", FilePath: "../../data/nation.parquet"},
+			{Type: "sqlite", TableName: "shop", FilePath: path, ExcludeTables: []string{"audit_*"}},
+		},
+		Query: QueryConfig{SQL: "SELECT count(*) FROM shop.customers JOIN nation USING (n_nationkey)"},
+	}
+	if err := joinWithDB(context.Background(), db, config); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+
+	var tables string
+	err := db.QueryRow(`SELECT string_agg(table_name, ',' ORDER BY table_name) FROM information_schema.tables WHERE table_schema = 'shop'`).Scan(&tables)
+	if err != nil {
+		t.Fatalf("failed to list tables: %v", err)
+	}
+	if tables != "active_customers,customers,mixed" {
+		t.Fatalf("unexpected tables: %s", tables)
+	}
+
+	typesOf := func(table string) string {
+		t.Helper()
+		var types string
+		err := db.QueryRow(`SELECT string_agg(column_name || ' ' || data_type, ', ' ORDER BY ordinal_position)
+			FROM information_schema.columns WHERE table_schema = 'shop' AND table_name = ?`, table).Scan(&types)
+		if err != nil {
+			t.Fatalf("failed to read columns of %s: %v", table, err)
+		}
+		return types
+	}
+	if got, want := typesOf("customers"), "id BIGINT, name VARCHAR, n_nationkey BIGINT, active BOOLEAN, joined DATE, "+
+		"seen TIMESTAMP, balance DECIMAL(10,2), score DOUBLE, avatar BLOB, extra VARCHAR"; got != want {
+		t.Fatalf("unexpected customers columns:\n got %s\nwant %s", got, want)
+	}
+	// Values that do not fit the declared types widen the columns instead
+	// of failing.
+	if got, want := typesOf("mixed"), "a DOUBLE, b VARCHAR, c VARCHAR, d VARCHAR, e BLOB"; got != want {
+		t.Fatalf("unexpected mixed columns:\n got %s\nwant %s", got, want)
+	}
+
+	var row string
+	err = db.QueryRow(`SELECT concat_ws('|', name, active, joined, seen, balance, score, hex(avatar), extra)
+		FROM shop.customers WHERE id = 1`).Scan(&row)
+	if err != nil {
+		t.Fatalf("failed to query customers: %v", err)
+	}
+	if row != "Ann|true|2024-01-02|2024-01-02 10:30:00|12.50|4.0|0102|7" {
+		t.Fatalf("unexpected customer: %s", row)
+	}
+
+	err = db.QueryRow(`SELECT concat_ws('|', a, b, c, d, hex(e)) FROM shop.mixed WHERE a = 2.5`).Scan(&row)
+	if err != nil {
+		t.Fatalf("failed to query mixed: %v", err)
+	}
+	if row != "2.5|n/a|someday|5|74657874" {
+		t.Fatalf("unexpected mixed row: %s", row)
+	}
+}
+
+func TestSQLiteSourceNumericTimes(t *testing.T) {
+	db := openTestDB(t)
+	path := filepath.Join(t.TempDir(), "times.db")
+	writeDBFixture(t, "sqlite", path, `
+		CREATE TABLE times (julian DATE, millis DATETIME, seconds DATETIME, mixed DATE, text DATE);
+		INSERT INTO times VALUES (2460311.5, 1704164645000, 1704164645, 1704164645, '2024-01-02'),
+			(NULL, NULL, NULL, '2024-01-02', NULL);
+	`)
+
+	config := &Config{
+		Sources: []DataSource{{Type: "sqlite", TableName: "t", FilePath: path}},
+		Query:   QueryConfig{SQL: "SELECT count(*) FROM t.times"},
+	}
+	if err := joinWithDB(context.Background(), db, config); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+
+	var row string
+	err := db.QueryRow(`SELECT concat_ws('|', typeof(julian), julian, typeof(millis), millis, typeof(seconds), seconds,
+		typeof(mixed), mixed, typeof(text), text) FROM t.times WHERE julian IS NOT NULL`).Scan(&row)
+	if err != nil {
+		t.Fatalf("failed to query times: %v", err)
+	}
+	// Numbers in date columns are kept as stored rather than guessing a unit.
+	if want := "DOUBLE|2460311.5|BIGINT|1704164645000|BIGINT|1704164645|VARCHAR|1704164645|DATE|2024-01-02"; row != want {
+		t.Fatalf("unexpected times:\n got %s\nwant %s", row, want)
+	}
+}
+
+func TestSQLiteSourceTextTimes(t *testing.T) {
+	db := openTestDB(t)
+	path := filepath.Join(t.TempDir(), "times.db")
+	// SQLite's date functions accept all of these, but only the last column
+	// holds text the row path parses. The Julian day is stored as a REAL by
+	// the DATE column's numeric affinity.
+	writeDBFixture(t, "sqlite", path, `
+		CREATE TABLE times (minutes DATETIME, julian DATE, now DATETIME, ok DATETIME);
+		INSERT INTO times VALUES ('2024-01-02 10:00', '2460000.5', 'now', '2024-01-02 10:00:00');
+	`)
+
+	config := &Config{
+		Sources: []DataSource{{Type: "sqlite", TableName: "t", FilePath: path}},
+		Query:   QueryConfig{SQL: "SELECT count(*) FROM t.times"},
+	}
+	if err := joinWithDB(context.Background(), db, config); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+
+	var row string
+	err := db.QueryRow(`SELECT concat_ws('|', typeof(minutes), minutes, typeof(julian), julian, typeof(now), now, typeof(ok), ok)
+		FROM t.times`).Scan(&row)
+	if err != nil {
+		t.Fatalf("failed to query times: %v", err)
+	}
+	if want := "VARCHAR|2024-01-02 10:00|DOUBLE|2460000.5|VARCHAR|now|TIMESTAMP|2024-01-02 10:00:00"; row != want {
+		t.Fatalf("unexpected times:\n got %s\nwant %s", row, want)
+	}
+}
+
+func TestSQLiteSourceTables(t *testing.T) {
+	db := openTestDB(t)
+	path := filepath.Join(t.TempDir(), "shop.db")
+	writeDBFixture(t, "sqlite", path, sqliteFixture)
+
+	config := &Config{
+		Sources: []DataSource{
+			{Type: "sqlite", TableName: "shop", FilePath: path, Tables: []string{"*customers"}, ExcludeTables: []string{"active_*"}},
+		},
+		Query: QueryConfig{SQL: "SELECT count(*) FROM shop.customers"},
+	}
+	if err := joinWithDB(context.Background(), db, config); err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+	var tables string
+	if err := db.QueryRow(`SELECT string_agg(table_name, ',') FROM information_schema.tables WHERE table_schema = 'shop'`).Scan(&tables); err != nil {
+		t.Fatalf("failed to list tables: %v", err)
+	}
+	if tables != "customers" {
+		t.Fatalf("unexpected tables: %s", tables)
+	}
+
+	for _, tc := range []struct {
+		source DataSource
+		want   string
+	}{
+		{DataSource{Type: "sqlite", TableName: "a", FilePath: path, Tables: []string{"orders"}}, `no tables match "orders"`},
+		{DataSource{Type: "sqlite", TableName: "b", FilePath: path, Tables: []string{"[a"}}, "invalid table pattern"},
+		{DataSource{Type: "sqlite", TableName: "c", FilePath: filepath.Join(t.TempDir(), "missing.db")}, "no such file"},
+	} {
+		err := joinWithDB(context.Background(), db, &Config{Sources: []DataSource{tc.source}, Query: config.Query})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("expected an error containing %q, got %v", tc.want, err)
+		}
+	}
+}
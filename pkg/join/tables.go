@@ -0,0 +1,113 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// matchTables returns the names that match one of the include patterns, or
+// any when there are none, and none of the exclude patterns. Patterns use
+// path.Match syntax. Names may be qualified as schema.table, in which case a
+// pattern without a dot matches the table name alone. An include pattern
+// that matches nothing is an error, as it is most likely a typo.
+func matchTables(names, include, exclude []string) ([]string, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid table pattern %q: %w", pattern, err)
+		}
+	}
+
+	used := make([]bool, len(include))
+	var tables []string
+	for _, name := range names {
+		included := len(include) == 0
+		for i, pattern := range include {
+			if matchTable(pattern, name) {
+				included, used[i] = true, true
+			}
+		}
+		for _, pattern := range exclude {
+			if matchTable(pattern, name) {
+				included = false
+			}
+		}
+		if included {
+			tables = append(tables, name)
+		}
+	}
+
+	for i, pattern := range include {
+		if !used[i] {
+			return nil, fmt.Errorf("no tables match %q", pattern)
+		}
+	}
+	return tables, nil
+}
+
+func matchTable(pattern, name string) bool {
+	if ok, _ := path.Match(pattern, name); ok {
+		return true
+	}
+	if _, table, qualified := strings.Cut(name, "."); qualified && !strings.Contains(pattern, ".") {
+		ok, _ := path.Match(pattern, table)
+		return ok
+	}
+	return false
+}
+
+// matchSchemas returns the set of schemas that match one of the patterns, or
+// every schema when there are none.
+func matchSchemas(schemas, patterns []string) (map[string]bool, error) {
+	visible := make(map[string]bool)
+	for _, pattern := range patterns {
+		matched := false
+		for _, schema := range schemas {
+			ok, err := path.Match(pattern, schema)
+			if err != nil {
+				return nil, fmt.Errorf("invalid schema pattern %q: %w", pattern, err)
+			}
+			if ok {
+				visible[schema], matched = true, true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no schemas match %q", pattern)
+		}
+	}
+	if len(patterns) == 0 {
+		for _, schema := range schemas {
+			visible[schema] = true
+		}
+	}
+	return visible, nil
+}
@@ -0,0 +1,697 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	lakearrow "github.com/TFMV/arrowlake/pkg/arrow"
+)
+
+// QueryConfig.SQL is a template. Text is copied as is, except for tags in
+// braces:
+//
+//	{name}                      a reference, see below
+//	{if name} ... {else} ... {end}
+//	{if not name} ... {end}     runs a branch depending on whether name is set
+//	                            to a value other than false, zero, null or empty
+//	{for x in name} ... {end}   repeats for each item of a list
+//	{{                          a literal {
+//
+// References are resolved in this order:
+//
+//	x, loop.first, loop.last, loop.index
+//	                            the item and position of the enclosing for
+//	select_columns              the select_columns expressions, comma separated
+//	join_columns                the join columns as source.column, comma separated
+//	sources                     the table names of all sources, comma separated
+//	param                       a query param, as an SQL literal; lists render as
+//	                            comma-separated literals
+//	source                      the table name of a source
+//	source.column               a column of a source, checked when possible
+//	catalog.schema.table        a table of a postgres, sqlite or duckdb source
+//	column                      the column of a join column
+//
+// Names are quoted as identifiers. Braces that do not hold a tag, such as those
+// of the DuckDB struct literal {'a': 1}, are left alone, as is everything in
+// string literals and comments, so '{x}' stays as written. References that do
+// not resolve are errors, except for a plain name in {if}, which is unset.
+
+// TemplateError is an error in QueryConfig.SQL at Offset, a byte offset into
+// the SQL.
+type TemplateError struct {
+	Offset       int
+	Line, Column int
+	Message      string
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("sql line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+func templateErrorf(sql string, offset int, format string, args ...any) *TemplateError {
+	line := strings.Count(sql[:offset], "\n") + 1
+	column := offset - strings.LastIndexByte(sql[:offset], '\n')
+	return &TemplateError{Offset: offset, Line: line, Column: column, Message: fmt.Sprintf(format, args...)}
+}
+
+type tmplNode interface{}
+
+type (
+	textNode string
+	refNode  struct {
+		path []string
+		tag  string
+		off  int
+	}
+	ifNode struct {
+		not       bool
+		cond      refNode
+		then, els []tmplNode
+	}
+	forNode struct {
+		name string
+		list refNode
+		body []tmplNode
+	}
+)
+
+var (
+	refRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+	ifRe  = regexp.MustCompile(`^if\s+(not\s+)?(\S+)$`)
+	forRe = regexp.MustCompile(`^for\s+([A-Za-z_][A-Za-z0-9_]*)\s+in\s+(\S+)$`)
+)
+
+// parseTemplate parses sql into a tree of nodes.
+func parseTemplate(sql string) ([]tmplNode, error) {
+	p := &tmplParser{sql: sql}
+	nodes, end, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, templateErrorf(sql, p.tagOff, "{%s} without {if} or {for}", end)
+	}
+	return nodes, nil
+}
+
+type tmplParser struct {
+	sql    string
+	pos    int
+	tagOff int
+}
+
+// parse reads nodes up to the end of the SQL or an {else} or {end} tag, which
+// it returns.
+func (p *tmplParser) parse() ([]tmplNode, string, error) {
+	var (
+		nodes []tmplNode
+		text  strings.Builder
+	)
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, textNode(text.String()))
+			text.Reset()
+		}
+	}
+
+	for p.pos < len(p.sql) {
+		i := strings.IndexAny(p.sql[p.pos:], "{'\"-/")
+		if i < 0 {
+			text.WriteString(p.sql[p.pos:])
+			break
+		}
+		text.WriteString(p.sql[p.pos : p.pos+i])
+		start := p.pos + i
+
+		if end := skipLiteral(p.sql, start); end > start {
+			text.WriteString(p.sql[start:end])
+			p.pos = end
+			continue
+		}
+		if p.sql[start] != '{' {
+			text.WriteByte(p.sql[start])
+			p.pos = start + 1
+			continue
+		}
+		if strings.HasPrefix(p.sql[start:], "{{") {
+			text.WriteByte('{')
+			p.pos = start + 2
+			continue
+		}
+		tag, ok := p.tag(start)
+		if !ok {
+			text.WriteByte('{')
+			p.pos = start + 1
+			continue
+		}
+		p.pos = start + len(tag) + 2
+		p.tagOff = start
+		tag = strings.TrimSpace(tag)
+
+		switch {
+		case tag == "else" || tag == "end":
+			flush()
+			return nodes, tag, nil
+		case refRe.MatchString(tag):
+			flush()
+			nodes = append(nodes, refNode{path: strings.Split(tag, "."), tag: tag, off: start})
+		case strings.HasPrefix(tag, "if "):
+			flush()
+			node, err := p.parseIf(tag, start)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, node)
+		case strings.HasPrefix(tag, "for "):
+			flush()
+			node, err := p.parseFor(tag, start)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, node)
+		default:
+			text.WriteString(p.sql[start:p.pos])
+		}
+	}
+	flush()
+	return nodes, "", nil
+}
+
+// skipLiteral returns the end of the string literal, quoted identifier or
+// comment at start, or start if there is none. An unterminated one runs to the
+// end of the SQL.
+func skipLiteral(sql string, start int) int {
+	rest := sql[start:]
+	switch {
+	case rest[0] == '\'' || rest[0] == '"':
+		quote := rest[0]
+		for i := 1; i < len(rest); i++ {
+			if rest[i] != quote {
+				continue
+			}
+			if i+1 < len(rest) && rest[i+1] == quote {
+				i++
+				continue
+			}
+			return start + i + 1
+		}
+	case strings.HasPrefix(rest, "--"):
+		if i := strings.IndexByte(rest, '\n'); i >= 0 {
+			return start + i
+		}
+	case strings.HasPrefix(rest, "/*"):
+		if i := strings.Index(rest[2:], "*/"); i >= 0 {
+			return start + i + 4
+		}
+	default:
+		return start
+	}
+	return len(sql)
+}
+
+// tag returns the text between the brace at start and the closing brace on
+// the same line, if the text is a tag.
+func (p *tmplParser) tag(start int) (string, bool) {
+	end := strings.IndexAny(p.sql[start+1:], "{}\n")
+	if end < 0 || p.sql[start+1+end] != '}' {
+		return "", false
+	}
+	tag := p.sql[start+1 : start+1+end]
+	trimmed := strings.TrimSpace(tag)
+	switch {
+	case trimmed == "else", trimmed == "end", refRe.MatchString(trimmed),
+		strings.HasPrefix(trimmed, "if "), strings.HasPrefix(trimmed, "for "):
+		return tag, true
+	}
+	return "", false
+}
+
+func (p *tmplParser) parseIf(tag string, start int) (tmplNode, error) {
+	m := ifRe.FindStringSubmatch(tag)
+	if m == nil || !refRe.MatchString(m[2]) {
+		return nil, templateErrorf(p.sql, start, "invalid tag {%s}, expected {if name} or {if not name}", tag)
+	}
+	node := ifNode{not: m[1] != "", cond: refNode{path: strings.Split(m[2], "."), tag: m[2], off: start}}
+
+	var (
+		end string
+		err error
+	)
+	node.then, end, err = p.parse()
+	if err == nil && end == "else" {
+		node.els, end, err = p.parse()
+		if err == nil && end == "else" {
+			return nil, templateErrorf(p.sql, p.tagOff, "{else} repeated in {%s}", tag)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if end == "" {
+		return nil, templateErrorf(p.sql, start, "{%s} without {end}", tag)
+	}
+	return node, nil
+}
+
+func (p *tmplParser) parseFor(tag string, start int) (tmplNode, error) {
+	m := forRe.FindStringSubmatch(tag)
+	if m == nil || !refRe.MatchString(m[2]) {
+		return nil, templateErrorf(p.sql, start, "invalid tag {%s}, expected {for x in name}", tag)
+	}
+	node := forNode{name: m[1], list: refNode{path: strings.Split(m[2], "."), tag: m[2], off: start}}
+
+	body, end, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	switch end {
+	case "":
+		return nil, templateErrorf(p.sql, start, "{%s} without {end}", tag)
+	case "else":
+		return nil, templateErrorf(p.sql, p.tagOff, "{else} in {%s}", tag)
+	}
+	node.body = body
+	return node, nil
+}
+
+// sqlText is SQL that is rendered as is.
+type sqlText string
+
+// tmplEnv resolves the references of a template.
+type tmplEnv struct {
+	sql     string
+	query   QueryConfig
+	sources map[string]DataSource
+	order   []string
+	// columns returns the columns of a table source. When nil, columns are
+	// not checked.
+	columns func(table string) ([]string, error)
+	vars    []tmplVar
+}
+
+type tmplVar struct {
+	name  string
+	value any
+}
+
+func newTemplateEnv(sources []DataSource, q QueryConfig) *tmplEnv {
+	env := &tmplEnv{sql: q.SQL, query: q, sources: make(map[string]DataSource)}
+	for _, source := range sources {
+		env.sources[source.TableName] = source
+		env.order = append(env.order, source.TableName)
+	}
+	return env
+}
+
+// renderSQL renders the query SQL of a config. Columns are checked with
+// columns unless it is nil.
+func renderSQL(sources []DataSource, q QueryConfig, columns func(table string) ([]string, error)) (string, error) {
+	nodes, err := parseTemplate(q.SQL)
+	if err != nil {
+		return "", err
+	}
+	env := newTemplateEnv(sources, q)
+	env.columns = columns
+	if err := env.check(nodes); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := env.render(&b, nodes); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// checkTemplate reports every error of the query SQL that can be found
+// without running it.
+func checkTemplate(sources []DataSource, q QueryConfig) []*TemplateError {
+	nodes, err := parseTemplate(q.SQL)
+	if err != nil {
+		return []*TemplateError{err.(*TemplateError)}
+	}
+	env := newTemplateEnv(sources, q)
+	var errs []*TemplateError
+	env.walk(nodes, func(err *TemplateError) { errs = append(errs, err) })
+	return errs
+}
+
+// check resolves every reference, including those in branches and loops that
+// will not run, and returns the first error.
+func (env *tmplEnv) check(nodes []tmplNode) error {
+	var first *TemplateError
+	env.walk(nodes, func(err *TemplateError) {
+		if first == nil {
+			first = err
+		}
+	})
+	if first != nil {
+		return first
+	}
+	return nil
+}
+
+func (env *tmplEnv) walk(nodes []tmplNode, report func(*TemplateError)) {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case refNode:
+			if v, err := env.resolve(node); err != nil {
+				report(err)
+			} else if _, err := renderValue(v); err != nil {
+				report(templateErrorf(env.sql, node.off, "{%s}: %v", node.tag, err))
+			}
+		case ifNode:
+			if _, err := env.condition(node.cond); err != nil {
+				report(err)
+			}
+			env.walk(node.then, report)
+			env.walk(node.els, report)
+		case forNode:
+			if v, err := env.resolve(node.list); err != nil {
+				report(err)
+			} else if _, ok := v.([]any); !ok {
+				report(templateErrorf(env.sql, node.list.off, "{for} over %s, which is not a list", node.list.tag))
+			}
+			env.push(node.name, sqlText(""), 0, 1)
+			env.walk(node.body, report)
+			env.pop()
+		}
+	}
+}
+
+func (env *tmplEnv) render(b *strings.Builder, nodes []tmplNode) error {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case textNode:
+			b.WriteString(string(node))
+		case refNode:
+			v, err := env.resolve(node)
+			if err != nil {
+				return err
+			}
+			if list, ok := v.([]any); ok && len(list) == 0 {
+				return templateErrorf(env.sql, node.off, "{%s} is an empty list", node.tag)
+			}
+			text, renderErr := renderValue(v)
+			if renderErr != nil {
+				return templateErrorf(env.sql, node.off, "{%s}: %v", node.tag, renderErr)
+			}
+			b.WriteString(text)
+		case ifNode:
+			v, err := env.condition(node.cond)
+			if err != nil {
+				return err
+			}
+			branch := node.els
+			if truthy(v) != node.not {
+				branch = node.then
+			}
+			if err := env.render(b, branch); err != nil {
+				return err
+			}
+		case forNode:
+			v, err := env.resolve(node.list)
+			if err != nil {
+				return err
+			}
+			items, _ := v.([]any)
+			for i, item := range items {
+				env.push(node.name, item, i, len(items))
+				err := env.render(b, node.body)
+				env.pop()
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (env *tmplEnv) push(name string, item any, i, n int) {
+	env.vars = append(env.vars,
+		tmplVar{name: "loop", value: map[string]any{"first": i == 0, "last": i == n-1, "index": i}},
+		tmplVar{name: name, value: item})
+}
+
+func (env *tmplEnv) pop() {
+	env.vars = env.vars[:len(env.vars)-2]
+}
+
+// condition returns the value of the reference of an {if}, which is nil for
+// a plain name that is not defined.
+func (env *tmplEnv) condition(ref refNode) (any, *TemplateError) {
+	v, err := env.resolve(ref)
+	if err != nil && len(ref.path) == 1 && !env.defined(ref.path[0]) {
+		return nil, nil
+	}
+	return v, err
+}
+
+// defined reports whether name is a loop variable, built-in, param, source
+// or join column.
+func (env *tmplEnv) defined(name string) bool {
+	for _, v := range env.vars {
+		if v.name == name {
+			return true
+		}
+	}
+	switch name {
+	case "select_columns", "join_columns", "sources":
+		return true
+	}
+	if _, ok := env.query.Params[name]; ok {
+		return true
+	}
+	if _, ok := env.sources[name]; ok {
+		return true
+	}
+	for _, col := range env.query.JoinColumns {
+		if col.Column == name || col.Source == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the value of a reference.
+func (env *tmplEnv) resolve(ref refNode) (any, *TemplateError) {
+	name, rest := ref.path[0], ref.path[1:]
+	errorf := func(format string, args ...any) (any, *TemplateError) {
+		return nil, templateErrorf(env.sql, ref.off, format, args...)
+	}
+
+	for i := len(env.vars) - 1; i >= 0; i-- {
+		if env.vars[i].name != name {
+			continue
+		}
+		v := env.vars[i].value
+		if len(rest) == 0 {
+			return v, nil
+		}
+		if fields, ok := v.(map[string]any); ok && len(rest) == 1 {
+			if field, ok := fields[rest[0]]; ok {
+				return field, nil
+			}
+		}
+		return errorf("unknown reference {%s}", ref.tag)
+	}
+
+	if len(rest) == 0 {
+		switch name {
+		case "select_columns":
+			items := make([]any, len(env.query.SelectColumns))
+			for i, col := range env.query.SelectColumns {
+				items[i] = sqlText(col)
+			}
+			return items, nil
+		case "join_columns":
+			items := make([]any, len(env.query.JoinColumns))
+			for i, col := range env.query.JoinColumns {
+				items[i] = sqlText(lakearrow.QuoteIdent(col.Source) + "." + lakearrow.QuoteIdent(col.Column))
+			}
+			return items, nil
+		case "sources":
+			items := make([]any, len(env.order))
+			for i, source := range env.order {
+				items[i] = sqlText(lakearrow.QuoteIdent(source))
+			}
+			return items, nil
+		}
+	}
+
+	param, isParam := env.query.Params[name]
+	source, isSource := env.sources[name]
+	if isParam && isSource {
+		return errorf("ambiguous reference {%s}: both a param and a source", name)
+	}
+	if isParam {
+		if len(rest) > 0 {
+			return errorf("unknown reference {%s}: param %s has no fields", ref.tag, name)
+		}
+		return param, nil
+	}
+
+	for _, col := range env.query.JoinColumns {
+		if len(rest) == 1 && col.Source == name && col.Column == rest[0] {
+			return sqlText(lakearrow.QuoteIdent(col.Source) + "." + lakearrow.QuoteIdent(col.Column)), nil
+		}
+	}
+
+	if isSource {
+		return env.sourceRef(ref, source, rest)
+	}
+
+	if len(rest) == 0 {
+		for _, col := range env.query.JoinColumns {
+			if col.Column == name {
+				return sqlText(lakearrow.QuoteIdent(col.Column)), nil
+			}
+		}
+	}
+	return errorf("unknown reference {%s}", ref.tag)
+}
+
+// sourceRef resolves a reference to a source, one of its columns or, for
+// sources that hold several tables, one of its tables.
+func (env *tmplEnv) sourceRef(ref refNode, source DataSource, rest []string) (any, *TemplateError) {
+	parts := append([]string{source.TableName}, rest...)
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		quoted[i] = lakearrow.QuoteIdent(part)
+	}
+	text := sqlText(strings.Join(quoted, "."))
+
+	switch source.Type {
+	case "postgres", "sqlite", "duckdb":
+		if len(parts) > 4 {
+			return nil, templateErrorf(env.sql, ref.off, "unknown reference {%s}", ref.tag)
+		}
+		return text, nil
+	}
+
+	if len(rest) > 1 {
+		return nil, templateErrorf(env.sql, ref.off, "unknown reference {%s}: %s has no schemas", ref.tag, source.TableName)
+	}
+	if len(rest) == 1 && env.columns != nil {
+		columns, err := env.columns(source.TableName)
+		if err != nil {
+			return nil, templateErrorf(env.sql, ref.off, "{%s}: %v", ref.tag, err)
+		}
+		found := false
+		for _, col := range columns {
+			found = found || strings.EqualFold(col, rest[0])
+		}
+		if !found {
+			return nil, templateErrorf(env.sql, ref.off, "unknown reference {%s}: %s has no column %s", ref.tag, source.TableName, rest[0])
+		}
+	}
+	return text, nil
+}
+
+// renderValue renders a value as SQL: params as literals, lists as comma
+// separated items and sqlText as is.
+func renderValue(v any) (string, error) {
+	switch x := v.(type) {
+	case sqlText:
+		return string(x), nil
+	case nil:
+		return "NULL", nil
+	case bool:
+		if x {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case string:
+		return lakearrow.QuoteString(x), nil
+	case int:
+		return strconv.Itoa(x), nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case uint64:
+		return strconv.FormatUint(x, 10), nil
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return "", fmt.Errorf("%v is not a valid SQL number", x)
+		}
+		return strconv.FormatFloat(x, 'g', -1, 64), nil
+	case time.Time:
+		if x.Equal(x.Truncate(24*time.Hour)) && x.Location() == time.UTC {
+			return "DATE " + lakearrow.QuoteString(x.Format("2006-01-02")), nil
+		}
+		return "TIMESTAMPTZ " + lakearrow.QuoteString(x.Format(time.RFC3339Nano)), nil
+	case []any:
+		items := make([]string, len(x))
+		for i, item := range x {
+			if _, nested := item.([]any); nested {
+				return "", fmt.Errorf("nested lists are not supported")
+			}
+			s, err := renderValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ", "), nil
+	}
+	return "", fmt.Errorf("unsupported value of type %T", v)
+}
+
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case string:
+		return x != ""
+	case sqlText:
+		return x != ""
+	case int:
+		return x != 0
+	case int64:
+		return x != 0
+	case uint64:
+		return x != 0
+	case float64:
+		return x != 0
+	case []any:
+		return len(x) > 0
+	case map[string]any:
+		return len(x) > 0
+	}
+	return true
+}
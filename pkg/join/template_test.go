@@ -0,0 +1,147 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var templateSources = []DataSource{
+	{Type: "parquet", TableName: "nation", FilePath: "../../data/nation.parquet"},
+	{Type: "csv", TableName: "orders", FilePath: "orders.csv"},
+	{Type: "postgres", TableName: "pg", ConnectionString: "dbname=shop"},
+}
+
+func TestRenderSQL(t *testing.T) {
+	query := QueryConfig{
+		SelectColumns: []string{"n.n_name", "count(*) AS orders"},
+		JoinColumns:   []JoinColumn{{Source: "nation", Column: "n_nationkey"}, {Source: "orders", Column: "nation_id"}},
+		Params: map[string]any{
+			"name": "O'Brien", "min": 10, "ratio": 0.5, "active": true, "none": nil,
+			"ids": []any{1, 2, 3}, "tags": []any{"a", "b"}, "empty": []any{}, "off": false,
+		},
+	}
+
+	for _, tc := range []struct {
+		sql, want string
+	}{
+		{"SELECT {select_columns} FROM {nation} n", `SELECT n.n_name, count(*) AS orders FROM "nation" n`},
+		{"ON {nation.n_nationkey} = {orders.nation_id}", `ON "nation"."n_nationkey" = "orders"."nation_id"`},
+		{"ON n.{n_nationkey} = o.{nation_id}", `ON n."n_nationkey" = o."nation_id"`},
+		{"SELECT {orders.total} FROM {pg.public.customers}", `SELECT "orders"."total" FROM "pg"."public"."customers"`},
+		{"WHERE name = {name} AND n > {min} AND r < {ratio} AND a = {active} AND x IS {none}",
+			`WHERE name = 'O''Brien' AND n > 10 AND r < 0.5 AND a = TRUE AND x IS NULL`},
+		{"id IN ({ids}) AND tag IN ({tags})", "id IN (1, 2, 3) AND tag IN ('a', 'b')"},
+		{"{if active}a{else}b{end} {if off}c{else}d{end} {if not empty}e{end} {if none}f{end}", "a d e "},
+		{"{if select_columns}{select_columns}{else}*{end}", "n.n_name, count(*) AS orders"},
+		{"{for c in select_columns}{c}{if not loop.last} | {end}{end}", "n.n_name | count(*) AS orders"},
+		{"{for s in sources}{loop.index}:{s}{if not loop.last},{end}{end}", `0:"nation",1:"orders",2:"pg"`},
+		{"{for id in ids}{for t in tags}({id}, {t}){end}{end}", "(1, 'a')(1, 'b')(2, 'a')(2, 'b')(3, 'a')(3, 'b')"},
+		{"{for i in empty}never {undefined}{end}done", ""},
+		{"{join_columns}", `"nation"."n_nationkey", "orders"."nation_id"`},
+		{"SELECT {'a': 1}, {{min}, {x: {min}}, '{}'", "SELECT {'a': 1}, {min}, {x: 10}, '{}'"},
+		{"{ min }", "10"},
+		{"{if unset}a{else}b{end}{if not unset}c{end}", "bc"},
+		{"WHERE s = '{min}' AND t = 'it''s {min}' -- {min}\nAND {min} /* {min} */", "WHERE s = '{min}' AND t = 'it''s {min}' -- {min}\nAND 10 /* {min} */"},
+		{"SELECT 8/2-1, 'open {min}", "SELECT 8/2-1, 'open {min}"},
+		{`SELECT "a{min}b", "say ""{min}""", {min}`, `SELECT "a{min}b", "say ""{min}""", 10`},
+	} {
+		query.SQL = tc.sql
+		got, err := renderSQL(templateSources, query, nil)
+		if tc.want == "" {
+			if err == nil || !strings.Contains(err.Error(), "unknown reference {undefined}") {
+				t.Errorf("%q: expected an unknown reference error, got %q, %v", tc.sql, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.sql, err)
+		} else if got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.sql, got, tc.want)
+		}
+	}
+}
+
+func TestRenderSQLErrors(t *testing.T) {
+	params := map[string]any{"nation": 1, "m": map[string]any{"a": 1}, "empty": []any{}, "nested": []any{[]any{1}}}
+	for _, tc := range []struct {
+		sql, want string
+	}{
+		{"SELECT {missing}", "sql line 1, column 8: unknown reference {missing}"},
+		{"SELECT 1\n  FROM {orders.a.b}", "sql line 2, column 8: unknown reference {orders.a.b}: orders has no schemas"},
+		{"{nation}", "ambiguous reference {nation}: both a param and a source"},
+		{"{m}", "{m}: unsupported value of type map[string]interface {}"},
+		{"{empty}", "{empty} is an empty list"},
+		{"{nested}", "{nested}: nested lists are not supported"},
+		{"{if orders}x", "{if orders} without {end}"},
+		{"x{end}", "sql line 1, column 2: {end} without {if} or {for}"},
+		{"{if orders}a{else}b{else}c{end}", "{else} repeated in {if orders}"},
+		{"{for o in orders}{o}{end}", "{for} over orders, which is not a list"},
+		{"{for o in empty}{o}{else}{end}", "{else} in {for o in empty}"},
+		{"{if a b}{end}", "invalid tag {if a b}"},
+		{"{for o of empty}{end}", "invalid tag {for o of empty}"},
+		{"{for o in empty}{end}{o}", "unknown reference {o}"},
+		{"{loop.first}", "unknown reference {loop.first}"},
+		{"{if orders.a.b}{end}", "unknown reference {orders.a.b}"},
+	} {
+		_, err := renderSQL(templateSources, QueryConfig{SQL: tc.sql, Params: params}, nil)
+		var terr *TemplateError
+		if !errors.As(err, &terr) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: expected an error containing %q, got %v", tc.sql, tc.want, err)
+		}
+	}
+}
+
+func TestJoinTemplateColumns(t *testing.T) {
+	config := &Config{
+		Sources: []DataSource{{Type: "parquet", TableName: "nation", FilePath: "../../data/nation.parquet"}},
+		Query: QueryConfig{
+			Params: map[string]any{"keys": []any{1, 2}},
+			SQL:    "SELECT {nation.n_name} FROM {nation} WHERE {nation.n_nationkey} IN ({keys}) ORDER BY 1",
+		},
+	}
+	rdr, err := JoinDataSourcesWithDB(context.Background(), openTestDB(t), config)
+	if err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+	defer rdr.Release()
+	if !rdr.Next() || rdr.Record().NumRows() != 2 || rdr.Record().Column(0).ValueStr(0) != "ARGENTINA" {
+		t.Fatalf("unexpected join result")
+	}
+
+	config.Query.SQL = "SELECT {nation.n_population} FROM {nation}"
+	_, err = JoinDataSourcesWithDB(context.Background(), openTestDB(t), config)
+	if err == nil || !strings.Contains(err.Error(), "nation has no column n_population") {
+		t.Fatalf("expected an unknown column error, got %v", err)
+	}
+}
@@ -0,0 +1,478 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a line and column of a config file, both counted from 1.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// ValidationError is a problem with one setting of a config. File and
+// Position are set for configs read by LoadConfig.
+type ValidationError struct {
+	File string
+	Position
+	// Path names the setting, such as sources[1].file_path.
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File + ":")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d:", e.Line, e.Column)
+	}
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path + ": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// ValidationErrors is every problem found by Config.Validate, in the order
+// they appear in the config file.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// sourceKind describes the settings a source type uses.
+type sourceKind struct {
+	// file sources read file_path, which glob sources may give as a pattern.
+	file, glob bool
+	// duckdbGlob sources leave patterns to DuckDB, which also expands **.
+	duckdbGlob bool
+	// conn sources read connection_string.
+	conn bool
+	// options sources are parsed as FileOptions says.
+	options bool
+	// tables sources select tables with tables and exclude_tables.
+	tables bool
+}
+
+var sourceKinds = map[string]sourceKind{
+	"parquet":   {file: true, glob: true, duckdbGlob: true},
+	"postgres":  {conn: true},
+	"arrow_ipc": {file: true, glob: true},
+	"avro":      {file: true, glob: true},
+	"csv":       {file: true, glob: true, duckdbGlob: true, options: true},
+	"json":      {file: true, glob: true, options: true},
+	"sqlite":    {file: true, tables: true},
+	"duckdb":    {file: true, tables: true},
+}
+
+// Validate checks the config without running it, and returns every problem
+// it finds as ValidationErrors. It checks that source types are known, that
+// each source has the settings its type requires and none that do not apply,
+// that files exist, that table names are unique, that the placeholders of
+// the query SQL resolve and that a join refers to known tables.
+func (c *Config) Validate() error {
+	v := &validator{src: c.src}
+	if c.src != nil {
+		v.errs = append(v.errs, c.src.unknown...)
+	}
+
+	names := make(map[string]string)
+	for i, source := range c.Sources {
+		v.source(fmt.Sprintf("sources[%d]", i), source, names)
+	}
+	v.query("query", c.Sources, c.Query)
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	for _, err := range v.errs {
+		err.Message = c.Redact(err.Message)
+	}
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		a, b := v.errs[i].Position, v.errs[j].Position
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return v.errs
+}
+
+type validator struct {
+	src  *configSource
+	errs ValidationErrors
+}
+
+func (v *validator) errorf(setting string, format string, args ...any) {
+	e := &ValidationError{Path: setting, Message: fmt.Sprintf(format, args...)}
+	if v.src != nil {
+		e.File = v.src.file
+		e.Position = v.src.position(setting)
+	}
+	v.errs = append(v.errs, e)
+}
+
+func (v *validator) check(setting string, err error) {
+	if err != nil {
+		v.errorf(setting, "%v", err)
+	}
+}
+
+func (v *validator) source(setting string, source DataSource, names map[string]string) {
+	kind, ok := sourceKinds[source.Type]
+	switch {
+	case source.Type == "":
+		v.errorf(setting+".type", "missing source type")
+	case !ok:
+		types := make([]string, 0, len(sourceKinds))
+		for t := range sourceKinds {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		v.errorf(setting+".type", "unsupported source type %q, expected one of %s", source.Type, strings.Join(types, ", "))
+	}
+
+	if err := checkIdent("table name", source.TableName); err != nil {
+		v.errorf(setting+".table_name", "%v", err)
+	} else if other, dup := names[strings.ToLower(source.TableName)]; dup {
+		// DuckDB identifiers are case insensitive.
+		v.errorf(setting+".table_name", "duplicate table name %q, also used by %s", source.TableName, other)
+	} else {
+		names[strings.ToLower(source.TableName)] = setting
+	}
+
+	if !ok {
+		return
+	}
+
+	switch {
+	case kind.file:
+		v.filePath(setting+".file_path", source.FilePath, kind)
+	case source.FilePath != "":
+		v.errorf(setting+".file_path", "file_path does not apply to %s sources", source.Type)
+	}
+
+	switch {
+	case kind.conn && source.ConnectionString == "":
+		v.errorf(setting+".connection_string", "missing connection string")
+	case kind.conn:
+		v.check(setting+".connection_string", checkConnectionString(source.ConnectionString))
+	case source.ConnectionString != "":
+		v.errorf(setting+".connection_string", "connection_string does not apply to %s sources", source.Type)
+	}
+
+	switch {
+	case kind.options:
+		v.options(setting+".options", source.Type, source.Options)
+	case !reflect.ValueOf(source.Options).IsZero():
+		v.errorf(setting+".options", "options do not apply to %s sources", source.Type)
+	}
+
+	if kind.tables {
+		v.patterns(setting+".tables", source.Tables)
+		v.patterns(setting+".exclude_tables", source.ExcludeTables)
+	} else {
+		if len(source.Tables) > 0 {
+			v.errorf(setting+".tables", "tables does not apply to %s sources", source.Type)
+		}
+		if len(source.ExcludeTables) > 0 {
+			v.errorf(setting+".exclude_tables", "exclude_tables does not apply to %s sources", source.Type)
+		}
+	}
+
+	if source.Type == "duckdb" {
+		v.patterns(setting+".schemas", source.Schemas)
+		filtered := len(source.Schemas) > 0 || len(source.Tables) > 0 || len(source.ExcludeTables) > 0
+		if source.ReadWrite && filtered {
+			v.errorf(setting+".read_write", "schemas and tables cannot be restricted on a read-write source")
+		}
+	} else {
+		if len(source.Schemas) > 0 {
+			v.errorf(setting+".schemas", "schemas does not apply to %s sources", source.Type)
+		}
+		if source.ReadWrite {
+			v.errorf(setting+".read_write", "read_write does not apply to %s sources", source.Type)
+		}
+	}
+}
+
+// filePath checks that a local file path names existing files. Remote
+// paths such as s3:// URLs, and ** patterns DuckDB expands, are left to
+// DuckDB.
+func (v *validator) filePath(setting, p string, kind sourceKind) {
+	if p == "" {
+		v.errorf(setting, "missing file path")
+		return
+	}
+	if err := checkText("file path", p); err != nil {
+		v.errorf(setting, "%v", err)
+		return
+	}
+	if strings.Contains(p, "://") {
+		return
+	}
+
+	if kind.duckdbGlob && recursiveGlob(p) {
+		return
+	}
+	if kind.glob {
+		v.check(setting, func() error { _, err := globFiles(p); return err }())
+		return
+	}
+	if _, err := os.Stat(p); os.IsNotExist(err) {
+		v.errorf(setting, "file %s does not exist", p)
+	} else if err != nil {
+		v.errorf(setting, "%v", err)
+	}
+}
+
+func (v *validator) options(setting, sourceType string, opts FileOptions) {
+	if sourceType != "csv" {
+		if opts.Delimiter != "" {
+			v.errorf(setting+".delimiter", "delimiter does not apply to %s sources", sourceType)
+		}
+		if opts.Quote != "" {
+			v.errorf(setting+".quote", "quote does not apply to %s sources", sourceType)
+		}
+		if opts.Header != nil {
+			v.errorf(setting+".header", "header does not apply to %s sources", sourceType)
+		}
+	}
+
+	switch opts.Compression {
+	case "", "auto", "none", "gzip", "zstd":
+	default:
+		v.errorf(setting+".compression", "unsupported compression %q, expected auto, none, gzip or zstd", opts.Compression)
+	}
+
+	switch opts.OnError {
+	case "", OnErrorFail, OnErrorSkip:
+		if opts.RejectFile != "" {
+			v.errorf(setting+".reject_file", "reject_file requires on_error %s", OnErrorReject)
+		}
+	case OnErrorReject:
+		if opts.RejectFile == "" {
+			v.errorf(setting+".on_error", "on_error %s requires a reject_file", OnErrorReject)
+		}
+	default:
+		v.errorf(setting+".on_error", "unknown on_error policy %q, expected %s, %s or %s",
+			opts.OnError, OnErrorFail, OnErrorSkip, OnErrorReject)
+	}
+
+	for i, col := range opts.Columns {
+		v.check(fmt.Sprintf("%s.columns[%d]", setting, i), checkColumnType(col))
+	}
+}
+
+func (v *validator) patterns(setting string, patterns []string) {
+	for i, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			v.errorf(fmt.Sprintf("%s[%d]", setting, i), "invalid pattern %q: %v", pattern, err)
+		}
+	}
+}
+
+func (v *validator) query(setting string, sources []DataSource, q QueryConfig) {
+	for i, col := range q.JoinColumns {
+		item := fmt.Sprintf("%s.join_columns[%d]", setting, i)
+		if col.Source == "" {
+			v.errorf(item+".source", "missing source")
+		}
+		if col.Column == "" {
+			v.errorf(item+".column", "missing column")
+		}
+	}
+
+	for name, value := range q.Params {
+		if _, err := renderValue(value); err != nil {
+			v.errorf(setting+".params."+name, "%v", err)
+		}
+	}
+
+	if q.Join != nil {
+		if q.SQL != "" {
+			v.errorf(setting+".sql", "sql cannot be given with join")
+		}
+		for _, err := range checkJoin(sources, *q.Join) {
+			v.errorf(setting+".join."+err.setting, "%s", err.message)
+		}
+		return
+	}
+
+	if strings.TrimSpace(q.SQL) == "" {
+		v.errorf(setting+".sql", "missing sql")
+		return
+	}
+
+	if len(q.SelectColumns) == 0 {
+		if i := strings.Index(q.SQL, "{select_columns}"); i >= 0 {
+			v.errorfAt(setting+".sql", q.SQL, i, "{select_columns} is used but select_columns is empty")
+		}
+	}
+	for _, err := range checkTemplate(sources, q) {
+		v.errorfAt(setting+".sql", q.SQL, err.Offset, "%s", err.Message)
+	}
+}
+
+// errorfAt reports a problem found at offset of the text of setting.
+func (v *validator) errorfAt(setting, text string, offset int, format string, args ...any) {
+	v.errorf(setting, format, args...)
+	if v.src != nil {
+		if pos, ok := v.src.textPosition(setting, text, offset); ok {
+			v.errs[len(v.errs)-1].Position = pos
+		}
+	}
+}
+
+// configSource locates the settings of a config file.
+type configSource struct {
+	file  string
+	lines []string
+	// nodes holds the value of each setting, keyed by its path.
+	nodes   map[string]*yaml.Node
+	unknown ValidationErrors
+}
+
+func newConfigSource(file string, data []byte, root *yaml.Node) *configSource {
+	src := &configSource{
+		file:  file,
+		lines: strings.Split(string(data), "\n"),
+		nodes: make(map[string]*yaml.Node),
+	}
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		src.walk(root.Content[0], reflect.TypeOf(Config{}), "")
+	}
+	return src
+}
+
+// walk records the nodes of the settings of type t, and the keys that do not
+// name a setting.
+func (src *configSource) walk(node *yaml.Node, t reflect.Type, setting string) {
+	src.nodes[setting] = node
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			fields[name] = f.Type
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			child := key.Value
+			if setting != "" {
+				child = setting + "." + key.Value
+			}
+			ft, ok := fields[key.Value]
+			if !ok {
+				src.unknown = append(src.unknown, &ValidationError{
+					File:     src.file,
+					Position: Position{key.Line, key.Column},
+					Path:     child,
+					Message:  fmt.Sprintf("unknown setting %q", key.Value),
+				})
+				continue
+			}
+			src.walk(value, ft, child)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			src.walk(item, t.Elem(), fmt.Sprintf("%s[%d]", setting, i))
+		}
+	}
+}
+
+// position returns the position of setting, or of its closest enclosing
+// setting when it is not in the file.
+func (src *configSource) position(setting string) Position {
+	for {
+		if node, ok := src.nodes[setting]; ok {
+			return Position{node.Line, node.Column}
+		}
+		i := strings.LastIndexAny(setting, ".[")
+		if i < 0 {
+			return Position{}
+		}
+		setting = setting[:i]
+	}
+}
+
+// textPosition returns the position of offset within the string value of
+// setting.
+func (src *configSource) textPosition(setting, text string, offset int) (Position, bool) {
+	node, ok := src.nodes[setting]
+	if !ok {
+		return Position{}, false
+	}
+
+	line := node.Line + strings.Count(text[:offset], "\n")
+	if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		// Block scalars start on the line after their indicator.
+		line++
+	}
+	if line < 1 || line > len(src.lines) {
+		return Position{}, false
+	}
+
+	start := strings.LastIndexByte(text[:offset], '\n') + 1
+	end := strings.IndexByte(text[offset:], '}') + offset + 1
+	col := strings.Index(src.lines[line-1], text[start:end])
+	if col < 0 {
+		return Position{}, false
+	}
+	return Position{line, col + 1 + offset - start}, true
+}
@@ -0,0 +1,175 @@
+// --------------------------------------------------------------------------------
+// Author: Thomas F McGeehan V
+//
+// This file is part of a software project developed by Thomas F McGeehan V.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// For more information about the MIT License, please visit:
+// https://opensource.org/licenses/MIT
+//
+// Acknowledgment appreciated but not required.
+// --------------------------------------------------------------------------------
+
+package join
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "orders.csv")
+	writeTestFile(t, csvPath, "id\n1\n")
+
+	path := filepath.Join(dir, "config.yaml")
+	writeTestFile(t, path, `sources:
+  - type: parquet
+    table_name: nation
+    file_path: ../../data/nation.parquet
+  - type: csv
+    table_name: Nation
+    file_path: `+csvPath+`
+    options:
+      on_error: reject
+      compression: lz4
+  - type: excel
+    table_name: sheet
+  - type: postgres
+    table_name: pg
+    file_path: db.sql
+  - type: sqlite
+    tabel_name: shop
+    file_path: `+filepath.Join(dir, "missing.db")+`
+  - type: json
+    table_name: events
+    file_path: `+filepath.Join(dir, "*.json")+`
+    options:
+      delimiter: ";"
+      columns:
+        - name: id
+          type: INTEGER; DROP TABLE nation
+query:
+  join_columns:
+    - source: nation
+      column: n_nationkey
+  sql: |
+    SELECT {select_columns}
+    FROM nation JOIN {orders} ON {nation.n_nationkey} = {orders.id}
+    WHERE struct_pack(a := 1) = {'a': 1}
+`)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	err = config.Validate()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+
+	want := []string{
+		"6:17: sources[1].table_name: duplicate table name \"Nation\", also used by sources[0]",
+		"9:17: sources[1].options.on_error: on_error reject requires a reject_file",
+		"10:20: sources[1].options.compression: unsupported compression \"lz4\", expected auto, none, gzip or zstd",
+		"11:11: sources[2].type: unsupported source type \"excel\", expected one of arrow_ipc, avro, csv, duckdb, json, parquet, postgres, sqlite",
+		"13:5: sources[3].connection_string: missing connection string",
+		"15:16: sources[3].file_path: file_path does not apply to postgres sources",
+		"16:5: sources[4].table_name: missing table name",
+		"17:5: sources[4].tabel_name: unknown setting \"tabel_name\"",
+		"18:16: sources[4].file_path: file " + filepath.Join(dir, "missing.db") + " does not exist",
+		"21:16: sources[5].file_path: no files match \"" + filepath.Join(dir, "*.json") + "\"",
+		"23:18: sources[5].options.delimiter: delimiter does not apply to json sources",
+		"25:11: sources[5].options.columns[0]: column id: invalid type \"INTEGER; DROP TABLE nation\"",
+		"32:12: query.sql: {select_columns} is used but select_columns is empty",
+		"33:22: query.sql: unknown reference {orders}",
+		"33:57: query.sql: unknown reference {orders.id}",
+	}
+	var got []string
+	for _, e := range errs {
+		if e.File != path {
+			t.Errorf("unexpected file %q in %v", e.File, e)
+		}
+		got = append(got, strings.TrimPrefix(e.Error(), path+":"))
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected validation errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestValidateWithoutFile(t *testing.T) {
+	config := &Config{
+		Sources: []DataSource{{Type: "parquet", TableName: "nation", FilePath: "../../data/nation.parquet"}},
+		Query:   QueryConfig{SQL: "SELECT count(*) FROM nation"},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	config.Query.SQL = ""
+	config.Sources = append(config.Sources, DataSource{Type: "duckdb", TableName: "db", FilePath: "../../data/nation.parquet",
+		ReadWrite: true, Schemas: []string{"[main"}})
+	err := config.Validate()
+	want := "sources[1].schemas[0]: invalid pattern \"[main\": syntax error in pattern\n" +
+		"sources[1].read_write: schemas and tables cannot be restricted on a read-write source\n" +
+		"query.sql: missing sql"
+	if err == nil || err.Error() != want {
+		t.Fatalf("unexpected validation errors:\n%v\nwant:\n%s", err, want)
+	}
+}
+
+func TestValidateRecursiveGlob(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile("../../data/nation.parquet")
+	if err != nil {
+		t.Fatalf("failed to read nation.parquet: %v", err)
+	}
+	nested := filepath.Join(dir, "region", "eu")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	writeTestFile(t, filepath.Join(nested, "nation.parquet"), string(data))
+
+	config := &Config{
+		Sources: []DataSource{{Type: "parquet", TableName: "nation", FilePath: filepath.Join(dir, "**", "*.parquet")}},
+		Query:   QueryConfig{SQL: "SELECT count(*) AS n FROM nation"},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	rdr, err := JoinDataSources(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to join data sources: %v", err)
+	}
+	defer rdr.Release()
+	if !rdr.Next() || rdr.Record().Column(0).ValueStr(0) != "25" {
+		t.Fatalf("expected 25 nations, got %v", rdr.Err())
+	}
+
+	config.Sources[0].Type = "avro"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "** is only supported by parquet and csv sources") {
+		t.Fatalf("expected a ** error, got %v", err)
+	}
+}